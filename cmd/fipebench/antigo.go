@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+	"fipe_project/internal/segmentos"
+	"fipe_project/internal/services"
+)
+
+// Implementações anteriores aos pipelines de agregação, mantidas aqui só
+// como referência de desempenho e de resultado.
+
+// resumoMarca são as estatísticas 0km de uma marca, comparáveis entre as
+// implementações.
+type resumoMarca struct {
+	TotalModelos int
+	ComPreco     int
+	Soma         models.Money
+	Menor        models.Money
+	Maior        models.Money
+}
+
+func resumir(stats map[int32]*models.BrandPeriodStats) map[int32]resumoMarca {
+	resumos := make(map[int32]resumoMarca, len(stats))
+	for codigo, s := range stats {
+		resumos[codigo] = resumoMarca{s.TotalModelos, s.TotalVeiculos0km, s.SomaValores0km, s.MenorPreco0km.Valor, s.MaiorPreco0km.Valor}
+	}
+	return resumos
+}
+
+// estatisticasMarcasAntigo lê todos os anos-modelo da tabela com
+// services.ItensTabela e acumula os 0km em Go, como EstatisticasMarcas fazia.
+func estatisticasMarcasAntigo(ctx context.Context, tabelaId int, filtro services.FiltroEstatisticas) (map[int32]resumoMarca, error) {
+	itens, nomes, err := services.ItensTabela(ctx, tabelaId, filtro)
+	if err != nil {
+		return nil, err
+	}
+	resumos := make(map[int32]resumoMarca, len(nomes))
+	for codigo := range nomes {
+		resumos[codigo] = resumoMarca{}
+	}
+	for _, item := range itens {
+		if !item.Ano.ZeroKm {
+			continue
+		}
+		r := resumos[item.BrandCode]
+		r.TotalModelos++
+		if item.PrecoValido {
+			if r.ComPreco == 0 || item.Preco < r.Menor {
+				r.Menor = item.Preco
+			}
+			if r.ComPreco == 0 || item.Preco > r.Maior {
+				r.Maior = item.Preco
+			}
+			r.ComPreco++
+			r.Soma = r.Soma.Add(item.Preco)
+		}
+		resumos[item.BrandCode] = r
+	}
+	return resumos, nil
+}
+
+// zeroKmAntigo percorre todos os documentos da tabela como bson.M e separa
+// as entradas 0km, como o handler de /api/0km fazia.
+func zeroKmAntigo(ctx context.Context, tabelaId int, combustivel models.Combustivel) ([]bson.M, error) {
+	classificador, err := segmentos.Atual(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := database.DB.Collection("Veiculos").Find(ctx, bson.M{"monthYearId": tabelaId})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar tabela %d: %v", tabelaId, err)
+	}
+	defer cursor.Close(ctx)
+
+	var selecionados []bson.M
+	for cursor.Next(ctx) {
+		var doc bson.M
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		modelos, _ := doc["models"].(primitive.A)
+		brandName, _ := doc["brandName"].(string)
+		for _, modelo := range modelos {
+			m, ok := modelo.(bson.M)
+			if !ok {
+				continue
+			}
+			modelCode, _ := m["modelCode"].(int32)
+			modelName, _ := m["modelName"].(string)
+			segmento := classificador.Classificar(brandName, modelName, modelCode)
+			anos, _ := m["years"].(primitive.A)
+			for _, ano := range anos {
+				yearMap, ok := ano.(bson.M)
+				if !ok {
+					continue
+				}
+				detalhes := services.DetalhesAno(yearMap)
+				if !detalhes.ZeroKm || (combustivel != models.CombustivelDesconhecido && detalhes.Combustivel != combustivel) {
+					continue
+				}
+				yearMap["model"] = modelName
+				yearMap["segmento"] = segmento
+				selecionados = append(selecionados, yearMap)
+			}
+		}
+	}
+	return selecionados, cursor.Err()
+}
+
+// zeroKmNovo é o caminho atual de /api/0km, sem a serialização da resposta.
+func zeroKmNovo(ctx context.Context, tabelaId int, combustivel models.Combustivel) ([]bson.M, error) {
+	classificador, err := segmentos.Atual(ctx)
+	if err != nil {
+		return nil, err
+	}
+	entradas, err := services.EntradasZeroKm(ctx, tabelaId)
+	if err != nil {
+		return nil, err
+	}
+	var selecionados []bson.M
+	for _, e := range entradas {
+		detalhes := services.DetalhesAno(e.Ano)
+		if !detalhes.ZeroKm || (combustivel != models.CombustivelDesconhecido && detalhes.Combustivel != combustivel) {
+			continue
+		}
+		e.Ano["model"] = e.ModelName
+		e.Ano["segmento"] = classificador.Classificar(e.BrandName, e.ModelName, e.ModelCode)
+		selecionados = append(selecionados, e.Ano)
+	}
+	return selecionados, nil
+}
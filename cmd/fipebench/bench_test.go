@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/migracoes"
+	"fipe_project/internal/models"
+	"fipe_project/internal/services"
+)
+
+// Os benchmarks usam o banco de FIPEBENCH_BANCO (padrão fipe_bench), com os
+// mesmos dados que o comando gera com os parâmetros padrão. Sem MongoDB
+// acessível, são ignorados.
+
+var (
+	prepararUma sync.Once
+	erroPreparo error
+)
+
+func preparar(b *testing.B) {
+	b.Helper()
+	prepararUma.Do(func() {
+		banco := os.Getenv("FIPEBENCH_BANCO")
+		if banco == "" {
+			banco = "fipe_bench"
+		}
+		if banco == "fipe_db" || banco == os.Getenv("MONGO_DATABASE") {
+			log.Fatalf("Use em FIPEBENCH_BANCO um banco separado do da aplicação (recebido '%s')", banco)
+		}
+		os.Setenv("MONGO_DATABASE", banco)
+		log.SetOutput(io.Discard)
+		if erroPreparo = database.ConnectMongoDB(); erroPreparo != nil {
+			return
+		}
+		ctx := context.Background()
+		var existentes int64
+		if existentes, erroPreparo = database.DB.Collection("Veiculos").CountDocuments(ctx, bson.M{}); erroPreparo != nil {
+			return
+		}
+		if existentes == 0 {
+			p := parametros{Marcas: 90, Modelos: 50, Anos: 4, Tabelas: 2, ChanceZeroKm: 0.2, Semente: 1}
+			if _, _, erroPreparo = gerarDados(ctx, p); erroPreparo != nil {
+				return
+			}
+		}
+		_, erroPreparo = migracoes.GarantirIndices(ctx, false)
+	})
+	if erroPreparo != nil {
+		b.Skipf("MongoDB indisponível: %v", erroPreparo)
+	}
+}
+
+func rodar(b *testing.B, f func(ctx context.Context) (any, int, error)) {
+	preparar(b)
+	ctx := context.Background()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := f(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDashboardAntigo(b *testing.B) {
+	rodar(b, func(ctx context.Context) (any, int, error) {
+		return dashboardAntigo(ctx, tabelaInicial, tabelaInicial+1, services.FiltroEstatisticas{})
+	})
+}
+
+func BenchmarkDashboardAgregacao(b *testing.B) {
+	rodar(b, func(ctx context.Context) (any, int, error) {
+		return dashboardNovo(ctx, tabelaInicial, tabelaInicial+1, services.FiltroEstatisticas{})
+	})
+}
+
+func BenchmarkDashboardFlexAntigo(b *testing.B) {
+	flex := services.FiltroEstatisticas{Combustivel: models.CombustivelFlex}
+	rodar(b, func(ctx context.Context) (any, int, error) {
+		return dashboardAntigo(ctx, tabelaInicial, tabelaInicial+1, flex)
+	})
+}
+
+func BenchmarkDashboardFlexAgregacao(b *testing.B) {
+	flex := services.FiltroEstatisticas{Combustivel: models.CombustivelFlex}
+	rodar(b, func(ctx context.Context) (any, int, error) {
+		return dashboardNovo(ctx, tabelaInicial, tabelaInicial+1, flex)
+	})
+}
+
+func BenchmarkZeroKmAntigo(b *testing.B) {
+	rodar(b, func(ctx context.Context) (any, int, error) {
+		r, err := zeroKmAntigo(ctx, tabelaInicial+1, models.CombustivelDesconhecido)
+		return r, len(r), err
+	})
+}
+
+func BenchmarkZeroKmAgregacao(b *testing.B) {
+	rodar(b, func(ctx context.Context) (any, int, error) {
+		r, err := zeroKmNovo(ctx, tabelaInicial+1, models.CombustivelDesconhecido)
+		return r, len(r), err
+	})
+}
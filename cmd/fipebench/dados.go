@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+)
+
+// Proporções próximas às de uma tabela FIPE real: cerca de 90 marcas, 6 mil
+// modelos e 25 mil anos-modelo, com 0km em uma parte dos modelos.
+type parametros struct {
+	Marcas       int
+	Modelos      int
+	Anos         int
+	Tabelas      int
+	ChanceZeroKm float64
+	Semente      int64
+}
+
+// combustiveis são os códigos da FIPE, com flex mais frequente.
+var combustiveis = []int{5, 5, 5, 5, 1, 1, 3, 2, 4, 6}
+
+// gerarDados apaga Veiculos e TabelaReferencia do banco de testes e grava
+// tabelas sintéticas. A tabela seguinte repete os modelos da anterior com
+// preços reajustados, como nas publicações mensais.
+func gerarDados(ctx context.Context, p parametros) (documentos, entradas int, err error) {
+	veiculos := database.DB.Collection("Veiculos")
+	tabelas := database.DB.Collection("TabelaReferencia")
+	if _, err := veiculos.DeleteMany(ctx, bson.M{}); err != nil {
+		return 0, 0, fmt.Errorf("erro ao limpar Veiculos: %v", err)
+	}
+	if _, err := tabelas.DeleteMany(ctx, bson.M{}); err != nil {
+		return 0, 0, fmt.Errorf("erro ao limpar TabelaReferencia: %v", err)
+	}
+
+	r := rand.New(rand.NewSource(p.Semente))
+	marcas := make([]models.Marca, p.Marcas)
+	codigoModelo := int32(1000)
+	for i := range marcas {
+		marca := &marcas[i]
+		marca.BrandCode = int32(i + 1)
+		marca.BrandName = fmt.Sprintf("Marca %02d", i+1)
+		// Poucas marcas concentram a maior parte dos modelos.
+		n := 1 + r.Intn(2*p.Modelos)
+		if i%10 == 0 {
+			n *= 3
+		}
+		for j := 0; j < n; j++ {
+			codigoModelo++
+			modelo := models.Modelo{ModelCode: codigoModelo, ModelName: fmt.Sprintf("%s Modelo %d %.1f", marca.BrandName, j+1, 1+r.Float64()*2)}
+			combustivel := combustiveis[r.Intn(len(combustiveis))]
+			fipe := fmt.Sprintf("%03d%03d-%d", i+1, j%1000, r.Intn(10))
+			base := models.Money(3_000_000 + r.Int63n(40_000_000))
+			primeiro := 2024 - r.Intn(25)
+			anos := 1 + r.Intn(2*p.Anos)
+			if r.Float64() < p.ChanceZeroKm {
+				ano, _ := models.NovoAnoModelo(fmt.Sprintf("%d-%d", models.AnoZeroKm, combustivel), (base + base/10).String())
+				ano.CodigoFipe = fipe
+				gravarCentavos(&ano)
+				modelo.Years = append(modelo.Years, ano)
+			}
+			for k := 0; k < anos && primeiro-k > 1985; k++ {
+				preco := base.Mul(1 - 0.07*float64(k))
+				ano, _ := models.NovoAnoModelo(strconv.Itoa(primeiro-k)+"-"+strconv.Itoa(combustivel), preco.String())
+				ano.CodigoFipe = fipe
+				gravarCentavos(&ano)
+				modelo.Years = append(modelo.Years, ano)
+			}
+			marca.Models = append(marca.Models, modelo)
+		}
+	}
+
+	for t := 0; t < p.Tabelas; t++ {
+		codigo := tabelaInicial + t
+		mes := fmt.Sprintf("%s/%d ", nomesMeses[t%12], 2024+t/12)
+		if _, err := tabelas.InsertOne(ctx, models.TabelaReferencia{Codigo: codigo, Mes: mes}); err != nil {
+			return documentos, entradas, fmt.Errorf("erro ao gravar tabela %d: %v", codigo, err)
+		}
+		lote := make([]interface{}, 0, len(marcas))
+		for i := range marcas {
+			marca := marcas[i]
+			marca.MonthYearId = codigo
+			if t > 0 {
+				marca.Models = reajustar(r, marca.Models)
+			}
+			for _, m := range marca.Models {
+				entradas += len(m.Years)
+			}
+			lote = append(lote, marca)
+		}
+		if _, err := database.DB.Collection("Veiculos").InsertMany(ctx, lote); err != nil {
+			return documentos, entradas, fmt.Errorf("erro ao gravar veículos da tabela %d: %v", codigo, err)
+		}
+		documentos += len(lote)
+	}
+	return documentos, entradas, nil
+}
+
+const tabelaInicial = 300
+
+var nomesMeses = []string{"janeiro", "fevereiro", "março", "abril", "maio", "junho", "julho", "agosto", "setembro", "outubro", "novembro", "dezembro"}
+
+// reajustar copia os modelos com os preços variando até 2% para cada lado.
+func reajustar(r *rand.Rand, modelos []models.Modelo) []models.Modelo {
+	copia := make([]models.Modelo, len(modelos))
+	for i, m := range modelos {
+		copia[i] = m
+		copia[i].Years = make([]models.AnoModelo, len(m.Years))
+		for j, y := range m.Years {
+			if preco, err := models.ParseMoney(y.Price); err == nil {
+				y.Price = preco.Mul(0.98 + r.Float64()*0.04).String()
+				gravarCentavos(&y)
+			}
+			copia[i].Years[j] = y
+		}
+	}
+	return copia
+}
+
+// gravarCentavos preenche priceCents a partir do texto, como a migração de
+// preços deixa os documentos.
+func gravarCentavos(ano *models.AnoModelo) {
+	if preco, err := models.ParseMoney(ano.Price); err == nil {
+		ano.PrecoCentavos = &preco
+	}
+}
@@ -0,0 +1,218 @@
+// Comando fipebench compara o desempenho das consultas do dashboard de marcas
+// e de /api/0km antes e depois dos pipelines de agregação. Gera tabelas
+// sintéticas de tamanho realista num banco separado, roda cada implementação
+// algumas vezes e confere se os resultados são iguais.
+//
+// Uso:
+//
+//	fipebench [-banco fipe_bench] [-n 5] [-reaproveitar] [-marcas 90 -modelos 50 -anos 4 -tabelas 2]
+//
+// O banco informado em -banco tem Veiculos e TabelaReferencia apagados; por
+// isso não pode ser o banco da aplicação (MONGO_DATABASE).
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/migracoes"
+	"fipe_project/internal/models"
+	"fipe_project/internal/services"
+)
+
+type caso struct {
+	nome     string
+	executar func(ctx context.Context) (resultado any, tamanho int, err error)
+}
+
+type medicao struct {
+	nome      string
+	duracoes  []time.Duration
+	alocado   uint64
+	tamanho   int
+	resultado any
+}
+
+func main() {
+	banco := flag.String("banco", "fipe_bench", "banco de testes (Veiculos e TabelaReferencia são apagados)")
+	n := flag.Int("n", 5, "execuções de cada caso, depois de uma de aquecimento")
+	reaproveitar := flag.Bool("reaproveitar", false, "usa os dados já gerados no banco de testes")
+	timeout := flag.Duration("timeout", 30*time.Minute, "tempo máximo do comando")
+	var p parametros
+	flag.IntVar(&p.Marcas, "marcas", 90, "marcas por tabela")
+	flag.IntVar(&p.Modelos, "modelos", 50, "média de modelos por marca")
+	flag.IntVar(&p.Anos, "anos", 4, "média de anos-modelo por modelo")
+	flag.IntVar(&p.Tabelas, "tabelas", 2, "tabelas de referência")
+	flag.Float64Var(&p.ChanceZeroKm, "zerokm", 0.2, "fração dos modelos com 0km")
+	flag.Int64Var(&p.Semente, "semente", 1, "semente do gerador")
+	flag.Parse()
+
+	if *banco == "" || *banco == "fipe_db" || *banco == os.Getenv("MONGO_DATABASE") {
+		log.Fatalf("Use em -banco um banco separado do da aplicação (recebido '%s')", *banco)
+	}
+	if p.Tabelas < 2 {
+		log.Fatalf("-tabelas deve ser pelo menos 2")
+	}
+	os.Setenv("MONGO_DATABASE", *banco)
+	if err := database.ConnectMongoDB(); err != nil {
+		log.Fatalf("Erro ao conectar no MongoDB: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	existentes, err := database.DB.Collection("Veiculos").CountDocuments(ctx, bson.M{})
+	if err != nil {
+		log.Fatalf("Erro ao contar documentos: %v", err)
+	}
+	if !*reaproveitar || existentes == 0 {
+		inicio := time.Now()
+		documentos, entradas, err := gerarDados(ctx, p)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		log.Printf("Gerados %d documentos e %d anos-modelo em %s", documentos, entradas, time.Since(inicio).Round(time.Millisecond))
+	}
+	if _, err := migracoes.GarantirIndices(ctx, false); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	t1, t2 := tabelaInicial, tabelaInicial+1
+	flex := services.FiltroEstatisticas{Combustivel: models.CombustivelFlex}
+	casos := [][2]caso{
+		{
+			{"dashboard antigo", func(ctx context.Context) (any, int, error) {
+				return dashboardAntigo(ctx, t1, t2, services.FiltroEstatisticas{})
+			}},
+			{"dashboard agregação", func(ctx context.Context) (any, int, error) {
+				return dashboardNovo(ctx, t1, t2, services.FiltroEstatisticas{})
+			}},
+		},
+		{
+			{"dashboard flex antigo", func(ctx context.Context) (any, int, error) {
+				return dashboardAntigo(ctx, t1, t2, flex)
+			}},
+			{"dashboard flex agregação", func(ctx context.Context) (any, int, error) {
+				return dashboardNovo(ctx, t1, t2, flex)
+			}},
+		},
+		{
+			{"0km antigo", func(ctx context.Context) (any, int, error) {
+				r, err := zeroKmAntigo(ctx, t2, models.CombustivelDesconhecido)
+				return chavesZeroKm(r), len(r), err
+			}},
+			{"0km agregação", func(ctx context.Context) (any, int, error) {
+				r, err := zeroKmNovo(ctx, t2, models.CombustivelDesconhecido)
+				return chavesZeroKm(r), len(r), err
+			}},
+		},
+	}
+
+	// Os serviços registram cada consulta; aqui só interessam os tempos.
+	log.SetOutput(io.Discard)
+	var medicoes []medicao
+	var divergencias []string
+	for _, par := range casos {
+		var par2 [2]medicao
+		for i, c := range par {
+			m, err := medir(ctx, c, *n)
+			if err != nil {
+				log.SetOutput(os.Stderr)
+				log.Fatalf("%s: %v", c.nome, err)
+			}
+			par2[i] = m
+			medicoes = append(medicoes, m)
+		}
+		if !reflect.DeepEqual(par2[0].resultado, par2[1].resultado) {
+			divergencias = append(divergencias, fmt.Sprintf("%s x %s", par2[0].nome, par2[1].nome))
+		}
+	}
+	log.SetOutput(os.Stderr)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "CASO\tMÍNIMO\tMEDIANA\tALOCADO/OP\tRESULTADOS\t")
+	for _, m := range medicoes {
+		sort.Slice(m.duracoes, func(i, j int) bool { return m.duracoes[i] < m.duracoes[j] })
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.1f MB\t%d\t\n", m.nome,
+			m.duracoes[0].Round(time.Millisecond), m.duracoes[len(m.duracoes)/2].Round(time.Millisecond),
+			float64(m.alocado)/float64(len(m.duracoes))/(1<<20), m.tamanho)
+	}
+	tw.Flush()
+	if len(divergencias) > 0 {
+		fmt.Fprintf(os.Stderr, "Resultados diferentes: %s\n", strings.Join(divergencias, "; "))
+		os.Exit(1)
+	}
+	fmt.Println("Resultados iguais nas duas implementações.")
+}
+
+// medir roda o caso uma vez para aquecer o cache do MongoDB e depois n vezes,
+// somando a memória alocada pelo processo.
+func medir(ctx context.Context, c caso, n int) (medicao, error) {
+	m := medicao{nome: c.nome}
+	var err error
+	if m.resultado, m.tamanho, err = c.executar(ctx); err != nil {
+		return m, err
+	}
+	var antes, depois runtime.MemStats
+	for i := 0; i < n; i++ {
+		runtime.GC()
+		runtime.ReadMemStats(&antes)
+		inicio := time.Now()
+		if _, _, err := c.executar(ctx); err != nil {
+			return m, err
+		}
+		m.duracoes = append(m.duracoes, time.Since(inicio))
+		runtime.ReadMemStats(&depois)
+		m.alocado += depois.TotalAlloc - antes.TotalAlloc
+	}
+	return m, nil
+}
+
+// dashboardAntigo e dashboardNovo calculam as estatísticas das duas tabelas
+// em sequência, como DashboardMarcas faz em paralelo.
+func dashboardAntigo(ctx context.Context, t1, t2 int, filtro services.FiltroEstatisticas) (any, int, error) {
+	r1, err := estatisticasMarcasAntigo(ctx, t1, filtro)
+	if err != nil {
+		return nil, 0, err
+	}
+	r2, err := estatisticasMarcasAntigo(ctx, t2, filtro)
+	if err != nil {
+		return nil, 0, err
+	}
+	return [2]map[int32]resumoMarca{r1, r2}, len(r1), nil
+}
+
+func dashboardNovo(ctx context.Context, t1, t2 int, filtro services.FiltroEstatisticas) (any, int, error) {
+	s1, _, err := services.EstatisticasMarcas(ctx, t1, "", filtro)
+	if err != nil {
+		return nil, 0, err
+	}
+	s2, _, err := services.EstatisticasMarcas(ctx, t2, "", filtro)
+	if err != nil {
+		return nil, 0, err
+	}
+	return [2]map[int32]resumoMarca{resumir(s1), resumir(s2)}, len(s1), nil
+}
+
+// chavesZeroKm identifica as entradas 0km, ordenadas, para comparar as
+// implementações sem depender da ordem dos documentos.
+func chavesZeroKm(entradas []bson.M) []string {
+	chaves := make([]string, len(entradas))
+	for i, e := range entradas {
+		chaves[i] = fmt.Sprint(e["model"], "|", e["yearCode"], "|", e["price"], "|", e["segmento"])
+	}
+	sort.Strings(chaves)
+	return chaves
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"fipe_project/internal/alerts"
+	"fipe_project/internal/migracoes"
+	"fipe_project/internal/models"
+	"fipe_project/internal/segmentos"
+	"fipe_project/internal/services"
+)
+
+func cmdTabelas(fs *flag.FlagSet) executor {
+	n := fs.Int("n", 24, "quantidade de tabelas, das mais recentes")
+	var filtro services.FiltroTabelas
+	fs.IntVar(&filtro.AnoInicial, "ano-inicial", 0, "só tabelas deste ano em diante")
+	fs.IntVar(&filtro.AnoFinal, "ano-final", 0, "só tabelas até este ano")
+	fs.StringVar(&filtro.Status, "status", "", "só tabelas nesta situação (pendente ou publicada)")
+	return func(ctx context.Context) (saida, error) {
+		tabelas, err := services.ListarTabelas(ctx, filtro)
+		if err != nil {
+			return saida{}, err
+		}
+		if len(tabelas) > *n {
+			tabelas = tabelas[:*n]
+		}
+		s := saida{Dados: tabelas, Cabecalho: []string{"codigo", "mes", "marcas", "modelos", "anos", "status"}}
+		for _, t := range tabelas {
+			s.Linhas = append(s.Linhas, []string{strconv.Itoa(t.Codigo), t.Mes, strconv.Itoa(t.Marcas),
+				strconv.Itoa(t.Modelos), strconv.Itoa(t.AnosModelo), t.Status})
+		}
+		return s, nil
+	}
+}
+
+func cmdMarcas(fs *flag.FlagSet) executor {
+	tabela := fs.Int("tabela", 0, "código da tabela de referência (sem ela, lista todas as marcas catalogadas)")
+	return func(ctx context.Context) (saida, error) {
+		if *tabela == 0 {
+			marcas, err := services.ListarMarcasGlobal(ctx)
+			if err != nil {
+				return saida{}, err
+			}
+			s := saida{Cabecalho: []string{"codigo", "marca", "tabelas", "ultima", "modelos", "0km", "faixa"}, Dados: marcas}
+			for _, m := range marcas {
+				s.Linhas = append(s.Linhas, []string{strconv.Itoa(int(m.BrandCode)), m.BrandName, strconv.Itoa(m.Tabelas),
+					m.Atual.Ref, strconv.Itoa(m.Atual.Modelos), strconv.Itoa(m.Atual.Modelos0km), formatarFaixa(m.Atual.Precos)})
+			}
+			return s, nil
+		}
+		marcas, err := services.ListarMarcas(ctx, *tabela)
+		if err != nil {
+			return saida{}, err
+		}
+		s := saida{Cabecalho: []string{"codigo", "marca", "modelos", "0km", "faixa"}, Dados: marcas}
+		for _, m := range marcas {
+			s.Linhas = append(s.Linhas, []string{strconv.Itoa(int(m.BrandCode)), m.BrandName,
+				strconv.Itoa(m.Modelos), strconv.Itoa(m.Modelos0km), formatarFaixa(m.Precos)})
+		}
+		return s, nil
+	}
+}
+
+func formatarFaixa(f *models.FaixaPreco) string {
+	if f == nil {
+		return "-"
+	}
+	return f.MinimoFmt + " a " + f.MaximoFmt
+}
+
+func cmdModelos(fs *flag.FlagSet) executor {
+	tabela := fs.Int("tabela", 0, "código da tabela de referência (obrigatório)")
+	marca := fs.Int("marca", 0, "código da marca (obrigatório)")
+	segmentoParam := fs.String("segmento", "", "filtra pelo segmento")
+	return func(ctx context.Context) (saida, error) {
+		if err := obrigatorios(fs, "tabela", "marca"); err != nil {
+			return saida{}, err
+		}
+		segmento := models.Segmento(*segmentoParam)
+		if segmento != "" && !segmento.Valido() {
+			return saida{}, fmt.Errorf("segmento '%s' inválido", segmento)
+		}
+		doc, err := services.BuscarMarca(ctx, *tabela, int32(*marca))
+		if err != nil {
+			return saida{}, err
+		}
+		classificador, err := segmentos.Atual(ctx)
+		if err != nil {
+			return saida{}, err
+		}
+		type modeloResumo struct {
+			ModelCode int32           `json:"modelCode"`
+			ModelName string          `json:"modelName"`
+			Segmento  models.Segmento `json:"segmento"`
+			Anos      int             `json:"anos"`
+		}
+		resumo := []modeloResumo{}
+		s := saida{Cabecalho: []string{"codigo", "modelo", "segmento", "anos"}}
+		for _, m := range doc.Models {
+			seg := classificador.Classificar(doc.BrandName, m.ModelName, m.ModelCode)
+			if segmento != "" && seg != segmento {
+				continue
+			}
+			resumo = append(resumo, modeloResumo{m.ModelCode, m.ModelName, seg, len(m.Years)})
+			s.Linhas = append(s.Linhas, []string{strconv.Itoa(int(m.ModelCode)), m.ModelName, string(seg), strconv.Itoa(len(m.Years))})
+		}
+		s.Dados = resumo
+		return s, nil
+	}
+}
+
+func cmdPreco(fs *flag.FlagSet) executor {
+	tabela := fs.Int("tabela", 0, "código da tabela de referência (obrigatório)")
+	modelo := fs.Int("modelo", 0, "código do modelo (obrigatório)")
+	anoParam := fs.String("ano", "", "ano-modelo, código de ano da FIPE (\"2019-1\") ou 0km; sem ele, todos os anos")
+	return func(ctx context.Context) (saida, error) {
+		if err := obrigatorios(fs, "tabela", "modelo"); err != nil {
+			return saida{}, err
+		}
+		var ano int32
+		if *anoParam != "" {
+			var err error
+			if ano, err = parseAno(*anoParam); err != nil {
+				return saida{}, err
+			}
+		}
+		marca, m, err := services.BuscarModelo(ctx, *tabela, *modelo)
+		if err != nil {
+			return saida{}, err
+		}
+		type precoAno struct {
+			BrandName  string           `json:"brandName"`
+			ModelName  string           `json:"modelName"`
+			Ano        models.CodigoAno `json:"ano"`
+			CodigoFipe string           `json:"fipeCode,omitempty"`
+			Preco      models.Money     `json:"preco"`
+			PrecoFmt   string           `json:"precoFmt"`
+		}
+		precos := []precoAno{}
+		s := saida{Cabecalho: []string{"marca", "modelo", "ano", "combustivel", "codigo_fipe", "preco"}}
+		for _, y := range m.Years {
+			if ano != 0 && y.Year != ano {
+				continue
+			}
+			preco, err := models.ParseMoney(y.Price)
+			if err != nil {
+				continue
+			}
+			detalhes := y.Detalhes()
+			precos = append(precos, precoAno{marca.BrandName, m.ModelName, detalhes, y.CodigoFipe, preco, preco.String()})
+			s.Linhas = append(s.Linhas, []string{marca.BrandName, m.ModelName, rotuloAno(detalhes), string(detalhes.Combustivel), y.CodigoFipe, valorDecimal(preco)})
+		}
+		if len(precos) == 0 {
+			return saida{}, services.ErrNaoEncontrado
+		}
+		s.Dados = precos
+		return s, nil
+	}
+}
+
+func cmdHistorico(fs *flag.FlagSet) executor {
+	tabela := fs.Int("tabela", 0, "tabela mais recente do histórico (obrigatório)")
+	modelo := fs.Int("modelo", 0, "código do modelo (obrigatório)")
+	anoParam := fs.String("ano", "", "ano-modelo, código de ano da FIPE ou 0km (obrigatório)")
+	n := fs.Int("n", 12, "quantidade de tabelas")
+	return func(ctx context.Context) (saida, error) {
+		if err := obrigatorios(fs, "tabela", "modelo", "ano"); err != nil {
+			return saida{}, err
+		}
+		ano, err := parseAno(*anoParam)
+		if err != nil {
+			return saida{}, err
+		}
+		historico, err := services.HistoricoPrecos(ctx, *modelo, ano, *tabela, *n)
+		if err != nil {
+			return saida{}, err
+		}
+		s := saida{Dados: historico, Cabecalho: []string{"tabela", "mes", "preco"}}
+		for _, p := range historico {
+			preco := ""
+			if p.Disponivel {
+				preco = valorDecimal(p.Valor)
+			}
+			s.Linhas = append(s.Linhas, []string{strconv.Itoa(p.TabelaId), p.Ref, preco})
+		}
+		return s, nil
+	}
+}
+
+func cmdDashboard(fs *flag.FlagSet) executor {
+	tabela1 := fs.Int("tabela1", 0, "primeira tabela (obrigatório)")
+	tabela2 := fs.Int("tabela2", 0, "segunda tabela (obrigatório)")
+	marca := fs.Int("marca", 0, "filtra pela marca")
+	combustivelParam := fs.String("combustivel", "", "filtra pelo combustível")
+	segmentoParam := fs.String("segmento", "", "filtra pelo segmento")
+	excluir := fs.Bool("excluir-sinalizados", false, "descarta os anos-modelo com ocorrências de qualidade")
+	return func(ctx context.Context) (saida, error) {
+		if err := obrigatorios(fs, "tabela1", "tabela2"); err != nil {
+			return saida{}, err
+		}
+		if *tabela1 == *tabela2 {
+			return saida{}, fmt.Errorf("os períodos de comparação devem ser diferentes")
+		}
+		filtro := services.FiltroEstatisticas{Segmento: models.Segmento(*segmentoParam), ExcluirSinalizados: *excluir}
+		if filtro.Segmento != "" && !filtro.Segmento.Valido() {
+			return saida{}, fmt.Errorf("segmento '%s' inválido", filtro.Segmento)
+		}
+		if *combustivelParam != "" {
+			var err error
+			if filtro.Combustivel, err = models.ParseCombustivel(*combustivelParam); err != nil {
+				return saida{}, err
+			}
+		}
+		if definido(fs, "marca") {
+			m := int32(*marca)
+			filtro.Marca = &m
+		}
+		entradas, err := services.DashboardMarcas(ctx, *tabela1, *tabela2, filtro)
+		if err != nil {
+			return saida{}, err
+		}
+		sort.Slice(entradas, func(i, j int) bool { return entradas[i].BrandName < entradas[j].BrandName })
+		s := saida{Dados: entradas, Cabecalho: []string{"marca", "modelos_1", "modelos_2", "medio_0km_1", "medio_0km_2", "dif_medio_pct", "dif_modelos_pct"}}
+		for _, e := range entradas {
+			s.Linhas = append(s.Linhas, []string{
+				e.BrandName,
+				strconv.Itoa(e.Periodo1.TotalModelos),
+				strconv.Itoa(e.Periodo2.TotalModelos),
+				valorDecimal(e.Periodo1.ValorMedio0km),
+				valorDecimal(e.Periodo2.ValorMedio0km),
+				percentual(e.DiferencasPercentuais.ValorMedio0km),
+				percentual(e.DiferencasPercentuais.TotalModelos),
+			})
+		}
+		return s, nil
+	}
+}
+
+func cmdIngestao(fs *flag.FlagSet) executor {
+	tabela := fs.Int("tabela", 0, "código da tabela ingerida (obrigatório)")
+	return func(ctx context.Context) (saida, error) {
+		if err := obrigatorios(fs, "tabela"); err != nil {
+			return saida{}, err
+		}
+		marcas, err := services.ContarMarcas(ctx, *tabela)
+		if err != nil {
+			return saida{}, err
+		}
+		if marcas == 0 {
+			return saida{}, fmt.Errorf("a tabela %d não tem veículos em Veiculos", *tabela)
+		}
+		alertas, err := alerts.PublicarTabela(ctx, *tabela, marcas)
+		if err != nil {
+			return saida{}, err
+		}
+		resultado := struct {
+			TabelaId int                  `json:"tabelaId"`
+			Marcas   int64                `json:"marcas"`
+			Alertas  []models.AlertaPreco `json:"alertas"`
+		}{*tabela, marcas, alertas}
+		return saida{
+			Dados:     resultado,
+			Cabecalho: []string{"tabela", "marcas", "alertas"},
+			Linhas:    [][]string{{strconv.Itoa(*tabela), strconv.FormatInt(marcas, 10), strconv.Itoa(len(alertas))}},
+		}, nil
+	}
+}
+
+func cmdReconstruir(fs *flag.FlagSet) executor {
+	tabela := fs.Int("tabela", 0, "código da tabela a reconstruir")
+	todas := fs.Bool("todas", false, "reconstrói todas as tabelas presentes em Veiculos")
+	return func(ctx context.Context) (saida, error) {
+		if *todas == definido(fs, "tabela") {
+			return saida{}, fmt.Errorf("informe -tabela ou -todas")
+		}
+		tabelas := []int{*tabela}
+		if *todas {
+			var err error
+			if tabelas, err = services.TabelasComVeiculos(ctx); err != nil {
+				return saida{}, err
+			}
+		}
+
+		type reconstrucao struct {
+			TabelaId int    `json:"tabelaId"`
+			Periodo  string `json:"periodo,omitempty"`
+			Marcas   int    `json:"marcas"`
+		}
+		resultado := []reconstrucao{}
+		s := saida{Cabecalho: []string{"tabela", "periodo", "marcas"}}
+		for _, tabelaId := range tabelas {
+			if err := services.AtualizarPeriodoTabela(ctx, tabelaId); err != nil {
+				return s, err
+			}
+			if err := services.AtualizarContagemTabela(ctx, tabelaId); err != nil {
+				return s, err
+			}
+			if _, err := services.GravarCombustivelTabela(ctx, tabelaId); err != nil {
+				return s, err
+			}
+			marcas, err := services.AtualizarCatalogoMarcas(ctx, tabelaId)
+			if err != nil {
+				return s, err
+			}
+			ref, err := services.TabelaRef(ctx, tabelaId)
+			if err != nil {
+				return s, err
+			}
+			r := reconstrucao{TabelaId: tabelaId, Periodo: models.PeriodoDoMes(ref), Marcas: marcas}
+			resultado = append(resultado, r)
+			s.Linhas = append(s.Linhas, []string{strconv.Itoa(r.TabelaId), r.Periodo, strconv.Itoa(r.Marcas)})
+		}
+		s.Dados = resultado
+		return s, nil
+	}
+}
+
+func cmdMigrar(fs *flag.FlagSet) executor {
+	simular := fs.Bool("simular", false, "só informa o que seria alterado, sem gravar")
+	reverter := fs.Int("reverter", -1, "reverte as migrações com versão maior que esta (0 reverte todas)")
+	situacao := fs.Bool("situacao", false, "lista as migrações e o registro de cada uma")
+	apenasIndices := fs.Bool("indices", false, "só confere e cria os índices")
+	return func(ctx context.Context) (saida, error) {
+		if *situacao {
+			situacoes, err := migracoes.Situacao(ctx)
+			if err != nil {
+				return saida{}, err
+			}
+			s := saida{Dados: situacoes, Cabecalho: []string{"versao", "nome", "reversivel", "status", "concluida", "alterados", "erro"}}
+			for _, m := range situacoes {
+				linha := []string{strconv.Itoa(m.Versao), m.Nome, strconv.FormatBool(m.Reversivel), "pendente", "", "", ""}
+				if r := m.Registro; r != nil {
+					linha[3] = r.Status
+					if r.ConcluidaEm != nil {
+						linha[4] = r.ConcluidaEm.Format(time.RFC3339)
+					}
+					linha[5] = strconv.FormatInt(r.Relatorio.Alterados, 10)
+					linha[6] = r.Erro
+				}
+				s.Linhas = append(s.Linhas, linha)
+			}
+			return s, nil
+		}
+
+		var resultado struct {
+			Indices   []models.EstadoIndice      `json:"indices,omitempty"`
+			Migracoes []models.ResultadoMigracao `json:"migracoes"`
+		}
+		var err error
+		if *reverter >= 0 {
+			resultado.Migracoes, err = migracoes.Reverter(ctx, *reverter, *simular)
+		} else {
+			resultado.Indices, err = migracoes.GarantirIndices(ctx, *simular)
+			if err == nil && !*apenasIndices {
+				resultado.Migracoes, err = migracoes.Aplicar(ctx, *simular, true)
+			}
+		}
+
+		s := saida{Dados: resultado, Cabecalho: []string{"tipo", "nome", "status", "documentos", "alterados", "entradas", "ignoradas", "erro"}}
+		for _, i := range resultado.Indices {
+			status := "existente"
+			if i.Criado {
+				status = "criado"
+			} else if !i.Existe {
+				status = "ausente"
+			}
+			s.Linhas = append(s.Linhas, []string{"indice", i.Colecao + "." + i.Nome, status, "", "", "", "", ""})
+		}
+		for _, m := range resultado.Migracoes {
+			r := m.Relatorio
+			s.Linhas = append(s.Linhas, []string{
+				"migracao", fmt.Sprintf("%d %s", m.Versao, m.Nome), m.Status,
+				strconv.FormatInt(r.Documentos, 10), strconv.FormatInt(r.Alterados, 10),
+				strconv.FormatInt(r.Entradas, 10), strconv.FormatInt(r.Ignoradas, 10), m.Erro,
+			})
+		}
+		return s, err
+	}
+}
+
+// obrigatorios confere se as opções foram informadas na linha de comando.
+func obrigatorios(fs *flag.FlagSet, nomes ...string) error {
+	for _, nome := range nomes {
+		if !definido(fs, nome) {
+			return fmt.Errorf("a opção -%s é obrigatória", nome)
+		}
+	}
+	return nil
+}
+
+func definido(fs *flag.FlagSet, nome string) bool {
+	encontrado := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == nome {
+			encontrado = true
+		}
+	})
+	return encontrado
+}
+
+// parseAno aceita "0km", o código de ano da FIPE ("2019-1") ou só o ano, como
+// os parâmetros 'ano' da API.
+func parseAno(v string) (int32, error) {
+	if v == "0km" {
+		return models.AnoZeroKm, nil
+	}
+	codigo, err := models.ParseCodigoAno(v)
+	if err != nil {
+		return 0, err
+	}
+	return codigo.AnoModelo, nil
+}
+
+func rotuloAno(ano models.CodigoAno) string {
+	if ano.ZeroKm {
+		return "0km"
+	}
+	return strconv.Itoa(int(ano.AnoModelo))
+}
+
+// valorDecimal formata o valor com ponto decimal, para planilhas e scripts.
+func valorDecimal(m models.Money) string {
+	return strconv.FormatFloat(m.Float64(), 'f', 2, 64)
+}
+
+func percentual(p *float64) string {
+	if p == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*p, 'f', 2, 64)
+}
@@ -0,0 +1,103 @@
+// Comando fipectl consulta e administra a base da FIPE direto no MongoDB, sem
+// passar pelo servidor HTTP. Usa as mesmas variáveis de ambiente do servidor
+// (MONGO_URI, MONGO_DATABASE e os arquivos de configuração).
+//
+// Uso:
+//
+//	fipectl [-formato tabela|json|csv] [-timeout 2m] <comando> [opções]
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"fipe_project/internal/database"
+)
+
+// executor roda o comando depois que as opções foram lidas e o banco conectado.
+type executor func(ctx context.Context) (saida, error)
+
+type comando struct {
+	descricao string
+	// configurar registra as opções do comando e devolve o executor.
+	configurar func(fs *flag.FlagSet) executor
+}
+
+var comandos = map[string]comando{
+	"tabelas":     {"lista as tabelas de referência", cmdTabelas},
+	"marcas":      {"lista as marcas de uma tabela ou de todas", cmdMarcas},
+	"modelos":     {"lista os modelos de uma marca", cmdModelos},
+	"preco":       {"mostra os preços dos anos-modelo de um modelo", cmdPreco},
+	"historico":   {"mostra o histórico de preço de um ano-modelo", cmdHistorico},
+	"dashboard":   {"compara as estatísticas das marcas entre duas tabelas", cmdDashboard},
+	"ingestao":    {"executa a etapa pós-ingestão de uma tabela (evento e watchlist)", cmdIngestao},
+	"reconstruir": {"reconstrói o período e o catálogo de marcas de uma tabela ou de todas", cmdReconstruir},
+	"migrar":      {"cria os índices e aplica, simula ou reverte as migrações de dados", cmdMigrar},
+}
+
+func main() {
+	formato := flag.String("formato", formatoTabela, "formato da saída: tabela, json ou csv")
+	timeout := flag.Duration("timeout", 2*time.Minute, "tempo máximo do comando")
+	flag.Usage = uso
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		uso()
+		os.Exit(2)
+	}
+	nome := flag.Arg(0)
+	cmd, ok := comandos[nome]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "fipectl: comando desconhecido '%s'\n\n", nome)
+		uso()
+		os.Exit(2)
+	}
+	if err := validarFormato(*formato); err != nil {
+		fmt.Fprintf(os.Stderr, "fipectl: %v\n", err)
+		os.Exit(2)
+	}
+
+	fs := flag.NewFlagSet("fipectl "+nome, flag.ExitOnError)
+	executar := cmd.configurar(fs)
+	fs.Parse(flag.Args()[1:])
+
+	if err := database.ConnectMongoDB(); err != nil {
+		log.Fatalf("Erro ao conectar no MongoDB: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	resultado, err := executar(ctx)
+	if err != nil {
+		// Comandos que param no meio (migrar) devolvem o que fizeram até ali.
+		if resultado.Dados != nil {
+			imprimir(os.Stdout, *formato, resultado)
+		}
+		fmt.Fprintf(os.Stderr, "fipectl %s: %v\n", nome, err)
+		os.Exit(1)
+	}
+	if err := imprimir(os.Stdout, *formato, resultado); err != nil {
+		fmt.Fprintf(os.Stderr, "fipectl %s: erro ao escrever a saída: %v\n", nome, err)
+		os.Exit(1)
+	}
+}
+
+func uso() {
+	saida := flag.CommandLine.Output()
+	fmt.Fprintf(saida, "Uso: fipectl [opções] <comando> [opções do comando]\n\nComandos:\n")
+	nomes := make([]string, 0, len(comandos))
+	for nome := range comandos {
+		nomes = append(nomes, nome)
+	}
+	sort.Strings(nomes)
+	for _, nome := range nomes {
+		fmt.Fprintf(saida, "  %-12s %s\n", nome, comandos[nome].descricao)
+	}
+	fmt.Fprintf(saida, "\nUse 'fipectl <comando> -h' para as opções de cada comando.\n\nOpções:\n")
+	flag.PrintDefaults()
+}
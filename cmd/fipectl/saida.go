@@ -0,0 +1,52 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	formatoTabela = "tabela"
+	formatoJSON   = "json"
+	formatoCSV    = "csv"
+)
+
+// saida é o resultado de um comando: Dados vai para o JSON como está;
+// Cabecalho e Linhas formam a tabela e o CSV.
+type saida struct {
+	Dados     any
+	Cabecalho []string
+	Linhas    [][]string
+}
+
+func validarFormato(formato string) error {
+	switch formato {
+	case formatoTabela, formatoJSON, formatoCSV:
+		return nil
+	}
+	return fmt.Errorf("formato '%s' inválido: use %s, %s ou %s", formato, formatoTabela, formatoJSON, formatoCSV)
+}
+
+func imprimir(w io.Writer, formato string, s saida) error {
+	switch formato {
+	case formatoJSON:
+		codificador := json.NewEncoder(w)
+		codificador.SetIndent("", "  ")
+		return codificador.Encode(s.Dados)
+	case formatoCSV:
+		escritor := csv.NewWriter(w)
+		escritor.Write(s.Cabecalho)
+		escritor.WriteAll(s.Linhas)
+		return escritor.Error()
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(s.Cabecalho, "\t")))
+	for _, linha := range s.Linhas {
+		fmt.Fprintln(tw, strings.Join(linha, "\t"))
+	}
+	return tw.Flush()
+}
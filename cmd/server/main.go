@@ -0,0 +1,47 @@
+// Comando server atende a API HTTP e os arquivos estáticos do frontend. O
+// trabalho de longa duração (jobs agendados, lotes de avaliação) roda no
+// cmd/worker.
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"fipe_project/internal/alerts"
+	"fipe_project/internal/config"
+	"fipe_project/internal/database"
+	"fipe_project/internal/ipva"
+	"fipe_project/internal/migracoes"
+	"fipe_project/internal/routes"
+)
+
+func main() {
+	cfg, err := config.Carregar()
+	if err != nil {
+		log.Fatalf("Configuração inválida: %v", err)
+	}
+
+	if err := database.ConnectMongoDB(); err != nil {
+		log.Fatalf("Erro ao conectar no MongoDB: %v", err)
+	}
+
+	if cfg.Migracoes != config.MigracoesNenhuma {
+		if err := migracoes.AoIniciar(context.Background(), cfg.Migracoes == config.MigracoesTodas); err != nil {
+			log.Fatalf("Erro nas migrações: %v", err)
+		}
+	}
+
+	// Valida as regras de IPVA (inclusive os exemplos de cada UF) na subida:
+	// com o arquivo inválido, o servidor não sobe.
+	if _, err := ipva.Atual(); err != nil {
+		log.Fatalf("Erro nas regras de IPVA: %v", err)
+	}
+
+	alerts.PermitirHostsInternos(cfg.WebhookHostsInternos)
+
+	router := routes.SetupRoutes(cfg)
+
+	log.Printf("Servidor rodando na porta %s", cfg.Porta)
+	log.Fatal(http.ListenAndServe(":"+cfg.Porta, router))
+}
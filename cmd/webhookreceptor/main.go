@@ -0,0 +1,67 @@
+// Comando webhookreceptor é um receptor local de webhooks da watchlist, para
+// testar alertas sem depender de um serviço externo. Ele confere a assinatura
+// de cada POST recebido e imprime o corpo.
+//
+//	go run ./cmd/webhookreceptor -porta 9000 -segredo <segredo do item>
+//
+// Webhooks para a rede interna são recusados, a menos que o servidor e o
+// worker subam com WEBHOOK_HOSTS_INTERNOS=localhost (ou o host do receptor).
+//
+// Com -falhas N, as N primeiras requisições recebem 500, para exercitar as
+// novas tentativas de entrega.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync/atomic"
+
+	"fipe_project/internal/alerts"
+)
+
+func main() {
+	porta := flag.Int("porta", 9000, "porta HTTP")
+	segredo := flag.String("segredo", "", "segredo do item da watchlist (vazio desativa a verificação)")
+	falhas := flag.Int64("falhas", 0, "número de requisições iniciais respondidas com erro 500")
+	flag.Parse()
+
+	var recebidas int64
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt64(&recebidas, 1)
+		corpo, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		assinatura := r.Header.Get(alerts.HeaderAssinatura)
+		valida := *segredo == "" || alerts.VerificarAssinatura(*segredo, r.Header.Get(alerts.HeaderTimestamp), corpo, assinatura)
+		log.Printf("#%d %s %s evento=%s entrega=%s assinatura válida=%v",
+			n, r.Method, r.URL.Path, r.Header.Get(alerts.HeaderEvento), r.Header.Get(alerts.HeaderEntrega), valida)
+
+		var formatado bytes.Buffer
+		if json.Indent(&formatado, corpo, "", "  ") == nil {
+			fmt.Println(formatado.String())
+		} else {
+			fmt.Println(string(corpo))
+		}
+
+		if !valida {
+			http.Error(w, "assinatura inválida", http.StatusUnauthorized)
+			return
+		}
+		if n <= *falhas {
+			http.Error(w, "falha simulada", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Printf("Receptor de webhooks ouvindo na porta %d", *porta)
+	log.Fatal(http.ListenAndServe(fmt.Sprintf(":%d", *porta), nil))
+}
@@ -0,0 +1,143 @@
+// Comando worker executa os jobs agendados (internal/agendador), como a
+// verificação de novas tabelas que dispara os alertas da watchlist, e as
+// tarefas da fila (internal/fila), como os lotes de avaliação grandes.
+// Vários workers podem rodar ao mesmo tempo: as travas do agendador e os
+// leases da fila evitam que o mesmo trabalho rode em dois deles.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"fipe_project/internal/agendador"
+	"fipe_project/internal/alerts"
+	"fipe_project/internal/config"
+	"fipe_project/internal/database"
+	"fipe_project/internal/fila"
+	"fipe_project/internal/migracoes"
+	"fipe_project/internal/models"
+	"fipe_project/internal/services"
+)
+
+func main() {
+	cfg, err := config.Carregar()
+	if err != nil {
+		log.Fatalf("Configuração inválida: %v", err)
+	}
+	if err := database.ConnectMongoDB(); err != nil {
+		log.Fatalf("Erro ao conectar no MongoDB: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.Migracoes != config.MigracoesNenhuma {
+		if err := migracoes.AoIniciar(ctx, cfg.Migracoes == config.MigracoesTodas); err != nil {
+			log.Fatalf("Erro nas migrações: %v", err)
+		}
+	}
+
+	if err := fila.GarantirIndices(ctx); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if err := agendador.GarantirIndices(ctx); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	alerts.PermitirHostsInternos(cfg.WebhookHostsInternos)
+
+	worker := fila.NovoWorker(cfg.WorkerId, cfg.LeaseTarefas, cfg.IntervaloFila, cfg.WorkerConcorrencia)
+	worker.Registrar(models.TarefaAvaliacaoLote, processarAvaliacaoLote)
+
+	jobs := agendador.Novo(cfg.WorkerId, cfg.FusoAgendador)
+	if err := jobs.Registrar(agendador.JobVerificarTabela, verificarTabela); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := jobs.Registrar(agendador.JobQualidade, verificarQualidade); err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := jobs.Registrar(agendador.JobAquecerCatalogo, aquecerCatalogo); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() { defer wg.Done(); worker.Executar(ctx) }()
+	go func() { defer wg.Done(); jobs.Executar(ctx) }()
+	wg.Wait()
+	log.Printf("Worker %s encerrado", cfg.WorkerId)
+}
+
+func verificarTabela(ctx context.Context) (map[string]int64, error) {
+	verificacao, err := alerts.VerificarNovaTabela(ctx)
+	if err != nil {
+		return nil, err
+	}
+	contagens := map[string]int64{"tabela": int64(verificacao.TabelaId), "marcas": verificacao.Marcas}
+	if verificacao.Publicada {
+		contagens["publicada"] = 1
+		contagens["alertas"] = int64(verificacao.Alertas)
+	}
+	return contagens, nil
+}
+
+// verificarQualidade roda as verificações de qualidade na tabela mais
+// recente e registra os totais por tipo de ocorrência.
+func verificarQualidade(ctx context.Context) (map[string]int64, error) {
+	tabelaId, err := services.UltimaTabelaComVeiculos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	relatorio, err := services.AnalisarQualidade(ctx, tabelaId, nil, services.LimitesQualidadePadrao)
+	if err != nil {
+		return nil, err
+	}
+	contagens := map[string]int64{
+		"tabela":      int64(tabelaId),
+		"entradas":    int64(relatorio.TotalEntradas),
+		"sinalizadas": int64(relatorio.Sinalizadas),
+	}
+	for tipo, total := range relatorio.Totais {
+		contagens[string(tipo)] = int64(total)
+	}
+	return contagens, nil
+}
+
+// aquecerCatalogo grava o catálogo das tabelas que /api/marcas ainda
+// resumiria direto de Veiculos a cada requisição, como as carregadas antes
+// da migração do catálogo ou cuja publicação falhou.
+func aquecerCatalogo(ctx context.Context) (map[string]int64, error) {
+	tabelas, err := services.TabelasSemCatalogo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	contagens := map[string]int64{"tabelas": 0, "marcas": 0}
+	for _, tabelaId := range tabelas {
+		marcas, err := services.AtualizarCatalogoMarcas(ctx, tabelaId)
+		if err != nil {
+			return contagens, err
+		}
+		contagens["tabelas"]++
+		contagens["marcas"] += int64(marcas)
+	}
+	return contagens, nil
+}
+
+func processarAvaliacaoLote(ctx context.Context, tarefa *models.Tarefa) error {
+	id, ok := tarefa.Dados["jobId"].(primitive.ObjectID)
+	if !ok {
+		return fmt.Errorf("tarefa %s sem jobId", tarefa.Id.Hex())
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+	return services.ProcessarJobAvaliacao(ctx, id)
+}
@@ -105,25 +105,33 @@ document.addEventListener('DOMContentLoaded', () => {
         if (modeloVal && tabelaVal1 && tabelaVal2) {
             resultadoComparacao.innerHTML = '<p>Carregando comparação...</p>';
 
-            Promise.all([
-                fetch(`/api/veiculos?modelo=${modeloVal}&tabela=${tabelaVal1}`).then(res => res.json()),
-                fetch(`/api/veiculos?modelo=${modeloVal}&tabela=${tabelaVal2}`).then(res => res.json()),
-            ])
-                .then(([dadosTabela1, dadosTabela2]) => {
-                    if (dadosTabela1.length > 0 && dadosTabela2.length > 0) {
+            fetch(`/api/comparar?veiculo=${modeloVal}&tabela=${tabelaVal1}&tabela=${tabelaVal2}`)
+                .then(res => {
+                    if (!res.ok) throw new Error(`HTTP ${res.status}`);
+                    return res.json();
+                })
+                .then(comparacao => {
+                    const veiculos = (comparacao.veiculos || []).filter(v => !v.erro);
+                    if (veiculos.length > 0) {
                         let html = '<h2>Comparação de Valores</h2>';
                         html += `<h3>${modeloSelect.options[modeloSelect.selectedIndex].text}</h3>`;
 
-                        dadosTabela1.forEach((veiculo2025, index) => {
-                            const veiculo2024 = dadosTabela2[index];
-                            if (veiculo2024) {
-                                html += `
-                                    <div class="vehicle-card">
-                                        <p><strong>Ano do Carro:</strong> ${veiculo2025.year === 32000 ? '0km' : veiculo2025.year}</p>
-                                        <p><strong>${refPeriodo1}:</strong> ${veiculo2025.price.replace(/"/g, '')}</p>
-                                        <p><strong>${refPeriodo2}:</strong> ${veiculo2024.price.replace(/"/g, '')}</p>
-                                    </div>`;
-                            }
+                        veiculos.forEach(veiculo => {
+                            const [preco1, preco2] = veiculo.precos;
+                            const variacao = preco2.variacao !== undefined
+                                ? `<p><strong>Variação:</strong> ${formatarPercentual(preco2.variacao)}</p>`
+                                : '';
+                            const depreciacao = veiculo.depreciacao !== undefined
+                                ? `<p><strong>Em relação ao 0km:</strong> ${formatarPercentual(veiculo.depreciacao)}</p>`
+                                : '';
+                            html += `
+                                <div class="vehicle-card">
+                                    <p><strong>Ano do Carro:</strong> ${veiculo.ano === 32000 ? '0km' : veiculo.ano}</p>
+                                    <p><strong>${refPeriodo1}:</strong> ${preco1.valorFmt}</p>
+                                    <p><strong>${refPeriodo2}:</strong> ${preco2.valorFmt}</p>
+                                    ${variacao}
+                                    ${depreciacao}
+                                </div>`;
                         });
 
                         resultadoComparacao.innerHTML = html;
@@ -162,6 +170,11 @@ function formatarPeriodoParaExibicao(mesAnoString) {
     return `Tabela ${mesAnoString}`;
 }
 
+function formatarPercentual(valor) {
+    const sinal = valor > 0 ? '+' : '';
+    return `${sinal}${valor.toFixed(2).replace('.', ',')}%`;
+}
+
 function capitalizeFirstLetter(string) {
     if (!string) return '';
     return string.charAt(0).toUpperCase() + string.slice(1).toLowerCase();
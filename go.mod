@@ -2,7 +2,10 @@ module fipe_project
 
 go 1.23
 
-require go.mongodb.org/mongo-driver v1.9.1
+require (
+	github.com/go-pdf/fpdf v0.9.0
+	go.mongodb.org/mongo-driver v1.9.1
+)
 
 require github.com/felixge/httpsnoop v1.0.3 // indirect
 
@@ -0,0 +1,218 @@
+package agendador
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"fipe_project/internal/models"
+)
+
+const (
+	// Frequência com que o agendador confere horários vencidos e execuções
+	// manuais pendentes.
+	intervaloVerificacao = 15 * time.Second
+	// Validade da trava de um job, renovada a cada terço enquanto ele roda.
+	validadeTrava = time.Minute
+)
+
+// Funcao executa um job e devolve as contagens a registrar no histórico.
+type Funcao func(ctx context.Context) (map[string]int64, error)
+
+type jobAgendado struct {
+	definicao models.DefinicaoJob
+	cron      Cron
+	funcao    Funcao
+	proxima   time.Time
+}
+
+// Agendador roda os jobs registrados nos horários do cron e as execuções
+// manuais disparadas pela API.
+type Agendador struct {
+	worker string
+	fuso   *time.Location
+	jobs   map[string]*jobAgendado
+}
+
+// Novo cria um agendador. worker identifica a réplica no histórico; os
+// horários do cron são interpretados no fuso informado.
+func Novo(worker string, fuso *time.Location) *Agendador {
+	return &Agendador{worker: worker, fuso: fuso, jobs: make(map[string]*jobAgendado)}
+}
+
+// Registrar associa a função a um job do catálogo. Jobs inativos continuam
+// disponíveis para execução manual.
+func (a *Agendador) Registrar(nome string, f Funcao) error {
+	definicao, err := Definicao(nome)
+	if err != nil {
+		return err
+	}
+	cron, err := ParseCron(definicao.Cron)
+	if err != nil {
+		return err
+	}
+	job := &jobAgendado{definicao: definicao, cron: cron, funcao: f}
+	if definicao.Ativo {
+		job.proxima = cron.Proxima(time.Now().In(a.fuso))
+	}
+	a.jobs[nome] = job
+	return nil
+}
+
+// Executar roda o agendador até ctx ser cancelado e espera as execuções em
+// andamento terminarem. Horários perdidos enquanto nenhum worker estava no ar
+// não são recuperados.
+func (a *Agendador) Executar(ctx context.Context) {
+	for nome, job := range a.jobs {
+		if job.definicao.Ativo {
+			log.Printf("Agendador: job %s (%s), próxima execução %s", nome, job.cron, job.proxima.Format(time.RFC3339))
+		} else {
+			log.Printf("Agendador: job %s inativo, apenas execução manual", nome)
+		}
+	}
+
+	var emAndamento sync.WaitGroup
+	defer emAndamento.Wait()
+	ticker := time.NewTicker(intervaloVerificacao)
+	defer ticker.Stop()
+	for {
+		a.verificar(ctx, &emAndamento)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (a *Agendador) verificar(ctx context.Context, emAndamento *sync.WaitGroup) {
+	if n, err := recuperarAbandonadas(ctx, validadeTrava); err != nil {
+		log.Printf("Agendador: %v", err)
+	} else if n > 0 {
+		log.Printf("Agendador: %d execuções abandonadas marcadas como falha", n)
+	}
+
+	agora := time.Now().In(a.fuso)
+	for nome, job := range a.jobs {
+		if job.proxima.IsZero() || agora.Before(job.proxima) {
+			continue
+		}
+		horario := job.proxima
+		job.proxima = job.cron.Proxima(agora)
+		execucao, criada, err := registrarAgendada(ctx, nome, horario, a.worker)
+		if err != nil {
+			log.Printf("Agendador: %v", err)
+			continue
+		}
+		if !criada {
+			// Outra réplica ficou com este horário.
+			continue
+		}
+		emAndamento.Add(1)
+		go func() {
+			defer emAndamento.Done()
+			a.rodar(ctx, job, execucao)
+		}()
+	}
+
+	nomes := make([]string, 0, len(a.jobs))
+	for nome := range a.jobs {
+		nomes = append(nomes, nome)
+	}
+	for ctx.Err() == nil {
+		execucao, err := assumirManual(ctx, nomes, a.worker)
+		if err != nil {
+			log.Printf("Agendador: %v", err)
+			return
+		}
+		if execucao == nil {
+			return
+		}
+		job := a.jobs[execucao.Job]
+		emAndamento.Add(1)
+		go func() {
+			defer emAndamento.Done()
+			a.rodar(ctx, job, execucao)
+		}()
+	}
+}
+
+// rodar executa o job sob a trava. Sem a trava, outra execução do job está
+// em andamento e esta é registrada como ignorada.
+func (a *Agendador) rodar(ctx context.Context, job *jobAgendado, execucao *models.ExecucaoJob) {
+	nome := job.definicao.Nome
+	dono := execucao.Id.Hex()
+
+	obtida, err := adquirirTrava(ctx, nome, dono, validadeTrava)
+	if err != nil || !obtida {
+		if err == nil {
+			err = fmt.Errorf("outra execução do job está em andamento")
+		}
+		log.Printf("Agendador: job %s ignorado: %v", nome, err)
+		registrarFim(execucao, models.StatusExecucaoIgnorada, err, nil)
+		return
+	}
+	defer func() {
+		fimCtx, fimCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer fimCancel()
+		if err := liberarTrava(fimCtx, nome, dono); err != nil {
+			log.Printf("Agendador: %v", err)
+		}
+	}()
+	if err := iniciarExecucao(ctx, execucao, a.worker); err != nil {
+		log.Printf("Agendador: %v", err)
+	}
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		ticker := time.NewTicker(validadeTrava / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-jobCtx.Done():
+				return
+			case <-ticker.C:
+				if ok, err := renovarTrava(jobCtx, nome, dono, validadeTrava); err != nil || !ok {
+					if jobCtx.Err() == nil {
+						log.Printf("Agendador: trava do job %s perdida (%v), cancelando", nome, err)
+						cancel()
+					}
+					return
+				}
+			}
+		}
+	}()
+
+	log.Printf("Agendador: job %s iniciado (%s)", nome, execucao.Origem)
+	contagens, err := executarFuncao(jobCtx, job.funcao)
+	status := models.StatusExecucaoConcluida
+	if err != nil {
+		status = models.StatusExecucaoFalhou
+		log.Printf("Agendador: job %s falhou: %v", nome, err)
+	} else {
+		log.Printf("Agendador: job %s concluído %v", nome, contagens)
+	}
+	registrarFim(execucao, status, err, contagens)
+}
+
+// registrarFim grava o resultado com um contexto próprio, para que ele não se
+// perca no encerramento do worker.
+func registrarFim(execucao *models.ExecucaoJob, status string, causa error, contagens map[string]int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := finalizarExecucao(ctx, execucao, status, causa, contagens); err != nil {
+		log.Printf("Agendador: %v", err)
+	}
+}
+
+func executarFuncao(ctx context.Context, f Funcao) (contagens map[string]int64, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("pânico: %v", r)
+		}
+	}()
+	return f(ctx)
+}
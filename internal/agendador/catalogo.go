@@ -0,0 +1,117 @@
+// Package agendador executa jobs periódicos do worker com expressões cron.
+// Cada execução fica registrada na coleção ExecucoesJobs (início, fim,
+// status, erro e contagens), e uma trava por job na coleção TravasJobs
+// garante que, com várias réplicas do worker, cada horário agendado rode no
+// máximo uma vez e duas execuções do mesmo job não se sobreponham.
+package agendador
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"fipe_project/internal/models"
+)
+
+// Jobs conhecidos. As funções são registradas pelo worker.
+const (
+	JobVerificarTabela = "verificar_tabela"
+	JobQualidade       = "qualidade"
+	JobAquecerCatalogo = "aquecer_catalogo"
+)
+
+// ArquivoConfigPadrao é usado quando a variável AGENDADOR_JOBS não está
+// definida.
+const ArquivoConfigPadrao = "./config/agendador.json"
+
+var (
+	// ErrJobDesconhecido indica um nome fora do catálogo.
+	ErrJobDesconhecido = errors.New("job desconhecido")
+	// ErrExecucaoPendente indica que o job já tem uma execução manual
+	// aguardando um worker.
+	ErrExecucaoPendente = errors.New("já existe uma execução pendente do job")
+)
+
+var catalogo = []models.DefinicaoJob{
+	{
+		Nome:      JobVerificarTabela,
+		Descricao: "Detecta o fim da ingestão de uma nova tabela, publica o evento e avalia a watchlist",
+		Cron:      "*/5 * * * *",
+		Ativo:     true,
+	},
+	{
+		Nome:      JobQualidade,
+		Descricao: "Verifica a qualidade dos dados da tabela mais recente",
+		Cron:      "0 4 * * *",
+		Ativo:     true,
+	},
+	{
+		Nome:      JobAquecerCatalogo,
+		Descricao: "Grava o catálogo de marcas das tabelas que ainda não o têm",
+		Cron:      "0 3 * * *",
+		Ativo:     true,
+	},
+}
+
+// Definicoes retorna o catálogo com os ajustes do arquivo de configuração.
+// Sem arquivo, valem os padrões.
+func Definicoes() ([]models.DefinicaoJob, error) {
+	definicoes := append([]models.DefinicaoJob(nil), catalogo...)
+
+	caminho := ArquivoConfigPadrao
+	if c := os.Getenv("AGENDADOR_JOBS"); c != "" {
+		caminho = c
+	}
+	dados, err := os.ReadFile(caminho)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("erro ao ler configuração do agendador em %s: %v", caminho, err)
+	}
+	if err == nil {
+		var config models.ConfigAgendador
+		if err := json.Unmarshal(dados, &config); err != nil {
+			return nil, fmt.Errorf("erro ao interpretar configuração do agendador em %s: %v", caminho, err)
+		}
+		for _, ajuste := range config.Jobs {
+			i := indiceJob(definicoes, ajuste.Nome)
+			if i < 0 {
+				return nil, fmt.Errorf("%w em %s: '%s'", ErrJobDesconhecido, caminho, ajuste.Nome)
+			}
+			if ajuste.Cron != "" {
+				definicoes[i].Cron = ajuste.Cron
+			}
+			if ajuste.Ativo != nil {
+				definicoes[i].Ativo = *ajuste.Ativo
+			}
+		}
+	}
+
+	for _, d := range definicoes {
+		if _, err := ParseCron(d.Cron); err != nil {
+			return nil, fmt.Errorf("job %s: %v", d.Nome, err)
+		}
+	}
+	return definicoes, nil
+}
+
+// Definicao retorna a definição de um job.
+func Definicao(nome string) (models.DefinicaoJob, error) {
+	definicoes, err := Definicoes()
+	if err != nil {
+		return models.DefinicaoJob{}, err
+	}
+	i := indiceJob(definicoes, nome)
+	if i < 0 {
+		return models.DefinicaoJob{}, fmt.Errorf("%w: '%s'", ErrJobDesconhecido, nome)
+	}
+	return definicoes[i], nil
+}
+
+func indiceJob(definicoes []models.DefinicaoJob, nome string) int {
+	for i, d := range definicoes {
+		if d.Nome == nome {
+			return i
+		}
+	}
+	return -1
+}
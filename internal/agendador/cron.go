@@ -0,0 +1,142 @@
+package agendador
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron é uma expressão de cinco campos (minuto, hora, dia do mês, mês e dia
+// da semana) com '*', listas ("1,15"), intervalos ("1-5") e passos ("*/10").
+// Também aceita @hourly, @daily, @weekly e @monthly. Como no cron, se dia do
+// mês e dia da semana forem ambos restritos, basta um deles casar.
+type Cron struct {
+	texto                               string
+	minutos, horas, dias, meses, semana uint64
+	diaLivre, semanaLivre               bool
+}
+
+var atalhos = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// ParseCron interpreta a expressão.
+func ParseCron(expressao string) (Cron, error) {
+	texto := strings.TrimSpace(expressao)
+	if atalho, ok := atalhos[texto]; ok {
+		texto = atalho
+	}
+	campos := strings.Fields(texto)
+	if len(campos) != 5 {
+		return Cron{}, fmt.Errorf("expressão cron '%s' deve ter 5 campos", expressao)
+	}
+	c := Cron{texto: expressao}
+	limites := []struct {
+		destino  *uint64
+		min, max int
+	}{
+		{&c.minutos, 0, 59}, {&c.horas, 0, 23}, {&c.dias, 1, 31}, {&c.meses, 1, 12}, {&c.semana, 0, 7},
+	}
+	for i, l := range limites {
+		bits, err := parseCampo(campos[i], l.min, l.max)
+		if err != nil {
+			return Cron{}, fmt.Errorf("expressão cron '%s': %v", expressao, err)
+		}
+		*l.destino = bits
+	}
+	// Domingo pode ser 0 ou 7.
+	if c.semana&(1<<7) != 0 {
+		c.semana = c.semana&^(1<<7) | 1
+	}
+	c.diaLivre = campos[2] == "*"
+	c.semanaLivre = campos[4] == "*"
+	return c, nil
+}
+
+func parseCampo(campo string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, parte := range strings.Split(campo, ",") {
+		intervalo, passoTexto, temPasso := strings.Cut(parte, "/")
+		passo := 1
+		if temPasso {
+			p, err := strconv.Atoi(passoTexto)
+			if err != nil || p < 1 {
+				return 0, fmt.Errorf("passo inválido em '%s'", parte)
+			}
+			passo = p
+		}
+		inicio, fim := min, max
+		if intervalo != "*" {
+			de, ate, temAte := strings.Cut(intervalo, "-")
+			var err error
+			if inicio, err = strconv.Atoi(de); err != nil {
+				return 0, fmt.Errorf("valor inválido em '%s'", parte)
+			}
+			fim = inicio
+			if temAte {
+				if fim, err = strconv.Atoi(ate); err != nil {
+					return 0, fmt.Errorf("valor inválido em '%s'", parte)
+				}
+			} else if temPasso {
+				fim = max
+			}
+		}
+		if inicio < min || fim > max || inicio > fim {
+			return 0, fmt.Errorf("'%s' fora de %d-%d", parte, min, max)
+		}
+		for v := inicio; v <= fim; v += passo {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+func (c Cron) String() string {
+	return c.texto
+}
+
+// Proxima retorna o primeiro instante após t, no fuso de t, que casa com a
+// expressão. Devolve o instante zero se não houver nenhum nos próximos cinco
+// anos (como "0 0 31 2 *").
+func (c Cron) Proxima(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limite := t.AddDate(5, 0, 0)
+	for t.Before(limite) {
+		if c.meses&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.casaDia(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.horas&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minutos&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c Cron) casaDia(t time.Time) bool {
+	dia := c.dias&(1<<uint(t.Day())) != 0
+	semana := c.semana&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.diaLivre && c.semanaLivre:
+		return true
+	case c.diaLivre:
+		return semana
+	case c.semanaLivre:
+		return dia
+	}
+	return dia || semana
+}
@@ -0,0 +1,312 @@
+package agendador
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+)
+
+const (
+	colecaoExecucoes = "ExecucoesJobs"
+	colecaoTravas    = "TravasJobs"
+
+	// Execuções ficam no histórico por este período.
+	retencaoHistorico = 90 * 24 * time.Hour
+	MaxExecucoes      = 500
+)
+
+// GarantirIndices cria os índices do histórico: a unicidade das execuções
+// agendadas, que impede duas réplicas de rodarem o mesmo horário, a de uma
+// execução manual pendente por job e a expiração das execuções antigas.
+func GarantirIndices(ctx context.Context) error {
+	_, err := database.DB.Collection(colecaoExecucoes).Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "job", Value: 1}, {Key: "agendadaPara", Value: 1}},
+			Options: options.Index().SetUnique(true).
+				SetPartialFilterExpression(bson.M{"origem": models.OrigemAgendada}),
+		},
+		{
+			Keys: bson.D{{Key: "job", Value: 1}},
+			Options: options.Index().SetName("job_manual_pendente").SetUnique(true).
+				SetPartialFilterExpression(bson.M{"origem": models.OrigemManual, "status": models.StatusExecucaoPendente}),
+		},
+		{Keys: bson.D{{Key: "job", Value: 1}, {Key: "criadaEm", Value: -1}}},
+		{Keys: bson.D{{Key: "origem", Value: 1}, {Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "criadaEm", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(retencaoHistorico.Seconds()))},
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao criar índices do histórico de jobs: %v", err)
+	}
+	return nil
+}
+
+// Disparar cria uma execução manual pendente, que o próximo worker livre
+// assume. O índice único parcial garante uma única execução pendente por
+// job mesmo com disparos simultâneos.
+func Disparar(ctx context.Context, nome string) (*models.ExecucaoJob, error) {
+	if _, err := Definicao(nome); err != nil {
+		return nil, err
+	}
+	execucao := &models.ExecucaoJob{
+		Job:      nome,
+		Origem:   models.OrigemManual,
+		Status:   models.StatusExecucaoPendente,
+		CriadaEm: time.Now(),
+	}
+	resultado, err := database.DB.Collection(colecaoExecucoes).InsertOne(ctx, execucao)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, fmt.Errorf("%w '%s'", ErrExecucaoPendente, nome)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao disparar job %s: %v", nome, err)
+	}
+	execucao.Id, _ = resultado.InsertedID.(primitive.ObjectID)
+	return execucao, nil
+}
+
+// ListarExecucoes retorna as execuções mais recentes, opcionalmente de um
+// job e de um status.
+func ListarExecucoes(ctx context.Context, job, status string, limite int64) ([]models.ExecucaoJob, error) {
+	filtro := bson.M{}
+	if job != "" {
+		filtro["job"] = job
+	}
+	if status != "" {
+		filtro["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "criadaEm", Value: -1}}).SetLimit(limite)
+	cursor, err := database.DB.Collection(colecaoExecucoes).Find(ctx, filtro, opts)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar execuções de jobs: %v", err)
+	}
+	execucoes := []models.ExecucaoJob{}
+	if err := cursor.All(ctx, &execucoes); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar execuções de jobs: %v", err)
+	}
+	return execucoes, nil
+}
+
+// Resumos lista os jobs com a próxima execução no fuso informado e a última
+// execução registrada.
+func Resumos(ctx context.Context, fuso *time.Location) ([]models.ResumoJob, error) {
+	definicoes, err := Definicoes()
+	if err != nil {
+		return nil, err
+	}
+	agora := time.Now().In(fuso)
+	resumos := make([]models.ResumoJob, 0, len(definicoes))
+	for _, d := range definicoes {
+		resumo := models.ResumoJob{DefinicaoJob: d}
+		if d.Ativo {
+			cron, _ := ParseCron(d.Cron)
+			if proxima := cron.Proxima(agora); !proxima.IsZero() {
+				resumo.Proxima = &proxima
+			}
+		}
+		ultimas, err := ListarExecucoes(ctx, d.Nome, "", 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(ultimas) > 0 {
+			resumo.UltimaExecucao = &ultimas[0]
+		}
+		resumos = append(resumos, resumo)
+	}
+	return resumos, nil
+}
+
+// registrarAgendada cria a execução do horário agendado, pendente até que o
+// worker obtenha a trava do job (ver iniciarExecucao). Se outra réplica já a
+// criou, devolve criada=false.
+func registrarAgendada(ctx context.Context, job string, horario time.Time, worker string) (*models.ExecucaoJob, bool, error) {
+	execucao := &models.ExecucaoJob{
+		Job:          job,
+		Origem:       models.OrigemAgendada,
+		AgendadaPara: &horario,
+		Status:       models.StatusExecucaoPendente,
+		Worker:       worker,
+		CriadaEm:     time.Now(),
+	}
+	resultado, err := database.DB.Collection(colecaoExecucoes).InsertOne(ctx, execucao)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("erro ao registrar execução do job %s: %v", job, err)
+	}
+	execucao.Id, _ = resultado.InsertedID.(primitive.ObjectID)
+	return execucao, true, nil
+}
+
+// assumirManual entrega ao worker a execução manual pendente mais antiga de
+// um dos jobs, ou nil se não houver.
+func assumirManual(ctx context.Context, jobs []string, worker string) (*models.ExecucaoJob, error) {
+	agora := time.Now()
+	var execucao models.ExecucaoJob
+	err := database.DB.Collection(colecaoExecucoes).FindOneAndUpdate(ctx,
+		bson.M{"job": bson.M{"$in": jobs}, "origem": models.OrigemManual, "status": models.StatusExecucaoPendente},
+		bson.M{"$set": bson.M{"status": models.StatusExecucaoExecutando, "worker": worker, "iniciadaEm": agora}},
+		options.FindOneAndUpdate().SetSort(bson.D{{Key: "criadaEm", Value: 1}}).SetReturnDocument(options.After),
+	).Decode(&execucao)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao assumir execução manual: %v", err)
+	}
+	return &execucao, nil
+}
+
+// iniciarExecucao marca a execução como em andamento, depois que o worker
+// obteve a trava do job.
+func iniciarExecucao(ctx context.Context, execucao *models.ExecucaoJob, worker string) error {
+	agora := time.Now()
+	_, err := database.DB.Collection(colecaoExecucoes).UpdateOne(ctx,
+		bson.M{"_id": execucao.Id},
+		bson.M{"$set": bson.M{"status": models.StatusExecucaoExecutando, "worker": worker, "iniciadaEm": agora}},
+	)
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar execução do job %s: %v", execucao.Job, err)
+	}
+	execucao.Status, execucao.Worker, execucao.IniciadaEm = models.StatusExecucaoExecutando, worker, &agora
+	return nil
+}
+
+// recuperarAbandonadas marca como falha as execuções de um worker que parou:
+// as em andamento sem a trava do job válida em nome delas e as agendadas que
+// ficaram pendentes (o worker parou antes de obter a trava). Só considera
+// execuções com mais de ttl, a validade da trava, para não pegar uma que
+// acabou de ser criada. Devolve quantas foram marcadas.
+func recuperarAbandonadas(ctx context.Context, ttl time.Duration) (int, error) {
+	agora := time.Now()
+	limite := agora.Add(-ttl)
+	coll := database.DB.Collection(colecaoExecucoes)
+	cursor, err := coll.Find(ctx, bson.M{"$or": bson.A{
+		bson.M{"status": models.StatusExecucaoExecutando, "iniciadaEm": bson.M{"$lt": limite}},
+		bson.M{"origem": models.OrigemAgendada, "status": models.StatusExecucaoPendente, "criadaEm": bson.M{"$lt": limite}},
+	}}, options.Find().SetProjection(bson.M{"job": 1, "status": 1}))
+	if err != nil {
+		return 0, fmt.Errorf("erro ao buscar execuções abandonadas: %v", err)
+	}
+	var candidatas []models.ExecucaoJob
+	if err := cursor.All(ctx, &candidatas); err != nil {
+		return 0, fmt.Errorf("erro ao decodificar execuções abandonadas: %v", err)
+	}
+	if len(candidatas) == 0 {
+		return 0, nil
+	}
+
+	jobs := make([]string, 0, len(candidatas))
+	for _, e := range candidatas {
+		jobs = append(jobs, e.Job)
+	}
+	cursor, err = database.DB.Collection(colecaoTravas).Find(ctx, bson.M{"_id": bson.M{"$in": jobs}, "expiraEm": bson.M{"$gte": agora}})
+	if err != nil {
+		return 0, fmt.Errorf("erro ao buscar travas de jobs: %v", err)
+	}
+	var travas []struct {
+		Dono string `bson:"dono"`
+	}
+	if err := cursor.All(ctx, &travas); err != nil {
+		return 0, fmt.Errorf("erro ao decodificar travas de jobs: %v", err)
+	}
+	vivas := make(map[string]bool, len(travas))
+	for _, t := range travas {
+		vivas[t.Dono] = true
+	}
+
+	marcadas := 0
+	for _, e := range candidatas {
+		if vivas[e.Id.Hex()] {
+			continue
+		}
+		resultado, err := coll.UpdateOne(ctx,
+			bson.M{"_id": e.Id, "status": e.Status},
+			bson.M{"$set": bson.M{"status": models.StatusExecucaoFalhou, "erro": "worker interrompido durante a execução", "finalizadaEm": agora}},
+		)
+		if err != nil {
+			return marcadas, fmt.Errorf("erro ao marcar execução abandonada do job %s: %v", e.Job, err)
+		}
+		marcadas += int(resultado.ModifiedCount)
+	}
+	return marcadas, nil
+}
+
+// finalizarExecucao grava o resultado de uma execução.
+func finalizarExecucao(ctx context.Context, execucao *models.ExecucaoJob, status string, causa error, contagens map[string]int64) error {
+	agora := time.Now()
+	set := bson.M{"status": status, "finalizadaEm": agora}
+	if execucao.IniciadaEm != nil {
+		set["duracaoMs"] = agora.Sub(*execucao.IniciadaEm).Milliseconds()
+	}
+	if causa != nil {
+		set["erro"] = causa.Error()
+	}
+	if len(contagens) > 0 {
+		set["contagens"] = contagens
+	}
+	_, err := database.DB.Collection(colecaoExecucoes).UpdateOne(ctx, bson.M{"_id": execucao.Id}, bson.M{"$set": set})
+	if err != nil {
+		return fmt.Errorf("erro ao gravar execução do job %s: %v", execucao.Job, err)
+	}
+	return nil
+}
+
+// adquirirTrava obtém a trava do job para a execução dona, com validade ttl.
+// Se a trava existente estiver vencida (worker parado no meio de uma
+// execução), ela é tomada e a execução anterior é marcada como falha.
+func adquirirTrava(ctx context.Context, job, dono string, ttl time.Duration) (bool, error) {
+	coll := database.DB.Collection(colecaoTravas)
+	agora := time.Now()
+	var anterior struct {
+		Dono string `bson:"dono"`
+	}
+	err := coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": job, "expiraEm": bson.M{"$lt": agora}},
+		bson.M{"$set": bson.M{"dono": dono, "expiraEm": agora.Add(ttl)}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.Before),
+	).Decode(&anterior)
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil && err != mongo.ErrNoDocuments {
+		return false, fmt.Errorf("erro ao adquirir trava do job %s: %v", job, err)
+	}
+	if err == nil && anterior.Dono != "" {
+		if id, errId := primitive.ObjectIDFromHex(anterior.Dono); errId == nil {
+			database.DB.Collection(colecaoExecucoes).UpdateOne(ctx,
+				bson.M{"_id": id, "status": models.StatusExecucaoExecutando},
+				bson.M{"$set": bson.M{"status": models.StatusExecucaoFalhou, "erro": "worker interrompido durante a execução", "finalizadaEm": agora}},
+			)
+		}
+	}
+	return true, nil
+}
+
+// renovarTrava estende a trava; devolve false se ela não pertence mais ao
+// dono.
+func renovarTrava(ctx context.Context, job, dono string, ttl time.Duration) (bool, error) {
+	resultado, err := database.DB.Collection(colecaoTravas).UpdateOne(ctx,
+		bson.M{"_id": job, "dono": dono},
+		bson.M{"$set": bson.M{"expiraEm": time.Now().Add(ttl)}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("erro ao renovar trava do job %s: %v", job, err)
+	}
+	return resultado.MatchedCount == 1, nil
+}
+
+func liberarTrava(ctx context.Context, job, dono string) error {
+	if _, err := database.DB.Collection(colecaoTravas).DeleteOne(ctx, bson.M{"_id": job, "dono": dono}); err != nil {
+		return fmt.Errorf("erro ao liberar trava do job %s: %v", job, err)
+	}
+	return nil
+}
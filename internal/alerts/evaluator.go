@@ -0,0 +1,133 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/events"
+	"fipe_project/internal/models"
+	"fipe_project/internal/services"
+	"fipe_project/internal/utils"
+)
+
+const EventoAlertaPreco = "alerta_preco"
+
+// AvaliarTabela compara, para cada item ativo da watchlist, o preço na tabela
+// informada com o da tabela anterior e entrega um webhook para os que variaram
+// além do limite. Itens que já tiveram alerta para essa tabela são ignorados,
+// então a avaliação pode ser repetida com segurança. Retorna os alertas disparados.
+func AvaliarTabela(ctx context.Context, tabelaId int) ([]models.AlertaPreco, error) {
+	tabelas, err := services.TabelasAte(ctx, tabelaId, 2)
+	if err != nil {
+		return nil, err
+	}
+	if len(tabelas) < 2 || tabelas[1].Codigo != tabelaId {
+		return nil, fmt.Errorf("tabela %d não possui tabela anterior para comparação", tabelaId)
+	}
+	anterior, atual := tabelas[0], tabelas[1]
+
+	itens, err := Listar(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var disparados []models.AlertaPreco
+	var wg sync.WaitGroup
+	for _, item := range itens {
+		if !item.Ativo {
+			continue
+		}
+		jaAvaliado, err := database.DB.Collection(colecaoEntregas).CountDocuments(ctx, bson.M{"watchId": item.Id, "tabelaId": tabelaId})
+		if err != nil {
+			return nil, fmt.Errorf("erro ao consultar entregas do item %s: %v", item.Id.Hex(), err)
+		}
+		if jaAvaliado > 0 {
+			continue
+		}
+
+		alerta, err := avaliarItem(ctx, item, anterior, atual)
+		if err != nil {
+			log.Printf("Watchlist %s: %v", item.Id.Hex(), err)
+			continue
+		}
+		if alerta == nil {
+			continue
+		}
+
+		disparados = append(disparados, *alerta)
+		wg.Add(1)
+		go func(item models.ItemWatchlist, alerta models.AlertaPreco) {
+			defer wg.Done()
+			if _, err := events.Publicar(ctx, events.AlertaDisparado, alerta); err != nil {
+				log.Printf("Erro ao publicar evento do alerta do item %s: %v", item.Id.Hex(), err)
+			}
+			if _, err := Entregar(ctx, item, tabelaId, alerta); err != nil {
+				log.Printf("Erro ao entregar alerta do item %s: %v", item.Id.Hex(), err)
+			}
+		}(item, *alerta)
+	}
+	wg.Wait()
+
+	log.Printf("Watchlist avaliada para a tabela %d: %d alerta(s) disparado(s)", tabelaId, len(disparados))
+	return disparados, nil
+}
+
+// avaliarItem retorna o alerta do item se a variação entre as tabelas passou
+// do limite, ou nil caso contrário.
+func avaliarItem(ctx context.Context, item models.ItemWatchlist, anterior, atual models.TabelaReferencia) (*models.AlertaPreco, error) {
+	precoAnterior, _, err := precoNaTabela(ctx, item, anterior)
+	if err != nil {
+		return nil, err
+	}
+	precoAtual, marca, err := precoNaTabela(ctx, item, atual)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, ok := utils.CalculatePercentageDiff(precoAtual.Valor.Float64(), precoAnterior.Valor.Float64())
+	if !ok || math.Abs(*diff) < item.LimitePercentual {
+		return nil, nil
+	}
+
+	return &models.AlertaPreco{
+		Evento:             EventoAlertaPreco,
+		WatchId:            item.Id,
+		Nome:               item.Nome,
+		BrandName:          marca.BrandName,
+		ModelCode:          item.ModelCode,
+		ModelName:          marca.Models[0].ModelName,
+		Ano:                item.Ano,
+		TabelaAnterior:     precoAnterior,
+		TabelaAtual:        precoAtual,
+		VariacaoPercentual: math.Round(*diff*100) / 100,
+		LimitePercentual:   item.LimitePercentual,
+		DisparadoEm:        time.Now(),
+	}, nil
+}
+
+func precoNaTabela(ctx context.Context, item models.ItemWatchlist, tabela models.TabelaReferencia) (models.PrecoAlerta, *models.Marca, error) {
+	marca, modelo, err := services.BuscarModelo(ctx, tabela.Codigo, int(item.ModelCode))
+	if errors.Is(err, services.ErrNaoEncontrado) {
+		return models.PrecoAlerta{}, nil, fmt.Errorf("modelo %d ausente na tabela %d", item.ModelCode, tabela.Codigo)
+	}
+	if err != nil {
+		return models.PrecoAlerta{}, nil, err
+	}
+	anoModelo, ok := modelo.Ano(item.Ano)
+	if !ok {
+		return models.PrecoAlerta{}, nil, fmt.Errorf("ano %d do modelo %d ausente na tabela %d", item.Ano, item.ModelCode, tabela.Codigo)
+	}
+	valor, err := anoModelo.Valor()
+	if err != nil {
+		return models.PrecoAlerta{}, nil, err
+	}
+	return models.PrecoAlerta{TabelaId: tabela.Codigo, Ref: tabela.Mes, Periodo: models.PeriodoDoMes(tabela.Mes), Valor: valor, ValorFmt: valor.String()}, marca, nil
+}
@@ -0,0 +1,143 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/events"
+	"fipe_project/internal/models"
+	"fipe_project/internal/services"
+)
+
+const colecaoEstado = "AlertasEstado"
+
+// VerificacaoTabela é o resultado de VerificarNovaTabela: a tabela mais
+// recente em Veiculos, quantas marcas ela tem e, se foi publicada nesta
+// verificação, quantos alertas gerou.
+type VerificacaoTabela struct {
+	TabelaId  int
+	Marcas    int64
+	Publicada bool
+	Alertas   int
+}
+
+// VerificarNovaTabela confere se uma nova tabela de referência terminou de
+// ser ingerida e, nesse caso, a publica (ver PublicarTabela). A ingestão é
+// feita fora desta aplicação, então uma tabela só é considerada completa
+// quando sua contagem de documentos em Veiculos para de mudar entre duas
+// verificações. A contagem anterior fica no estado do monitor, e não em
+// memória, para que verificações seguidas possam rodar em workers
+// diferentes.
+func VerificarNovaTabela(ctx context.Context) (*VerificacaoTabela, error) {
+	tabelaId, err := services.UltimaTabelaComVeiculos(ctx)
+	if errors.Is(err, services.ErrNaoEncontrado) {
+		return &VerificacaoTabela{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	verificacao := &VerificacaoTabela{TabelaId: tabelaId}
+
+	estado := database.DB.Collection(colecaoEstado)
+	var atual struct {
+		UltimaTabela     int   `bson:"ultimaTabela"`
+		TabelaPendente   int   `bson:"tabelaPendente"`
+		ContagemPendente int64 `bson:"contagemPendente"`
+	}
+	err = estado.FindOne(ctx, bson.M{"_id": "monitor"}).Decode(&atual)
+	if err == mongo.ErrNoDocuments {
+		// Primeira execução: apenas marca a tabela atual, sem disparar alertas retroativos.
+		return verificacao, salvarUltimaTabela(ctx, tabelaId)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler estado do monitor: %v", err)
+	}
+	if tabelaId <= atual.UltimaTabela {
+		return verificacao, nil
+	}
+
+	contagem, err := services.ContarMarcas(ctx, tabelaId)
+	if err != nil {
+		return nil, err
+	}
+	verificacao.Marcas = contagem
+	if atual.TabelaPendente != tabelaId || atual.ContagemPendente != contagem {
+		log.Printf("Monitor da watchlist: tabela %d em ingestão (%d marcas)", tabelaId, contagem)
+		_, err := estado.UpdateOne(ctx,
+			bson.M{"_id": "monitor"},
+			bson.M{"$set": bson.M{"tabelaPendente": tabelaId, "contagemPendente": contagem, "atualizadoEm": time.Now()}},
+		)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao salvar estado do monitor: %v", err)
+		}
+		return verificacao, nil
+	}
+
+	log.Printf("Monitor da watchlist: tabela %d ingerida, avaliando alertas", tabelaId)
+	alertas, err := PublicarTabela(ctx, tabelaId, contagem)
+	if err != nil {
+		return nil, err
+	}
+	verificacao.Publicada = true
+	verificacao.Alertas = len(alertas)
+	return verificacao, nil
+}
+
+// PublicarTabela executa a etapa pós-ingestão de uma tabela: grava o período
+// e as contagens da tabela e o combustível dos anos-modelo, atualiza o catálogo de marcas, avalia a watchlist contra ela,
+// registra a tabela como processada pelo monitor e como publicada em
+// TabelaReferencia e, só então, publica o evento TabelaPublicada. É chamada
+// pelo monitor quando a ingestão termina e pode ser disparada manualmente
+// (fipectl ingestao) para reprocessar uma tabela. Devolve os alertas gerados.
+func PublicarTabela(ctx context.Context, tabelaId int, marcas int64) ([]models.AlertaPreco, error) {
+	if err := services.AtualizarPeriodoTabela(ctx, tabelaId); err != nil {
+		return nil, err
+	}
+	if err := services.AtualizarContagemTabela(ctx, tabelaId); err != nil {
+		return nil, err
+	}
+	if _, err := services.GravarCombustivelTabela(ctx, tabelaId); err != nil {
+		return nil, err
+	}
+	if _, err := services.AtualizarCatalogoMarcas(ctx, tabelaId); err != nil {
+		return nil, err
+	}
+	alertas, err := AvaliarTabela(ctx, tabelaId)
+	if err != nil {
+		return nil, err
+	}
+	if err := salvarUltimaTabela(ctx, tabelaId); err != nil {
+		return alertas, err
+	}
+	if err := services.MarcarTabelaPublicada(ctx, tabelaId); err != nil {
+		return alertas, err
+	}
+	ref, err := services.TabelaRef(ctx, tabelaId)
+	if err != nil {
+		return alertas, err
+	}
+	if _, err := events.Publicar(ctx, events.TabelaPublicada, bson.M{"tabelaId": tabelaId, "ref": ref, "marcas": marcas}); err != nil {
+		log.Printf("Monitor da watchlist: %v", err)
+	}
+	return alertas, nil
+}
+
+func salvarUltimaTabela(ctx context.Context, tabelaId int) error {
+	_, err := database.DB.Collection(colecaoEstado).UpdateOne(ctx,
+		bson.M{"_id": "monitor"},
+		bson.M{"$max": bson.M{"ultimaTabela": tabelaId}, "$set": bson.M{"atualizadoEm": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("erro ao salvar estado do monitor: %v", err)
+	}
+	return nil
+}
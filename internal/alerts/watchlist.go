@@ -0,0 +1,137 @@
+// Package alerts mantém a watchlist de anos-modelo, avalia as variações de
+// preço a cada nova tabela de referência e entrega os alertas via webhook.
+package alerts
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+	"fipe_project/internal/services"
+)
+
+const colecaoWatchlist = "Watchlist"
+
+// ErrWatchInvalido indica que o item da watchlist enviado não passou na validação.
+var ErrWatchInvalido = errors.New("item da watchlist inválido")
+
+func validar(item *models.ItemWatchlist) error {
+	if item.ModelCode <= 0 {
+		return fmt.Errorf("%w: 'modelCode' é obrigatório", ErrWatchInvalido)
+	}
+	if item.Ano <= 0 {
+		return fmt.Errorf("%w: 'ano' é obrigatório", ErrWatchInvalido)
+	}
+	if item.LimitePercentual <= 0 {
+		return fmt.Errorf("%w: 'limitePercentual' deve ser maior que zero", ErrWatchInvalido)
+	}
+	u, err := url.Parse(item.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: 'webhookUrl' deve ser uma URL http(s)", ErrWatchInvalido)
+	}
+	if hostInterno(u.Hostname()) {
+		return fmt.Errorf("%w: 'webhookUrl' não pode apontar para a rede interna", ErrWatchInvalido)
+	}
+	return nil
+}
+
+// Criar valida e grava um novo item. Se o segredo não for informado, um é gerado;
+// ele é usado para assinar os webhooks e só é devolvido na criação.
+func Criar(ctx context.Context, item *models.ItemWatchlist) error {
+	if err := validar(item); err != nil {
+		return err
+	}
+	if item.Segredo == "" {
+		segredo, err := gerarSegredo()
+		if err != nil {
+			return err
+		}
+		item.Segredo = segredo
+	}
+	agora := time.Now()
+	item.Id = primitive.NewObjectID()
+	item.Ativo = true
+	item.CriadoEm = agora
+	item.AtualizadoEm = agora
+	if _, err := database.DB.Collection(colecaoWatchlist).InsertOne(ctx, item); err != nil {
+		return fmt.Errorf("erro ao criar item da watchlist: %v", err)
+	}
+	return nil
+}
+
+func Listar(ctx context.Context) ([]models.ItemWatchlist, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "criadoEm", Value: 1}})
+	cursor, err := database.DB.Collection(colecaoWatchlist).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar watchlist: %v", err)
+	}
+	itens := []models.ItemWatchlist{}
+	if err := cursor.All(ctx, &itens); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar watchlist: %v", err)
+	}
+	return itens, nil
+}
+
+func Buscar(ctx context.Context, id primitive.ObjectID) (*models.ItemWatchlist, error) {
+	var item models.ItemWatchlist
+	err := database.DB.Collection(colecaoWatchlist).FindOne(ctx, bson.M{"_id": id}).Decode(&item)
+	if err == mongo.ErrNoDocuments {
+		return nil, services.ErrNaoEncontrado
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar item %s da watchlist: %v", id.Hex(), err)
+	}
+	return &item, nil
+}
+
+// Atualizar substitui os campos editáveis de um item. O segredo é mantido
+// quando não for enviado.
+func Atualizar(ctx context.Context, id primitive.ObjectID, item *models.ItemWatchlist) error {
+	atual, err := Buscar(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := validar(item); err != nil {
+		return err
+	}
+	if item.Segredo == "" {
+		item.Segredo = atual.Segredo
+	}
+	item.Id = id
+	item.CriadoEm = atual.CriadoEm
+	item.AtualizadoEm = time.Now()
+	if _, err := database.DB.Collection(colecaoWatchlist).ReplaceOne(ctx, bson.M{"_id": id}, item); err != nil {
+		return fmt.Errorf("erro ao atualizar item %s da watchlist: %v", id.Hex(), err)
+	}
+	return nil
+}
+
+func Remover(ctx context.Context, id primitive.ObjectID) error {
+	res, err := database.DB.Collection(colecaoWatchlist).DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return fmt.Errorf("erro ao remover item %s da watchlist: %v", id.Hex(), err)
+	}
+	if res.DeletedCount == 0 {
+		return services.ErrNaoEncontrado
+	}
+	return nil
+}
+
+func gerarSegredo() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("erro ao gerar segredo do webhook: %v", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,244 @@
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+)
+
+const colecaoEntregas = "WebhookEntregas"
+
+// Cabeçalhos enviados em cada POST de webhook. A assinatura é o HMAC-SHA256,
+// com o segredo do item da watchlist, de "<timestamp>.<corpo>".
+const (
+	HeaderAssinatura = "X-Fipe-Assinatura"
+	HeaderTimestamp  = "X-Fipe-Timestamp"
+	HeaderEvento     = "X-Fipe-Evento"
+	HeaderEntrega    = "X-Fipe-Entrega"
+)
+
+var (
+	// MaxTentativas é o número de envios antes de marcar a entrega como falha.
+	MaxTentativas = 5
+	// IntervaloInicial é a espera antes da primeira nova tentativa; dobra a cada falha.
+	IntervaloInicial = 2 * time.Second
+
+	clienteWebhook = &http.Client{Timeout: 10 * time.Second, Transport: transporteWebhook()}
+)
+
+// ErrDestinoInterno indica um webhook que resolve para um endereço da rede
+// interna (loopback, privado, link-local ou não especificado).
+var ErrDestinoInterno = errors.New("destino do webhook na rede interna")
+
+// enderecoInterno informa se o IP não pode receber webhooks.
+func enderecoInterno(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+}
+
+// hostsPermitidos são os hosts liberados por PermitirHostsInternos.
+var hostsPermitidos map[string]bool
+
+// PermitirHostsInternos libera webhooks para os hosts informados (nomes ou
+// IPs, como na URL), mesmo na rede interna. Serve para testar as entregas com
+// um receptor local; sem chamada, nenhum host interno é aceito. Deve ser
+// chamada na subida, antes de qualquer entrega.
+func PermitirHostsInternos(hosts []string) {
+	hostsPermitidos = make(map[string]bool, len(hosts))
+	for _, h := range hosts {
+		hostsPermitidos[normalizarHost(h)] = true
+	}
+}
+
+func normalizarHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// hostPermitido informa se o host foi liberado por PermitirHostsInternos.
+func hostPermitido(host string) bool {
+	return hostsPermitidos[normalizarHost(host)]
+}
+
+// hostInterno informa se o host da URL é, sem resolver nomes, um endereço
+// interno ou localhost não liberado. Nomes que resolvem para a rede interna
+// são barrados na conexão (ver transporteWebhook).
+func hostInterno(host string) bool {
+	if hostPermitido(host) {
+		return false
+	}
+	host = normalizarHost(host)
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && enderecoInterno(ip)
+}
+
+// transporteWebhook confere o IP de cada conexão depois da resolução do
+// nome, o que cobre redirecionamentos e nomes que mudam de endereço depois
+// do cadastro. Hosts liberados por PermitirHostsInternos conectam sem a
+// conferência. Não usa proxy, para que o IP conferido seja o do destino.
+func transporteWebhook() *http.Transport {
+	livre := &net.Dialer{Timeout: 10 * time.Second}
+	conferido := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(_, endereco string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(endereco)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || enderecoInterno(ip) {
+				return fmt.Errorf("%w: %s", ErrDestinoInterno, host)
+			}
+			return nil
+		},
+	}
+	transporte := http.DefaultTransport.(*http.Transport).Clone()
+	transporte.Proxy = nil
+	transporte.DialContext = func(ctx context.Context, rede, endereco string) (net.Conn, error) {
+		if host, _, err := net.SplitHostPort(endereco); err == nil && hostPermitido(host) {
+			return livre.DialContext(ctx, rede, endereco)
+		}
+		return conferido.DialContext(ctx, rede, endereco)
+	}
+	return transporte
+}
+
+// Assinar calcula a assinatura enviada em HeaderAssinatura.
+func Assinar(segredo, timestamp string, corpo []byte) string {
+	mac := hmac.New(sha256.New, []byte(segredo))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(corpo)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerificarAssinatura confere, em tempo constante, a assinatura recebida por um webhook.
+func VerificarAssinatura(segredo, timestamp string, corpo []byte, assinatura string) bool {
+	return hmac.Equal([]byte(Assinar(segredo, timestamp, corpo)), []byte(assinatura))
+}
+
+// Entregar registra a entrega em WebhookEntregas e envia o alerta ao webhook
+// do item, repetindo com espera exponencial até MaxTentativas.
+func Entregar(ctx context.Context, item models.ItemWatchlist, tabelaId int, alerta models.AlertaPreco) (*models.EntregaWebhook, error) {
+	coll := database.DB.Collection(colecaoEntregas)
+	entrega := &models.EntregaWebhook{
+		Id:         primitive.NewObjectID(),
+		WatchId:    item.Id,
+		TabelaId:   tabelaId,
+		URL:        item.WebhookURL,
+		Evento:     alerta.Evento,
+		Payload:    alerta,
+		Status:     models.StatusEntregaPendente,
+		Tentativas: []models.TentativaEntrega{},
+		CriadoEm:   time.Now(),
+	}
+	if _, err := coll.InsertOne(ctx, entrega); err != nil {
+		return nil, fmt.Errorf("erro ao registrar entrega de webhook: %v", err)
+	}
+
+	corpo, err := json.Marshal(alerta)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar alerta: %v", err)
+	}
+
+	espera := IntervaloInicial
+envio:
+	for tentativa := 1; tentativa <= MaxTentativas; tentativa++ {
+		resultado := enviar(ctx, item, entrega.Id, alerta.Evento, corpo)
+		entrega.Tentativas = append(entrega.Tentativas, resultado)
+		if _, err := coll.UpdateOne(ctx, bson.M{"_id": entrega.Id}, bson.M{"$push": bson.M{"tentativas": resultado}}); err != nil {
+			log.Printf("Erro ao registrar tentativa da entrega %s: %v", entrega.Id.Hex(), err)
+		}
+		if resultado.Erro == "" {
+			entrega.Status = models.StatusEntregaEntregue
+			break
+		}
+		log.Printf("Webhook %s (entrega %s) falhou na tentativa %d/%d: %s", item.WebhookURL, entrega.Id.Hex(), tentativa, MaxTentativas, resultado.Erro)
+		if tentativa == MaxTentativas {
+			entrega.Status = models.StatusEntregaFalhou
+			break
+		}
+		select {
+		case <-ctx.Done():
+			entrega.Status = models.StatusEntregaFalhou
+			break envio
+		case <-time.After(espera):
+			espera *= 2
+		}
+	}
+
+	if _, err := coll.UpdateOne(ctx, bson.M{"_id": entrega.Id}, bson.M{"$set": bson.M{"status": entrega.Status}}); err != nil {
+		log.Printf("Erro ao atualizar status da entrega %s: %v", entrega.Id.Hex(), err)
+	}
+	return entrega, nil
+}
+
+func enviar(ctx context.Context, item models.ItemWatchlist, entregaId primitive.ObjectID, evento string, corpo []byte) models.TentativaEntrega {
+	inicio := time.Now()
+	resultado := models.TentativaEntrega{Em: inicio}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, item.WebhookURL, bytes.NewReader(corpo))
+	if err != nil {
+		resultado.Erro = err.Error()
+		return resultado
+	}
+	timestamp := strconv.FormatInt(inicio.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "fipe-project-webhook/1.0")
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderAssinatura, Assinar(item.Segredo, timestamp, corpo))
+	req.Header.Set(HeaderEvento, evento)
+	req.Header.Set(HeaderEntrega, entregaId.Hex())
+
+	resp, err := clienteWebhook.Do(req)
+	resultado.DuracaoMs = time.Since(inicio).Milliseconds()
+	if err != nil {
+		resultado.Erro = err.Error()
+		return resultado
+	}
+	defer resp.Body.Close()
+	resultado.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		resultado.Erro = fmt.Sprintf("status HTTP %d", resp.StatusCode)
+	}
+	return resultado
+}
+
+// ListarEntregas retorna o log de entregas, mais recentes primeiro, opcionalmente
+// filtrado por item da watchlist.
+func ListarEntregas(ctx context.Context, watchId *primitive.ObjectID, limite int64) ([]models.EntregaWebhook, error) {
+	filter := bson.M{}
+	if watchId != nil {
+		filter["watchId"] = *watchId
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "criadoEm", Value: -1}}).SetLimit(limite)
+	cursor, err := database.DB.Collection(colecaoEntregas).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar entregas de webhook: %v", err)
+	}
+	entregas := []models.EntregaWebhook{}
+	if err := cursor.All(ctx, &entregas); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar entregas de webhook: %v", err)
+	}
+	return entregas, nil
+}
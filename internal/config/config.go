@@ -0,0 +1,117 @@
+// Package config lê do ambiente as configurações comuns ao servidor e ao
+// worker. A conexão com o MongoDB (MONGO_URI e MONGO_DATABASE) fica no pacote
+// database, e os arquivos de regras em seus próprios pacotes.
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	// Base de fusos embutida, para o agendador não depender do sistema.
+	_ "time/tzdata"
+)
+
+type Config struct {
+	// Porta do servidor HTTP (PORTA).
+	Porta string
+	// AdminToken é exigido como "Authorization: Bearer" nas rotas /api/admin
+	// e /api/watchlist (ADMIN_TOKEN); sem ele, essas rotas ficam fechadas.
+	AdminToken string
+	// FusoAgendador é o fuso das expressões cron do agendador
+	// (AGENDADOR_FUSO).
+	FusoAgendador *time.Location
+	// WorkerId identifica o worker nos leases (WORKER_ID); o padrão é
+	// hostname-pid.
+	WorkerId string
+	// WorkerConcorrencia é quantas tarefas o worker executa ao mesmo tempo
+	// (WORKER_CONCORRENCIA).
+	WorkerConcorrencia int
+	// LeaseTarefas é a validade do lease de uma tarefa (FILA_LEASE).
+	LeaseTarefas time.Duration
+	// IntervaloFila é a espera entre buscas com a fila vazia (FILA_INTERVALO).
+	IntervaloFila time.Duration
+	// WebhookHostsInternos são os hosts da rede interna que podem receber
+	// webhooks da watchlist (WEBHOOK_HOSTS_INTERNOS, separados por vírgula).
+	// Vazio por padrão: nenhum destino interno é aceito.
+	WebhookHostsInternos []string
+	// Migracoes define o que roda na subida (MIGRACOES): MigracoesTodas
+	// (índices e migrações de dados), MigracoesIndices ou MigracoesNenhuma.
+	Migracoes string
+}
+
+const (
+	MigracoesTodas   = "todas"
+	MigracoesIndices = "indices"
+	MigracoesNenhuma = "nenhuma"
+)
+
+// Carregar lê a configuração, com valores padrão para variáveis ausentes.
+func Carregar() (Config, error) {
+	c := Config{
+		Porta:              "8080",
+		WorkerConcorrencia: 2,
+		LeaseTarefas:       2 * time.Minute,
+		IntervaloFila:      5 * time.Second,
+		Migracoes:          MigracoesTodas,
+	}
+	if v := os.Getenv("PORTA"); v != "" {
+		c.Porta = v
+	}
+	c.AdminToken = os.Getenv("ADMIN_TOKEN")
+	if v := os.Getenv("MIGRACOES"); v != "" {
+		switch v {
+		case MigracoesTodas, MigracoesIndices, MigracoesNenhuma:
+			c.Migracoes = v
+		default:
+			return Config{}, fmt.Errorf("MIGRACOES inválido: '%s'", v)
+		}
+	}
+	fuso := "America/Sao_Paulo"
+	if v := os.Getenv("AGENDADOR_FUSO"); v != "" {
+		fuso = v
+	}
+	var err error
+	if c.FusoAgendador, err = time.LoadLocation(fuso); err != nil {
+		return Config{}, fmt.Errorf("AGENDADOR_FUSO inválido: '%s'", fuso)
+	}
+	duracoes := []struct {
+		nome    string
+		destino *time.Duration
+	}{
+		{"FILA_LEASE", &c.LeaseTarefas},
+		{"FILA_INTERVALO", &c.IntervaloFila},
+	}
+	for _, d := range duracoes {
+		v := os.Getenv(d.nome)
+		if v == "" {
+			continue
+		}
+		duracao, err := time.ParseDuration(v)
+		if err != nil || duracao <= 0 {
+			return Config{}, fmt.Errorf("%s inválido: '%s'", d.nome, v)
+		}
+		*d.destino = duracao
+	}
+	if v := os.Getenv("WORKER_CONCORRENCIA"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return Config{}, fmt.Errorf("WORKER_CONCORRENCIA inválido: '%s'", v)
+		}
+		c.WorkerConcorrencia = n
+	}
+	if v := os.Getenv("WEBHOOK_HOSTS_INTERNOS"); v != "" {
+		for _, h := range strings.Split(v, ",") {
+			if h = strings.TrimSpace(h); h != "" {
+				c.WebhookHostsInternos = append(c.WebhookHostsInternos, h)
+			}
+		}
+	}
+	c.WorkerId = os.Getenv("WORKER_ID")
+	if c.WorkerId == "" {
+		host, _ := os.Hostname()
+		c.WorkerId = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	return c, nil
+}
@@ -0,0 +1,96 @@
+// Package events publica eventos da aplicação (novas tabelas, estatísticas
+// reconstruídas, alertas) em uma coleção do MongoDB, de onde são lidos pelo
+// stream /api/eventos. Guardar os eventos no banco permite retomar o stream
+// pelo Last-Event-ID e publicar a partir de qualquer processo.
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+)
+
+// Tipos de evento publicados.
+const (
+	TabelaPublicada           = "tabela_publicada"
+	EstatisticasReconstruidas = "estatisticas_reconstruidas"
+	AlertaDisparado           = "alerta_disparado"
+)
+
+const (
+	colecaoEventos    = "Eventos"
+	colecaoContadores = "Contadores"
+)
+
+// Publicar grava um evento com o próximo id da sequência. dados deve ser um
+// documento (struct ou mapa) serializável em BSON.
+func Publicar(ctx context.Context, tipo string, dados interface{}) (*models.Evento, error) {
+	raw, err := bson.Marshal(dados)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao serializar dados do evento %s: %v", tipo, err)
+	}
+	var documento bson.M
+	if err := bson.Unmarshal(raw, &documento); err != nil {
+		return nil, fmt.Errorf("erro ao serializar dados do evento %s: %v", tipo, err)
+	}
+
+	var contador struct {
+		Valor int64 `bson:"valor"`
+	}
+	err = database.DB.Collection(colecaoContadores).FindOneAndUpdate(ctx,
+		bson.M{"_id": colecaoEventos},
+		bson.M{"$inc": bson.M{"valor": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&contador)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao gerar id do evento: %v", err)
+	}
+
+	evento := &models.Evento{Id: contador.Valor, Tipo: tipo, Dados: documento, CriadoEm: time.Now()}
+	if _, err := database.DB.Collection(colecaoEventos).InsertOne(ctx, evento); err != nil {
+		return nil, fmt.Errorf("erro ao publicar evento %s: %v", tipo, err)
+	}
+	return evento, nil
+}
+
+// ListarDesde retorna até limite eventos com id maior que ultimoId, em ordem.
+// Se tipos não for vazio, apenas esses tipos são retornados.
+func ListarDesde(ctx context.Context, ultimoId int64, tipos []string, limite int64) ([]models.Evento, error) {
+	filter := bson.M{"_id": bson.M{"$gt": ultimoId}}
+	if len(tipos) > 0 {
+		filter["tipo"] = bson.M{"$in": tipos}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limite)
+	cursor, err := database.DB.Collection(colecaoEventos).Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar eventos: %v", err)
+	}
+	var eventos []models.Evento
+	if err := cursor.All(ctx, &eventos); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar eventos: %v", err)
+	}
+	return eventos, nil
+}
+
+// UltimoId retorna o id do evento mais recente, ou 0 se não houver eventos.
+func UltimoId(ctx context.Context) (int64, error) {
+	var evento struct {
+		Id int64 `bson:"_id"`
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "_id", Value: -1}}).SetProjection(bson.M{"_id": 1})
+	err := database.DB.Collection(colecaoEventos).FindOne(ctx, bson.M{}, opts).Decode(&evento)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("erro ao buscar último evento: %v", err)
+	}
+	return evento.Id, nil
+}
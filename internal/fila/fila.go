@@ -0,0 +1,278 @@
+// Package fila implementa a fila de tarefas dos workers na coleção Tarefas do
+// MongoDB. Cada tarefa é reservada por um worker com um lease renovado
+// enquanto ele trabalha; um lease vencido (worker parado ou travado) devolve
+// a tarefa para a fila, então vários workers podem rodar sem duplicar
+// trabalho.
+package fila
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+)
+
+const (
+	colecaoTarefas = "Tarefas"
+
+	maxTentativasPadrao = 3
+	// Tarefas finalizadas ficam na coleção por este período.
+	retencaoTarefas = 7 * 24 * time.Hour
+	// Espera antes de repetir uma tarefa que falhou, dobrada a cada tentativa.
+	esperaBaseRepeticao = 30 * time.Second
+)
+
+// ErrLeasePerdido indica que a tarefa não pertence mais ao worker: o lease
+// venceu e ela foi reservada por outro.
+var ErrLeasePerdido = errors.New("lease da tarefa perdido")
+
+// Opcoes ajustam Enfileirar. Com Chave, só é criada uma tarefa por chave
+// enquanto ela existir na coleção (inclusive finalizada).
+type Opcoes struct {
+	Chave         string
+	DisponivelEm  time.Time
+	MaxTentativas int
+}
+
+func colecao() *mongo.Collection {
+	return database.DB.Collection(colecaoTarefas)
+}
+
+// GarantirIndices cria os índices da fila: busca de tarefas disponíveis,
+// chave única e expiração das finalizadas.
+func GarantirIndices(ctx context.Context) error {
+	_, err := colecao().Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "disponivelEm", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "leaseAte", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "chave", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"chave": bson.M{"$exists": true}}),
+		},
+		{Keys: bson.D{{Key: "expiraEm", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		return fmt.Errorf("erro ao criar índices da fila de tarefas: %v", err)
+	}
+	return nil
+}
+
+// Enfileirar cria uma tarefa. dados deve ser serializável em BSON. Com
+// opcoes.Chave já existente, devolve a tarefa existente e criada=false.
+func Enfileirar(ctx context.Context, tipo string, dados interface{}, opcoes Opcoes) (tarefa *models.Tarefa, criada bool, err error) {
+	var documento bson.M
+	if dados != nil {
+		raw, err := bson.Marshal(dados)
+		if err != nil {
+			return nil, false, fmt.Errorf("erro ao serializar dados da tarefa %s: %v", tipo, err)
+		}
+		if err := bson.Unmarshal(raw, &documento); err != nil {
+			return nil, false, fmt.Errorf("erro ao serializar dados da tarefa %s: %v", tipo, err)
+		}
+	}
+	agora := time.Now()
+	nova := &models.Tarefa{
+		Id:            primitive.NewObjectID(),
+		Tipo:          tipo,
+		Chave:         opcoes.Chave,
+		Dados:         documento,
+		Status:        models.StatusTarefaPendente,
+		MaxTentativas: opcoes.MaxTentativas,
+		DisponivelEm:  opcoes.DisponivelEm,
+		CriadaEm:      agora,
+	}
+	if nova.MaxTentativas <= 0 {
+		nova.MaxTentativas = maxTentativasPadrao
+	}
+	if nova.DisponivelEm.IsZero() {
+		nova.DisponivelEm = agora
+	}
+
+	if opcoes.Chave == "" {
+		if _, err := colecao().InsertOne(ctx, nova); err != nil {
+			return nil, false, fmt.Errorf("erro ao enfileirar tarefa %s: %v", tipo, err)
+		}
+		return nova, true, nil
+	}
+
+	resultado, err := colecao().UpdateOne(ctx,
+		bson.M{"chave": opcoes.Chave},
+		bson.M{"$setOnInsert": nova},
+		options.Update().SetUpsert(true),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		// Dois workers enfileirando a mesma chave ao mesmo tempo.
+		err = nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("erro ao enfileirar tarefa %s (%s): %v", tipo, opcoes.Chave, err)
+	}
+	if resultado.UpsertedCount == 1 {
+		return nova, true, nil
+	}
+	var existente models.Tarefa
+	if err := colecao().FindOne(ctx, bson.M{"chave": opcoes.Chave}).Decode(&existente); err != nil {
+		return nil, false, fmt.Errorf("erro ao buscar tarefa %s: %v", opcoes.Chave, err)
+	}
+	return &existente, false, nil
+}
+
+// Reservar entrega ao worker a próxima tarefa disponível de um dos tipos:
+// pendente e com DisponivelEm vencido, ou em execução com o lease vencido e
+// tentativas restantes. Devolve nil quando não há tarefa.
+func Reservar(ctx context.Context, worker string, tipos []string, lease time.Duration) (*models.Tarefa, error) {
+	agora := time.Now()
+	if err := falharLeasesEsgotados(ctx, tipos, agora); err != nil {
+		return nil, err
+	}
+	filtro := bson.M{
+		"tipo": bson.M{"$in": tipos},
+		"$or": bson.A{
+			bson.M{"status": models.StatusTarefaPendente, "disponivelEm": bson.M{"$lte": agora}},
+			bson.M{
+				"status":   models.StatusTarefaExecutando,
+				"leaseAte": bson.M{"$lt": agora},
+				"$expr":    bson.M{"$lt": bson.A{"$tentativas", "$maxTentativas"}},
+			},
+		},
+	}
+	atualizacao := bson.M{
+		"$set": bson.M{
+			"status":     models.StatusTarefaExecutando,
+			"worker":     worker,
+			"leaseAte":   agora.Add(lease),
+			"iniciadaEm": agora,
+		},
+		"$inc": bson.M{"tentativas": 1},
+	}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "disponivelEm", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var tarefa models.Tarefa
+	err := colecao().FindOneAndUpdate(ctx, filtro, atualizacao, opts).Decode(&tarefa)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao reservar tarefa: %v", err)
+	}
+	return &tarefa, nil
+}
+
+// falharLeasesEsgotados marca como falhou as tarefas com lease vencido que já
+// usaram todas as tentativas. Sem isso, uma tarefa que derruba ou trava o
+// worker (falta de memória, kill) seria reservada de novo para sempre.
+func falharLeasesEsgotados(ctx context.Context, tipos []string, agora time.Time) error {
+	_, err := colecao().UpdateMany(ctx,
+		bson.M{
+			"tipo":     bson.M{"$in": tipos},
+			"status":   models.StatusTarefaExecutando,
+			"leaseAte": bson.M{"$lt": agora},
+			"$expr":    bson.M{"$gte": bson.A{"$tentativas", "$maxTentativas"}},
+		},
+		bson.M{"$set": bson.M{
+			"status":      models.StatusTarefaFalhou,
+			"erro":        "lease vencido na última tentativa: o worker parou ou travou durante a execução",
+			"concluidaEm": agora,
+			"expiraEm":    agora.Add(retencaoTarefas),
+		}},
+	)
+	if err != nil {
+		return fmt.Errorf("erro ao encerrar tarefas com lease vencido: %v", err)
+	}
+	return nil
+}
+
+// Renovar estende o lease de uma tarefa que o worker ainda detém.
+func Renovar(ctx context.Context, tarefa *models.Tarefa, worker string, lease time.Duration) error {
+	resultado, err := colecao().UpdateOne(ctx,
+		filtroDono(tarefa, worker),
+		bson.M{"$set": bson.M{"leaseAte": time.Now().Add(lease)}},
+	)
+	if err != nil {
+		return fmt.Errorf("erro ao renovar lease da tarefa %s: %v", tarefa.Id.Hex(), err)
+	}
+	if resultado.MatchedCount == 0 {
+		return ErrLeasePerdido
+	}
+	return nil
+}
+
+// Concluir marca a tarefa como concluída.
+func Concluir(ctx context.Context, tarefa *models.Tarefa, worker string) error {
+	agora := time.Now()
+	return finalizar(ctx, tarefa, worker, bson.M{
+		"status":      models.StatusTarefaConcluida,
+		"concluidaEm": agora,
+		"expiraEm":    agora.Add(retencaoTarefas),
+	})
+}
+
+// Falhar registra o erro. Se restarem tentativas, a tarefa volta para a fila
+// após uma espera que dobra a cada tentativa; senão, fica como falhou.
+func Falhar(ctx context.Context, tarefa *models.Tarefa, worker string, causa error) error {
+	agora := time.Now()
+	set := bson.M{"erro": causa.Error()}
+	if tarefa.Tentativas < tarefa.MaxTentativas {
+		set["status"] = models.StatusTarefaPendente
+		set["disponivelEm"] = agora.Add(esperaBaseRepeticao << (tarefa.Tentativas - 1))
+	} else {
+		set["status"] = models.StatusTarefaFalhou
+		set["concluidaEm"] = agora
+		set["expiraEm"] = agora.Add(retencaoTarefas)
+	}
+	return finalizar(ctx, tarefa, worker, set)
+}
+
+// Liberar devolve à fila uma tarefa interrompida pelo encerramento do
+// worker, sem contar a tentativa.
+func Liberar(ctx context.Context, tarefa *models.Tarefa, worker string) error {
+	resultado, err := colecao().UpdateOne(ctx,
+		filtroDono(tarefa, worker),
+		bson.M{
+			"$set":   bson.M{"status": models.StatusTarefaPendente, "disponivelEm": time.Now()},
+			"$unset": bson.M{"leaseAte": "", "worker": ""},
+			"$inc":   bson.M{"tentativas": -1},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("erro ao liberar tarefa %s: %v", tarefa.Id.Hex(), err)
+	}
+	if resultado.MatchedCount == 0 {
+		return ErrLeasePerdido
+	}
+	return nil
+}
+
+func finalizar(ctx context.Context, tarefa *models.Tarefa, worker string, set bson.M) error {
+	resultado, err := colecao().UpdateOne(ctx,
+		filtroDono(tarefa, worker),
+		bson.M{"$set": set, "$unset": bson.M{"leaseAte": ""}},
+	)
+	if err != nil {
+		return fmt.Errorf("erro ao finalizar tarefa %s: %v", tarefa.Id.Hex(), err)
+	}
+	if resultado.MatchedCount == 0 {
+		return ErrLeasePerdido
+	}
+	return nil
+}
+
+// filtroDono casa a tarefa apenas se ainda estiver com o worker, na mesma
+// tentativa em que foi reservada.
+func filtroDono(tarefa *models.Tarefa, worker string) bson.M {
+	return bson.M{
+		"_id":        tarefa.Id,
+		"status":     models.StatusTarefaExecutando,
+		"worker":     worker,
+		"tentativas": tarefa.Tentativas,
+	}
+}
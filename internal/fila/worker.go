@@ -0,0 +1,152 @@
+package fila
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"fipe_project/internal/models"
+)
+
+// Processador executa uma tarefa. O contexto é cancelado se o worker perder
+// o lease ou for encerrado.
+type Processador func(ctx context.Context, tarefa *models.Tarefa) error
+
+// Worker reserva e executa tarefas dos tipos registrados, até Concorrencia
+// ao mesmo tempo, renovando o lease de cada uma enquanto ela roda.
+type Worker struct {
+	Id           string
+	Lease        time.Duration
+	Intervalo    time.Duration
+	Concorrencia int
+
+	processadores map[string]Processador
+}
+
+// NovoWorker cria um worker. Intervalo é a espera entre buscas quando a fila
+// está vazia.
+func NovoWorker(id string, lease, intervalo time.Duration, concorrencia int) *Worker {
+	if concorrencia < 1 {
+		concorrencia = 1
+	}
+	return &Worker{
+		Id:            id,
+		Lease:         lease,
+		Intervalo:     intervalo,
+		Concorrencia:  concorrencia,
+		processadores: make(map[string]Processador),
+	}
+}
+
+// Registrar associa um tipo de tarefa ao seu processador.
+func (w *Worker) Registrar(tipo string, p Processador) {
+	w.processadores[tipo] = p
+}
+
+// Executar processa tarefas até ctx ser cancelado e espera as que estão em
+// andamento terminarem.
+func (w *Worker) Executar(ctx context.Context) {
+	tipos := make([]string, 0, len(w.processadores))
+	for tipo := range w.processadores {
+		tipos = append(tipos, tipo)
+	}
+	log.Printf("Worker %s iniciado (tipos %v, concorrência %d)", w.Id, tipos, w.Concorrencia)
+
+	vagas := make(chan struct{}, w.Concorrencia)
+	var emAndamento sync.WaitGroup
+	defer emAndamento.Wait()
+	for {
+		select {
+		case vagas <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+		tarefa, err := Reservar(ctx, w.Id, tipos, w.Lease)
+		if err != nil || tarefa == nil {
+			<-vagas
+			if err != nil && ctx.Err() == nil {
+				log.Printf("Worker %s: %v", w.Id, err)
+			}
+			select {
+			case <-time.After(w.Intervalo):
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		emAndamento.Add(1)
+		go func() {
+			defer emAndamento.Done()
+			defer func() { <-vagas }()
+			w.processar(ctx, tarefa)
+		}()
+	}
+}
+
+func (w *Worker) processar(ctx context.Context, tarefa *models.Tarefa) {
+	tarefaCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Renova o lease a cada terço do prazo; se ele for perdido, cancela a
+	// tarefa, que já pode estar com outro worker.
+	renovacao := make(chan struct{})
+	go func() {
+		defer close(renovacao)
+		ticker := time.NewTicker(w.Lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-tarefaCtx.Done():
+				return
+			case <-ticker.C:
+				if err := Renovar(tarefaCtx, tarefa, w.Id, w.Lease); err != nil {
+					if tarefaCtx.Err() == nil {
+						log.Printf("Worker %s: tarefa %s: %v", w.Id, tarefa.Id.Hex(), err)
+						cancel()
+					}
+					return
+				}
+			}
+		}
+	}()
+
+	inicio := time.Now()
+	err := w.executarProcessador(tarefaCtx, tarefa)
+	perdido := tarefaCtx.Err() != nil && ctx.Err() == nil
+	cancel()
+	<-renovacao
+	if perdido {
+		return
+	}
+
+	// A finalização usa um contexto próprio para ser gravada mesmo durante o
+	// encerramento do worker.
+	fimCtx, fimCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer fimCancel()
+	switch {
+	case ctx.Err() != nil:
+		log.Printf("Worker %s: tarefa %s (%s) interrompida pelo encerramento", w.Id, tarefa.Id.Hex(), tarefa.Tipo)
+		err = Liberar(fimCtx, tarefa, w.Id)
+	case err != nil:
+		log.Printf("Worker %s: tarefa %s (%s) falhou na tentativa %d: %v", w.Id, tarefa.Id.Hex(), tarefa.Tipo, tarefa.Tentativas, err)
+		err = Falhar(fimCtx, tarefa, w.Id, err)
+	default:
+		log.Printf("Worker %s: tarefa %s (%s) concluída em %s", w.Id, tarefa.Id.Hex(), tarefa.Tipo, time.Since(inicio).Round(time.Millisecond))
+		err = Concluir(fimCtx, tarefa, w.Id)
+	}
+	if err != nil && !errors.Is(err, ErrLeasePerdido) {
+		log.Printf("Worker %s: %v", w.Id, err)
+	}
+}
+
+func (w *Worker) executarProcessador(ctx context.Context, tarefa *models.Tarefa) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("pânico: %v", r)
+		}
+	}()
+	return w.processadores[tarefa.Tipo](ctx, tarefa)
+}
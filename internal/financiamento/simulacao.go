@@ -0,0 +1,195 @@
+// Package financiamento monta tabelas de amortização (Price e SAC) para o
+// financiamento de veículos, com IOF e custo efetivo total. Os valores são
+// calculados em centavos com models.Money.
+package financiamento
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"fipe_project/internal/models"
+)
+
+const (
+	MaxPrazo      = 120
+	MaxTaxaMensal = 20.0
+
+	// IOF de operações de crédito a pessoa física: alíquota fixa sobre o
+	// valor financiado mais a diária sobre cada amortização, pelo prazo até
+	// o seu vencimento, limitado a 365 dias. Os meses contam 30 dias.
+	aliquotaIOFFixa   = 0.0038
+	aliquotaIOFDiaria = 0.000082
+	diasMaximosIOF    = 365
+	diasPorMes        = 30
+)
+
+// ErrCondicoesInvalidas indica condições de financiamento fora dos limites.
+var ErrCondicoesInvalidas = errors.New("condições de financiamento inválidas")
+
+// Condicoes são os parâmetros da simulação. TaxaMensal é percentual. Com
+// FinanciarCustos, IOF e tarifas entram no principal; sem, são pagos à vista.
+type Condicoes struct {
+	ValorVeiculo    models.Money
+	Entrada         models.Money
+	Tarifas         models.Money
+	Prazo           int
+	TaxaMensal      float64
+	Sistema         string
+	FinanciarCustos bool
+}
+
+// Simular preenche em s os valores, a tabela de parcelas e os totais.
+func Simular(c Condicoes, s *models.SimulacaoFinanciamento) error {
+	if c.Sistema == "" {
+		c.Sistema = models.SistemaPrice
+	}
+	if c.Sistema != models.SistemaPrice && c.Sistema != models.SistemaSAC {
+		return fmt.Errorf("%w: sistema deve ser '%s' ou '%s'", ErrCondicoesInvalidas, models.SistemaPrice, models.SistemaSAC)
+	}
+	if c.Prazo < 1 || c.Prazo > MaxPrazo {
+		return fmt.Errorf("%w: prazo deve estar entre 1 e %d meses", ErrCondicoesInvalidas, MaxPrazo)
+	}
+	if c.TaxaMensal < 0 || c.TaxaMensal > MaxTaxaMensal {
+		return fmt.Errorf("%w: taxa mensal deve estar entre 0 e %.0f%%", ErrCondicoesInvalidas, MaxTaxaMensal)
+	}
+	if c.Entrada < 0 || c.Entrada >= c.ValorVeiculo {
+		return fmt.Errorf("%w: a entrada deve ser menor que o valor do veículo", ErrCondicoesInvalidas)
+	}
+	if c.Tarifas < 0 {
+		return fmt.Errorf("%w: tarifas negativas", ErrCondicoesInvalidas)
+	}
+
+	taxa := c.TaxaMensal / 100
+	financiado := c.ValorVeiculo.Sub(c.Entrada)
+	principal := financiado
+	if c.FinanciarCustos {
+		principal = principal.Add(c.Tarifas)
+	}
+
+	// O IOF financiado incide sobre o próprio IOF. Como ele é proporcional
+	// ao principal, basta calcular a proporção sobre o principal sem IOF.
+	var iof models.Money
+	if c.FinanciarCustos {
+		proporcao := calcularIOF(tabela(principal, c.Prazo, taxa, c.Sistema), principal).Float64() / principal.Float64()
+		bruto := principal.Mul(1 / (1 - proporcao))
+		iof = calcularIOF(tabela(bruto, c.Prazo, taxa, c.Sistema), bruto)
+		principal = principal.Add(iof)
+	} else {
+		iof = calcularIOF(tabela(principal, c.Prazo, taxa, c.Sistema), principal)
+	}
+	parcelas := tabela(principal, c.Prazo, taxa, c.Sistema)
+
+	var totalParcelas, totalJuros models.Money
+	for _, p := range parcelas {
+		totalParcelas = totalParcelas.Add(p.Parcela)
+		totalJuros = totalJuros.Add(p.Juros)
+	}
+	liberado := financiado
+	custoTotal := c.Entrada.Add(totalParcelas)
+	if !c.FinanciarCustos {
+		liberado = liberado.Sub(iof).Sub(c.Tarifas)
+		custoTotal = custoTotal.Add(iof).Add(c.Tarifas)
+	}
+	cet := custoEfetivo(liberado, parcelas)
+
+	s.Sistema = c.Sistema
+	s.Prazo = c.Prazo
+	s.TaxaMensal = c.TaxaMensal
+	s.TaxaAnual = arredondar((math.Pow(1+taxa, 12) - 1) * 100)
+	s.CustosFinanciados = c.FinanciarCustos
+	s.ValorVeiculo, s.ValorVeiculoFmt = c.ValorVeiculo, c.ValorVeiculo.String()
+	s.Entrada, s.EntradaFmt = c.Entrada, c.Entrada.String()
+	s.ValorFinanciado, s.ValorFinanciadoFmt = financiado, financiado.String()
+	s.IOF, s.IOFFmt = iof, iof.String()
+	s.Tarifas, s.TarifasFmt = c.Tarifas, c.Tarifas.String()
+	s.Principal, s.PrincipalFmt = principal, principal.String()
+	s.TotalParcelas, s.TotalParcelasFmt = totalParcelas, totalParcelas.String()
+	s.TotalJuros, s.TotalJurosFmt = totalJuros, totalJuros.String()
+	s.CustoTotal, s.CustoTotalFmt = custoTotal, custoTotal.String()
+	s.CETMensal = arredondar(cet * 100)
+	s.CETAnual = arredondar((math.Pow(1+cet, 12) - 1) * 100)
+	s.Parcelas = parcelas
+	return nil
+}
+
+// tabela monta as parcelas. Na Price a parcela é constante; no SAC, a
+// amortização. Os arredondamentos se acumulam na última parcela, que zera o
+// saldo.
+func tabela(principal models.Money, prazo int, taxa float64, sistema string) []models.ParcelaFinanciamento {
+	parcelas := make([]models.ParcelaFinanciamento, 0, prazo)
+	saldo := principal
+	var prestacao, amortizacaoSAC models.Money
+	if sistema == models.SistemaPrice {
+		if taxa == 0 {
+			prestacao = principal.Div(int64(prazo))
+		} else {
+			prestacao = principal.Mul(taxa / (1 - math.Pow(1+taxa, -float64(prazo))))
+		}
+	} else {
+		amortizacaoSAC = principal.Div(int64(prazo))
+	}
+	for n := 1; n <= prazo; n++ {
+		juros := saldo.Mul(taxa)
+		var amortizacao models.Money
+		switch {
+		case n == prazo:
+			amortizacao = saldo
+		case sistema == models.SistemaPrice:
+			amortizacao = prestacao.Sub(juros)
+		default:
+			amortizacao = amortizacaoSAC
+		}
+		saldo = saldo.Sub(amortizacao)
+		parcelas = append(parcelas, models.ParcelaFinanciamento{
+			Numero:      n,
+			Parcela:     juros.Add(amortizacao),
+			Juros:       juros,
+			Amortizacao: amortizacao,
+			Saldo:       saldo,
+		})
+	}
+	return parcelas
+}
+
+func calcularIOF(parcelas []models.ParcelaFinanciamento, principal models.Money) models.Money {
+	iof := principal.Mul(aliquotaIOFFixa)
+	for _, p := range parcelas {
+		dias := p.Numero * diasPorMes
+		if dias > diasMaximosIOF {
+			dias = diasMaximosIOF
+		}
+		iof = iof.Add(p.Amortizacao.Mul(aliquotaIOFDiaria * float64(dias)))
+	}
+	return iof
+}
+
+// custoEfetivo é a taxa mensal que iguala o valor presente das parcelas ao
+// valor liberado, encontrada por bisseção.
+func custoEfetivo(liberado models.Money, parcelas []models.ParcelaFinanciamento) float64 {
+	valorPresente := func(taxa float64) float64 {
+		var vp float64
+		for _, p := range parcelas {
+			vp += p.Parcela.Float64() / math.Pow(1+taxa, float64(p.Numero))
+		}
+		return vp
+	}
+	alvo := liberado.Float64()
+	if alvo <= 0 || valorPresente(0) <= alvo {
+		return 0
+	}
+	baixo, alto := 0.0, 1.0
+	for i := 0; i < 100; i++ {
+		meio := (baixo + alto) / 2
+		if valorPresente(meio) > alvo {
+			baixo = meio
+		} else {
+			alto = meio
+		}
+	}
+	return (baixo + alto) / 2
+}
+
+func arredondar(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
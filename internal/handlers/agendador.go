@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"fipe_project/internal/agendador"
+	"fipe_project/internal/models"
+)
+
+// ExigirToken protege as rotas administrativas e as da watchlist: exige o
+// cabeçalho "Authorization: Bearer <token>". Sem token configurado, as rotas
+// ficam fechadas e respondem 503.
+func ExigirToken(token string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodOptions {
+				if token == "" {
+					http.Error(w, "Rota desativada: ADMIN_TOKEN não definido", http.StatusServiceUnavailable)
+					return
+				}
+				esperado := []byte("Bearer " + token)
+				if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), esperado) != 1 {
+					http.Error(w, "Não autorizado", http.StatusUnauthorized)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// GetJobs lista os jobs agendados com a próxima execução (no fuso do
+// agendador) e a última execução registrada.
+func GetJobs(fuso *time.Location) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		resumos, err := agendador.Resumos(ctx, fuso)
+		if err != nil {
+			log.Printf("Erro ao listar jobs: %v", err)
+			http.Error(w, "Erro interno", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resumos)
+	}
+}
+
+// PostExecutarJob dispara uma execução manual do job. A execução fica
+// pendente até um worker assumi-la; o andamento aparece em
+// /api/admin/jobs/execucoes.
+func PostExecutarJob(w http.ResponseWriter, r *http.Request) {
+	nome := mux.Vars(r)["nome"]
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	execucao, err := agendador.Disparar(ctx, nome)
+	if errors.Is(err, agendador.ErrJobDesconhecido) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, agendador.ErrExecucaoPendente) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao disparar job %s: %v", nome, err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(execucao)
+}
+
+// GetExecucoesJobs retorna o histórico de execuções, do mais recente para o
+// mais antigo, filtrado por 'job' e 'status'.
+func GetExecucoesJobs(w http.ResponseWriter, r *http.Request) {
+	job := r.URL.Query().Get("job")
+	if job != "" {
+		if _, err := agendador.Definicao(job); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.StatusExecucaoPendente, models.StatusExecucaoExecutando, models.StatusExecucaoConcluida,
+		models.StatusExecucaoFalhou, models.StatusExecucaoIgnorada:
+	default:
+		http.Error(w, "Parâmetro 'status' inválido", http.StatusBadRequest)
+		return
+	}
+	limite := int64(50)
+	if limiteParam := r.URL.Query().Get("limite"); limiteParam != "" {
+		l, err := strconv.Atoi(limiteParam)
+		if err != nil || l <= 0 || l > agendador.MaxExecucoes {
+			http.Error(w, fmt.Sprintf("Parâmetro 'limite' deve estar entre 1 e %d", agendador.MaxExecucoes), http.StatusBadRequest)
+			return
+		}
+		limite = int64(l)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	execucoes, err := agendador.ListarExecucoes(ctx, job, status, limite)
+	if err != nil {
+		log.Printf("Erro ao listar execuções de jobs: %v", err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(execucoes)
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"fipe_project/internal/fila"
+	"fipe_project/internal/models"
+	"fipe_project/internal/services"
+)
+
+const tamanhoMaximoLote = 10 << 20 // 10 MB
+
+type requisicaoAvaliacaoLote struct {
+	Tabela   json.RawMessage        `json:"tabela"`
+	Veiculos []models.ItemAvaliacao `json:"veiculos"`
+}
+
+// PostAvaliacaoLote avalia uma lista de veículos (JSON ou CSV) contra uma tabela.
+// Lotes com até services.LimiteLoteSincrono veículos são respondidos na hora;
+// lotes maiores viram um job assíncrono consultável pela URL devolvida.
+func PostAvaliacaoLote(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, tamanhoMaximoLote)
+
+	var itens []models.ItemAvaliacao
+	tabelaParam := r.URL.Query().Get("tabela")
+
+	contentType := strings.ToLower(r.Header.Get("Content-Type"))
+	if strings.Contains(contentType, "csv") {
+		var err error
+		itens, err = lerLoteCSV(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("CSV inválido: %v", err), http.StatusBadRequest)
+			return
+		}
+	} else {
+		corpo, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Erro ao ler corpo da requisição", http.StatusBadRequest)
+			return
+		}
+		// Aceita tanto {"tabela": ..., "veiculos": [...]} quanto a lista pura.
+		if trimmed := strings.TrimSpace(string(corpo)); strings.HasPrefix(trimmed, "[") {
+			err = json.Unmarshal(corpo, &itens)
+		} else {
+			var req requisicaoAvaliacaoLote
+			err = json.Unmarshal(corpo, &req)
+			itens = req.Veiculos
+			if tabelaParam == "" {
+				tabelaParam = strings.Trim(string(req.Tabela), `"`)
+			}
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("JSON inválido: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if tabelaParam == "" {
+		http.Error(w, "Parâmetro 'tabela' é obrigatório", http.StatusBadRequest)
+		return
+	}
+	tabelaId, ok := resolverTabelaParam(w, "tabela", tabelaParam)
+	if !ok {
+		return
+	}
+	if len(itens) == 0 {
+		http.Error(w, "Nenhum veículo informado", http.StatusBadRequest)
+		return
+	}
+	if len(itens) > services.LimiteLote {
+		http.Error(w, fmt.Sprintf("Lote excede o limite de %d veículos", services.LimiteLote), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if len(itens) > services.LimiteLoteSincrono {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		job, err := services.CriarJobAvaliacao(ctx, tabelaId, itens)
+		if err != nil {
+			log.Printf("Erro ao criar job de avaliação: %v", err)
+			http.Error(w, "Erro interno ao criar job", http.StatusInternalServerError)
+			return
+		}
+		// O lote é processado pelo worker (cmd/worker).
+		if _, _, err := fila.Enfileirar(ctx, models.TarefaAvaliacaoLote, bson.M{"jobId": job.Id}, fila.Opcoes{}); err != nil {
+			log.Printf("Erro ao enfileirar job de avaliação %s: %v", job.Id.Hex(), err)
+			// Sem tarefa na fila o job ficaria pendente para sempre; o
+			// contexto da requisição pode já ter expirado.
+			ctxDescarte, cancelDescarte := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancelDescarte()
+			if err := services.DescartarJobAvaliacao(ctxDescarte, job.Id); err != nil {
+				log.Printf("Erro ao descartar job de avaliação %s: %v", job.Id.Hex(), err)
+			}
+			http.Error(w, "Erro interno ao criar job", http.StatusInternalServerError)
+			return
+		}
+
+		statusURL := "/api/avaliacao/lote/" + job.Id.Hex()
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", statusURL)
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"id":        job.Id.Hex(),
+			"status":    job.Status,
+			"total":     job.Total,
+			"statusUrl": statusURL,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resultado, err := services.AvaliarLote(ctx, tabelaId, itens)
+	if errors.Is(err, services.ErrNaoEncontrado) {
+		http.Error(w, "Tabela não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao avaliar lote na tabela %d: %v", tabelaId, err)
+		http.Error(w, "Erro interno ao avaliar lote", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resultado)
+}
+
+// GetAvaliacaoLote retorna o estado de um job de avaliação em lote.
+func GetAvaliacaoLote(w http.ResponseWriter, r *http.Request) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Identificador de job inválido", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	job, err := services.BuscarJobAvaliacao(ctx, id)
+	if errors.Is(err, services.ErrNaoEncontrado) {
+		http.Error(w, "Job não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao buscar job de avaliação: %v", err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
+
+// lerLoteCSV lê um CSV com cabeçalho. As colunas reconhecidas são codigoFipe,
+// marca, modelo e ano; o separador pode ser vírgula ou ponto e vírgula.
+func lerLoteCSV(r io.Reader) ([]models.ItemAvaliacao, error) {
+	corpo, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	leitor := csv.NewReader(strings.NewReader(string(corpo)))
+	primeiraLinha := string(corpo)
+	if i := strings.IndexByte(primeiraLinha, '\n'); i >= 0 {
+		primeiraLinha = primeiraLinha[:i]
+	}
+	if strings.Count(primeiraLinha, ";") > strings.Count(primeiraLinha, ",") {
+		leitor.Comma = ';'
+	}
+	leitor.TrimLeadingSpace = true
+	leitor.FieldsPerRecord = -1
+
+	cabecalho, err := leitor.Read()
+	if err != nil {
+		return nil, fmt.Errorf("cabeçalho ausente: %v", err)
+	}
+	colunas := make(map[string]int)
+	for i, nome := range cabecalho {
+		nome = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(nome, "\ufeff")))
+		switch nome {
+		case "codigofipe", "codigo_fipe", "fipe", "fipecode":
+			colunas["codigoFipe"] = i
+		case "marca", "modelo", "ano":
+			colunas[nome] = i
+		}
+	}
+	if len(colunas) == 0 {
+		return nil, fmt.Errorf("nenhuma coluna reconhecida (use codigoFipe, marca, modelo, ano)")
+	}
+
+	campo := func(registro []string, nome string) string {
+		if i, ok := colunas[nome]; ok && i < len(registro) {
+			return strings.TrimSpace(registro[i])
+		}
+		return ""
+	}
+
+	var itens []models.ItemAvaliacao
+	for {
+		registro, err := leitor.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		itens = append(itens, models.ItemAvaliacao{
+			CodigoFipe: campo(registro, "codigoFipe"),
+			Marca:      campo(registro, "marca"),
+			Modelo:     campo(registro, "modelo"),
+			Ano:        campo(registro, "ano"),
+		})
+	}
+	return itens, nil
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fipe_project/internal/services"
+)
+
+// GetCicloVida lista os lançamentos, descontinuações, renomeações e novos
+// anos-modelo entre tabelas consecutivas. 'tabela' deve ser informado ao
+// menos duas vezes (ou como lista separada por vírgula); 'marca' é opcional.
+func GetCicloVida(w http.ResponseWriter, r *http.Request) {
+	tabelasParam := valoresMultiplos(r, "tabela")
+	if len(tabelasParam) < 2 {
+		http.Error(w, "Informe ao menos duas tabelas no parâmetro 'tabela'", http.StatusBadRequest)
+		return
+	}
+	var tabelaIds []int
+	for _, t := range tabelasParam {
+		id, ok := resolverTabelaParam(w, "tabela", t)
+		if !ok {
+			return
+		}
+		tabelaIds = append(tabelaIds, id)
+	}
+	var marca *int32
+	if marcaParam := r.URL.Query().Get("marca"); marcaParam != "" {
+		codigo, err := strconv.Atoi(marcaParam)
+		if err != nil {
+			http.Error(w, "Parâmetro 'marca' inválido", http.StatusBadRequest)
+			return
+		}
+		c := int32(codigo)
+		marca = &c
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	ciclo, err := services.CicloVidaModelos(ctx, tabelaIds, marca)
+	if errors.Is(err, services.ErrCicloVidaInvalido) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao calcular ciclo de vida dos modelos: %v", err)
+		http.Error(w, "Erro interno ao comparar catálogos", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ciclo)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fipe_project/internal/models"
+	"fipe_project/internal/services"
+)
+
+// GetComparar compara anos-modelo entre uma ou mais tabelas. Cada parâmetro
+// 'veiculo' é "modelo:ano" (ano aceita "0km") ou apenas "modelo" para todos os
+// anos; 'tabela' pode ser repetido. Ambos aceitam listas separadas por vírgula.
+func GetComparar(w http.ResponseWriter, r *http.Request) {
+	veiculosParam := valoresMultiplos(r, "veiculo")
+	tabelasParam := valoresMultiplos(r, "tabela")
+	if len(veiculosParam) == 0 || len(tabelasParam) == 0 {
+		http.Error(w, "Parâmetros 'veiculo' e 'tabela' são obrigatórios", http.StatusBadRequest)
+		return
+	}
+
+	var pares []models.ParVeiculo
+	for _, v := range veiculosParam {
+		par, err := parseParVeiculo(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Parâmetro 'veiculo' inválido: %s", v), http.StatusBadRequest)
+			return
+		}
+		pares = append(pares, par)
+	}
+	var tabelaIds []int
+	for _, t := range tabelasParam {
+		id, ok := resolverTabelaParam(w, "tabela", t)
+		if !ok {
+			return
+		}
+		tabelaIds = append(tabelaIds, id)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	comparacao, err := services.CompararVeiculos(ctx, pares, tabelaIds)
+	if errors.Is(err, services.ErrComparacaoInvalida) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao comparar veículos: %v", err)
+		http.Error(w, "Erro interno ao comparar veículos", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(comparacao)
+}
+
+// valoresMultiplos junta as ocorrências repetidas de um parâmetro de query,
+// separando também valores com vírgula.
+func valoresMultiplos(r *http.Request, nome string) []string {
+	var valores []string
+	for _, v := range r.URL.Query()[nome] {
+		for _, parte := range strings.Split(v, ",") {
+			if parte = strings.TrimSpace(parte); parte != "" {
+				valores = append(valores, parte)
+			}
+		}
+	}
+	return valores
+}
+
+func parseParVeiculo(v string) (models.ParVeiculo, error) {
+	modeloParam, anoParam, temAno := strings.Cut(v, ":")
+	modelo, err := strconv.Atoi(modeloParam)
+	if err != nil {
+		return models.ParVeiculo{}, err
+	}
+	par := models.ParVeiculo{ModelCode: int32(modelo)}
+	if temAno {
+		if par.Ano, err = parseAnoParam(anoParam); err != nil {
+			return models.ParVeiculo{}, err
+		}
+	}
+	return par, nil
+}
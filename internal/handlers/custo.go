@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fipe_project/internal/ipva"
+	"fipe_project/internal/services"
+)
+
+// GetCustoTotal estima o custo total de posse de um ou mais anos-modelo.
+// Parâmetros: 'veiculo' ("modelo:ano", repetível), 'tabela', 'uf' e os
+// opcionais 'anos', 'km' (quilometragem anual) e 'seguro' (% do valor FIPE).
+func GetCustoTotal(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	veiculosParam := valoresMultiplos(r, "veiculo")
+	tabelaParam := q.Get("tabela")
+	uf := q.Get("uf")
+	if len(veiculosParam) == 0 || tabelaParam == "" || uf == "" {
+		http.Error(w, "Parâmetros 'veiculo', 'tabela' e 'uf' são obrigatórios", http.StatusBadRequest)
+		return
+	}
+
+	consulta := services.ConsultaCustoTotal{UF: uf}
+	for _, v := range veiculosParam {
+		par, err := parseParVeiculo(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Parâmetro 'veiculo' inválido: %s", v), http.StatusBadRequest)
+			return
+		}
+		consulta.Veiculos = append(consulta.Veiculos, par)
+	}
+	var ok bool
+	if consulta.TabelaId, ok = resolverTabelaParam(w, "tabela", tabelaParam); !ok {
+		return
+	}
+	var err error
+	if v := q.Get("anos"); v != "" {
+		if consulta.Anos, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Parâmetro 'anos' inválido", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("km"); v != "" {
+		if consulta.KmAnual, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Parâmetro 'km' inválido", http.StatusBadRequest)
+			return
+		}
+	}
+	if v := q.Get("seguro"); v != "" {
+		if consulta.SeguroPercentual, err = strconv.ParseFloat(v, 64); err != nil {
+			http.Error(w, "Parâmetro 'seguro' inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resultado, err := services.CalcularCustoTotal(ctx, consulta)
+	if errors.Is(err, services.ErrCustoTotalInvalido) || errors.Is(err, ipva.ErrUFInvalida) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrNaoEncontrado) {
+		http.Error(w, fmt.Sprintf("Veículo não encontrado na tabela informada (%v)", err), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao calcular custo total na tabela %d: %v", consulta.TabelaId, err)
+		http.Error(w, "Erro interno ao calcular custo total", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resultado)
+}
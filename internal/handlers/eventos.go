@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fipe_project/internal/events"
+)
+
+var (
+	// intervaloEventos é a frequência com que o stream consulta novos eventos.
+	intervaloEventos = 2 * time.Second
+	// intervaloHeartbeat mantém a conexão aberta através de proxies ociosos.
+	intervaloHeartbeat = 15 * time.Second
+)
+
+// GetEventos abre um stream Server-Sent Events com os eventos publicados
+// (tabela_publicada, estatisticas_reconstruidas, alerta_disparado). O cliente
+// retoma de onde parou enviando o cabeçalho Last-Event-ID (ou o parâmetro
+// 'desde'); sem ele, recebe apenas eventos novos. O parâmetro 'tipos' filtra
+// os tipos desejados, separados por vírgula.
+func GetEventos(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming não suportado", http.StatusInternalServerError)
+		return
+	}
+
+	var tipos []string
+	if tiposParam := r.URL.Query().Get("tipos"); tiposParam != "" {
+		for _, t := range strings.Split(tiposParam, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				tipos = append(tipos, t)
+			}
+		}
+	}
+
+	ctx := r.Context()
+
+	desde := r.Header.Get("Last-Event-ID")
+	if desde == "" {
+		desde = r.URL.Query().Get("desde")
+	}
+	var ultimoId int64
+	if desde != "" {
+		id, err := strconv.ParseInt(desde, 10, 64)
+		if err != nil || id < 0 {
+			http.Error(w, "Last-Event-ID inválido", http.StatusBadRequest)
+			return
+		}
+		ultimoId = id
+	} else {
+		consultaCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		id, err := events.UltimoId(consultaCtx)
+		cancel()
+		if err != nil {
+			log.Printf("Erro ao iniciar stream de eventos: %v", err)
+			http.Error(w, "Erro interno", http.StatusInternalServerError)
+			return
+		}
+		ultimoId = id
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", intervaloEventos.Milliseconds())
+	flusher.Flush()
+
+	consulta := time.NewTicker(intervaloEventos)
+	defer consulta.Stop()
+	heartbeat := time.NewTicker(intervaloHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		case <-consulta.C:
+			consultaCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+			novos, err := events.ListarDesde(consultaCtx, ultimoId, tipos, 100)
+			cancel()
+			if err != nil {
+				log.Printf("Erro ao consultar eventos: %v", err)
+				continue
+			}
+			for _, evento := range novos {
+				dados, err := json.Marshal(evento)
+				if err != nil {
+					log.Printf("Erro ao serializar evento %d: %v", evento.Id, err)
+					continue
+				}
+				fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", evento.Id, evento.Tipo, dados)
+				ultimoId = evento.Id
+			}
+			if len(novos) > 0 {
+				flusher.Flush()
+			}
+		}
+	}
+}
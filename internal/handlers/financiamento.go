@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fipe_project/internal/financiamento"
+	"fipe_project/internal/models"
+	"fipe_project/internal/services"
+)
+
+type requisicaoSimulacao struct {
+	Tabela            json.RawMessage `json:"tabela"`
+	Modelo            json.Number     `json:"modelo"`
+	Ano               json.RawMessage `json:"ano"`
+	EntradaPercentual float64         `json:"entradaPercentual"`
+	Entrada           models.Money    `json:"entrada"`
+	Prazo             int             `json:"prazo"`
+	TaxaMensal        float64         `json:"taxaMensal"`
+	Sistema           string          `json:"sistema"`
+	FinanciarCustos   *bool           `json:"financiarCustos"`
+	Tarifas           models.Money    `json:"tarifas"`
+}
+
+// PostSimulacaoFinanciamento simula o financiamento de um ano-modelo pelo
+// valor FIPE da tabela. O corpo traz tabela, modelo, ano (número ou texto,
+// como "0km" ou "2019-1"), entradaPercentual ou entrada em reais, prazo em
+// meses, taxaMensal (%), sistema ("price" ou "sac"), tarifas e
+// financiarCustos (padrão true). Com '?formato=csv', devolve só as parcelas.
+func PostSimulacaoFinanciamento(w http.ResponseWriter, r *http.Request) {
+	formato := r.URL.Query().Get("formato")
+	if formato != "" && formato != "json" && formato != "csv" {
+		http.Error(w, "Parâmetro 'formato' deve ser 'json' ou 'csv'", http.StatusBadRequest)
+		return
+	}
+
+	var req requisicaoSimulacao
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("JSON inválido: %v", err), http.StatusBadRequest)
+		return
+	}
+	modeloId, err := strconv.Atoi(req.Modelo.String())
+	if len(req.Tabela) == 0 || err != nil || len(req.Ano) == 0 {
+		http.Error(w, "Campos 'tabela', 'modelo' e 'ano' são obrigatórios", http.StatusBadRequest)
+		return
+	}
+	tabelaId, ok := resolverTabelaParam(w, "tabela", strings.Trim(string(req.Tabela), `"`))
+	if !ok {
+		return
+	}
+	ano, err := parseAnoParam(strings.Trim(string(req.Ano), `"`))
+	if err != nil {
+		http.Error(w, "Campo 'ano' inválido", http.StatusBadRequest)
+		return
+	}
+	if req.EntradaPercentual < 0 || req.EntradaPercentual >= 100 {
+		http.Error(w, "Campo 'entradaPercentual' deve estar entre 0 e 100", http.StatusBadRequest)
+		return
+	}
+
+	pedido := services.PedidoFinanciamento{
+		TabelaId:          tabelaId,
+		ModeloId:          modeloId,
+		Ano:               ano,
+		EntradaPercentual: req.EntradaPercentual,
+		Entrada:           req.Entrada,
+		Prazo:             req.Prazo,
+		TaxaMensal:        req.TaxaMensal,
+		Sistema:           req.Sistema,
+		FinanciarCustos:   req.FinanciarCustos == nil || *req.FinanciarCustos,
+		Tarifas:           req.Tarifas,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	simulacao, err := services.SimularFinanciamento(ctx, pedido)
+	if errors.Is(err, financiamento.ErrCondicoesInvalidas) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrNaoEncontrado) {
+		http.Error(w, "Veículo não encontrado na tabela informada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao simular financiamento do modelo %d/%d na tabela %d: %v", modeloId, ano, tabelaId, err)
+		http.Error(w, "Erro interno ao simular financiamento", http.StatusInternalServerError)
+		return
+	}
+
+	if formato == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="financiamento_%d_%d_%s.csv"`, modeloId, ano, simulacao.Sistema))
+		if err := escreverParcelasCSV(w, simulacao.Parcelas); err != nil {
+			log.Printf("Erro ao exportar simulação em CSV: %v", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(simulacao)
+}
+
+func escreverParcelasCSV(w http.ResponseWriter, parcelas []models.ParcelaFinanciamento) error {
+	valor := func(m models.Money) string { return strconv.FormatFloat(m.Float64(), 'f', 2, 64) }
+	escritor := csv.NewWriter(w)
+	escritor.Write([]string{"numero", "parcela", "juros", "amortizacao", "saldo"})
+	for _, p := range parcelas {
+		escritor.Write([]string{strconv.Itoa(p.Numero), valor(p.Parcela), valor(p.Juros), valor(p.Amortizacao), valor(p.Saldo)})
+	}
+	escritor.Flush()
+	return escritor.Error()
+}
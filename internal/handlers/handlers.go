@@ -3,144 +3,124 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"math"
 	"net/http"
 	"strconv"
-	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"fipe_project/internal/database"
 	"fipe_project/internal/models"
-	"fipe_project/internal/utils"
+	"fipe_project/internal/segmentos"
+	"fipe_project/internal/services"
 )
 
-// GetTabelasReferencia busca todas as tabelas de referência e retorna apenas
-// aquelas que possuem veículos associados, fazendo a verificação de forma concorrente.
+// GetTabelasReferencia lista as tabelas de referência com veículos, da mais
+// recente para a mais antiga. Aceita os filtros opcionais 'anoInicial',
+// 'anoFinal' e 'status' (pendente ou publicada).
 func GetTabelasReferencia(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second) // Aumentei o timeout para dar conta de mais requisições
-	defer cancel()
-
-	collection := database.DB.Collection("TabelaReferencia")
-
-	// 1. Carregamos todas as tabelas em memória primeiro.
-	// Isso simplifica a lógica de concorrência, pois não precisamos nos preocupar
-	// com o cursor do banco de dados sendo acessado por múltiplas goroutines.
-	cursor, err := collection.Find(ctx, bson.M{})
-	if err != nil {
-		log.Printf("Erro ao buscar tabelas de referência: %v", err)
-		http.Error(w, "Erro interno ao buscar dados", http.StatusInternalServerError)
-		return
-	}
-	defer cursor.Close(ctx)
-
-	var todasTabelas []bson.M
-	if err = cursor.All(ctx, &todasTabelas); err != nil {
-		log.Printf("Erro ao decodificar todas as tabelas: %v", err)
-		http.Error(w, "Erro interno ao processar dados", http.StatusInternalServerError)
-		return
-	}
-
-	// 2. Preparamos a estrutura para processamento concorrente.
-	var wg sync.WaitGroup
-	var mutex sync.Mutex // Usamos um Mutex para proteger o acesso ao slice de resultados.
-	var tabelasFiltradas []bson.M
-
-	// 3. Iteramos sobre as tabelas e disparamos uma goroutine para cada uma.
-	for _, tabela := range todasTabelas {
-		wg.Add(1) // Adiciona ao WaitGroup antes de iniciar a goroutine.
-
-		go func(tabela bson.M) {
-			defer wg.Done() // Garante que o Done() seja chamado ao final da goroutine.
-
-			codigo, ok := tabela["codigo"].(int32)
-			if !ok {
-				log.Printf("Código da tabela não encontrado ou não é int32: %v", tabela)
-				return // Apenas pula esta tabela se o código estiver malformado.
-			}
-
-			temVeiculos, err := TabelaTemVeiculos(ctx, int(codigo))
-			if err != nil {
-				// Logamos o erro, mas não paramos todo o processo.
-				// Um erro em uma tabela não deve impedir as outras de serem processadas.
-				log.Printf("Erro ao verificar veículos para a tabela %d: %v", codigo, err)
+	query := r.URL.Query()
+	var filtro services.FiltroTabelas
+	for nome, destino := range map[string]*int{"anoInicial": &filtro.AnoInicial, "anoFinal": &filtro.AnoFinal} {
+		if valor := query.Get(nome); valor != "" {
+			ano, err := strconv.Atoi(valor)
+			if err != nil || ano <= 0 {
+				http.Error(w, fmt.Sprintf("Parâmetro '%s' inválido", nome), http.StatusBadRequest)
 				return
 			}
-
-			if temVeiculos {
-				// 4. Se a condição for atendida, adicionamos ao slice de resultados de forma segura.
-				mutex.Lock()
-				tabelasFiltradas = append(tabelasFiltradas, tabela)
-				mutex.Unlock()
-			}
-		}(tabela)
+			*destino = ano
+		}
 	}
-
-	// 5. Esperamos todas as goroutines terminarem.
-	wg.Wait()
-
-	// 6. Enviamos a resposta.
-	// Neste ponto, `tabelasFiltradas` contém todos os resultados válidos.
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(tabelasFiltradas); err != nil {
-		log.Printf("Erro ao encodar a resposta JSON: %v", err)
-		// A resposta pode já ter sido parcialmente enviada, então não podemos setar um novo http.Error.
+	if filtro.AnoInicial != 0 && filtro.AnoFinal != 0 && filtro.AnoInicial > filtro.AnoFinal {
+		http.Error(w, "'anoInicial' deve ser menor ou igual a 'anoFinal'", http.StatusBadRequest)
+		return
+	}
+	filtro.Status = query.Get("status")
+	if filtro.Status != "" && filtro.Status != models.StatusTabelaPendente && filtro.Status != models.StatusTabelaPublicada {
+		http.Error(w, "Parâmetro 'status' inválido", http.StatusBadRequest)
+		return
 	}
-}
 
-// TabelaTemVeiculos verifica se existem documentos na coleção "Veiculos"
-// que correspondem a um determinado ID de tabela.
-func TabelaTemVeiculos(ctx context.Context, tabelaId int) (bool, error) {
-	collection := database.DB.Collection("Veiculos")
-	filter := bson.M{"monthYearId": tabelaId}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	// Usar CountDocuments é eficiente para esta verificação.
-	count, err := collection.CountDocuments(ctx, filter)
+	tabelas, err := services.ListarTabelas(ctx, filtro)
 	if err != nil {
-		return false, fmt.Errorf("falha ao contar documentos: %w", err)
+		log.Printf("Erro ao buscar tabelas de referência: %v", err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
+		return
 	}
-	return count > 0, nil
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tabelas)
 }
 
+// GetMarcas lista as marcas do catálogo. Com 'tabela', retorna o resumo de
+// cada marca naquela tabela; sem, todas as marcas já catalogadas.
 func GetMarcas(w http.ResponseWriter, r *http.Request) {
 	tabelaParam := r.URL.Query().Get("tabela")
+	tabelaId := 0
+	if tabelaParam != "" {
+		var ok bool
+		if tabelaId, ok = resolverTabelaParam(w, "tabela", tabelaParam); !ok {
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var marcas interface{}
+	var err error
 	if tabelaParam == "" {
-		http.Error(w, "Parâmetro 'tabela' é obrigatório", http.StatusBadRequest)
+		marcas, err = services.ListarMarcasGlobal(ctx)
+	} else {
+		marcas, err = services.ListarMarcas(ctx, tabelaId)
+	}
+	if err != nil {
+		log.Printf("Erro ao buscar marcas: %v", err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
 		return
 	}
-	tabelaId, err := strconv.Atoi(tabelaParam)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(marcas)
+}
+
+// GetMarca detalha uma marca do catálogo: o resumo na tabela pedida (ou na
+// mais recente) e o histórico por tabela.
+func GetMarca(w http.ResponseWriter, r *http.Request) {
+	codMarca, err := strconv.ParseInt(mux.Vars(r)["marca"], 10, 32)
 	if err != nil {
-		http.Error(w, "Parâmetro 'tabela' inválido", http.StatusBadRequest)
+		http.Error(w, "Código de marca inválido", http.StatusBadRequest)
 		return
 	}
+	tabelaId := 0
+	if tabelaParam := r.URL.Query().Get("tabela"); tabelaParam != "" {
+		var ok bool
+		if tabelaId, ok = resolverTabelaParam(w, "tabela", tabelaParam); !ok {
+			return
+		}
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	collection := database.DB.Collection("Veiculos")
-	filter := bson.M{"monthYearId": tabelaId}
-	projection := options.Find().SetProjection(bson.M{"brandName": 1, "brandCode": 1, "_id": 0})
-	cursor, err := collection.Find(ctx, filter, projection)
-	if err != nil {
-		log.Printf("Erro ao buscar marcas: %v", err)
-		http.Error(w, "Erro interno", http.StatusInternalServerError)
+	detalhe, err := services.DetalharMarca(ctx, int32(codMarca), tabelaId)
+	if errors.Is(err, services.ErrNaoEncontrado) {
+		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
-	var marcas []bson.M
-	if err := cursor.All(ctx, &marcas); err != nil {
-		log.Printf("Erro ao decodificar marcas: %v", err)
+	if err != nil {
+		log.Printf("Erro ao detalhar marca %d: %v", codMarca, err)
 		http.Error(w, "Erro interno", http.StatusInternalServerError)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(marcas)
+	json.NewEncoder(w).Encode(detalhe)
 }
 
 func GetModelos(w http.ResponseWriter, r *http.Request) {
@@ -156,9 +136,13 @@ func GetModelos(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Parâmetro 'tabela' é obrigatório", http.StatusBadRequest)
 		return
 	}
-	tabelaId, err := strconv.Atoi(tabelaParam)
+	tabelaId, ok := resolverTabelaParam(w, "tabela", tabelaParam)
+	if !ok {
+		return
+	}
+	segmento, err := parseSegmentoParam(r)
 	if err != nil {
-		http.Error(w, "Parâmetro 'tabela' inválido", http.StatusBadRequest)
+		http.Error(w, "Parâmetro 'segmento' inválido", http.StatusBadRequest)
 		return
 	}
 
@@ -173,13 +157,31 @@ func GetModelos(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Marca não encontrada", http.StatusNotFound)
 		return
 	}
-	models, ok := brand["models"]
+	models, ok := brand["models"].(primitive.A)
 	if !ok {
 		http.Error(w, "Modelos não encontrados", http.StatusNotFound)
 		return
 	}
+	classificador, err := segmentos.Atual(ctx)
+	if err != nil {
+		log.Printf("Erro ao carregar segmentos: %v", err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
+		return
+	}
+	brandName, _ := brand["brandName"].(string)
+	selecionados := primitive.A{}
+	for _, model := range models {
+		m, ok := model.(bson.M)
+		if !ok {
+			continue
+		}
+		if s := anotarSegmento(classificador, brandName, m); segmento != "" && s != segmento {
+			continue
+		}
+		selecionados = append(selecionados, m)
+	}
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(models)
+	json.NewEncoder(w).Encode(selecionados)
 }
 
 func GetVeiculos(w http.ResponseWriter, r *http.Request) {
@@ -195,9 +197,8 @@ func GetVeiculos(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tabelaId, err := strconv.Atoi(tabelaParam)
-	if err != nil {
-		http.Error(w, "Parâmetro 'tabela' inválido", http.StatusBadRequest)
+	tabelaId, ok := resolverTabelaParam(w, "tabela", tabelaParam)
+	if !ok {
 		return
 	}
 
@@ -247,6 +248,7 @@ func GetVeiculos(w http.ResponseWriter, r *http.Request) {
 				for _, year := range years {
 					if yearMap, isMap := year.(bson.M); isMap {
 						yearMap["model"] = m["modelName"]
+						anotarAno(yearMap)
 						selectedYears = append(selectedYears, yearMap)
 					}
 				}
@@ -269,42 +271,42 @@ func GetVeiculos(w http.ResponseWriter, r *http.Request) {
 
 // Dashboard de Marcas - de acordo com as marcas analisar para dois períodos
 // infomações como carro/modelo com menor e maior preço 0km, valor médio,
-// número de modelos disponíveis e as difenças em porcentagens entre esses aspectos
-
-func getTabelaRef(ctx context.Context, tabelaId int) (string, error) {
-	coll := database.DB.Collection("TabelaReferencia")
-	var result struct {
-		Mes string `bson:"mes"`
-	}
-
-	log.Printf("Log tabela: %d", tabelaId)
-
-	filter := bson.M{"codigo": tabelaId}
-	err := coll.FindOne(ctx, filter).Decode(&result)
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return fmt.Sprintf("Tabela %d", tabelaId), nil
-		}
-		return "", fmt.Errorf("erro ao buscar ref da tabela %d: %v", tabelaId, err)
-	}
-	return result.Mes, nil
-}
-
+// número de modelos disponíveis e as difenças em porcentagens entre esses aspectos.
+// O filtro 'combustivel' usa o fuel gravado ou, sem ele, o combustível do
+// yearCode, interpretado na consulta. Entradas só com "year" têm combustível
+// desconhecido e nunca passam no filtro.
 func GetDashboardMarcas(w http.ResponseWriter, r *http.Request) {
 
 	tabela1Param := r.URL.Query().Get("tabela1")
 	tabela2Param := r.URL.Query().Get("tabela2")
 	marcaParam := r.URL.Query().Get("marca")
+	combustivel, err := parseCombustivelParam(r)
+	if err != nil {
+		http.Error(w, "Parâmetro 'combustivel' inválido", http.StatusBadRequest)
+		return
+	}
+	segmento, err := parseSegmentoParam(r)
+	if err != nil {
+		http.Error(w, "Parâmetro 'segmento' inválido", http.StatusBadRequest)
+		return
+	}
+	excluirSinalizados, err := parseExcluirSinalizados(r)
+	if err != nil {
+		http.Error(w, "Parâmetro 'excluirSinalizados' inválido", http.StatusBadRequest)
+		return
+	}
 
 	if tabela1Param == "" || tabela2Param == "" {
 		http.Error(w, "Parâmetros 'tabela1' e 'tabela2' são obrigatórios", http.StatusBadRequest)
 		return
 	}
 
-	tabela1Id, err1 := strconv.Atoi(tabela1Param)
-	tabela2Id, err2 := strconv.Atoi(tabela2Param)
-	if err1 != nil || err2 != nil {
-		http.Error(w, "Parâmetros 'tabela1' ou 'tabela2' inválidos", http.StatusBadRequest)
+	tabela1Id, ok := resolverTabelaParam(w, "tabela1", tabela1Param)
+	if !ok {
+		return
+	}
+	tabela2Id, ok := resolverTabelaParam(w, "tabela2", tabela2Param)
+	if !ok {
 		return
 	}
 
@@ -327,263 +329,25 @@ func GetDashboardMarcas(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Iniciando GetDashboardMarcas para tabelas: %d e %d (todas as marcas)", tabela1Id, tabela2Id)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	var tabela1Ref, tabela2Ref string
-	var refErr1, refErr2 error
-	var wgRefs sync.WaitGroup
-	wgRefs.Add(2)
-	go func() { defer wgRefs.Done(); tabela1Ref, refErr1 = getTabelaRef(ctx, tabela1Id) }()
-	go func() { defer wgRefs.Done(); tabela2Ref, refErr2 = getTabelaRef(ctx, tabela2Id) }()
-	wgRefs.Wait()
-	if refErr1 != nil {
-		log.Printf("Erro ao buscar referência da tabela %d: %v", tabela1Id, refErr1)
-	}
-	if refErr2 != nil {
-		log.Printf("Erro ao buscar referência da tabela %d: %v", tabela2Id, refErr2)
-	}
-
-	statsTabela1 := make(map[int32]*models.BrandPeriodStats)
-	statsTabela2 := make(map[int32]*models.BrandPeriodStats)
-	BrandInfo := make(map[int32]struct {
-		Name string
-		Code int32
-	})
-
-	var processErr1, processErr2 error
-	var wgProcess sync.WaitGroup
-	wgProcess.Add(2)
-
-	processTable := func(tabelaId int, tabelaRef string, targetStats map[int32]*models.BrandPeriodStats, filterBrand *int32) error {
-		defer wgProcess.Done()
-		collection := database.DB.Collection("Veiculos")
-
-		filter := bson.M{"monthYearId": tabelaId}
-		if filterBrand != nil {
-			filter["brandCode"] = *filterBrand
-			log.Printf("Tabela %d: Aplicando filtro para brandCode: %d", tabelaId, *filterBrand)
-		} else {
-			log.Printf("Tabela %d: Buscando todas as marcas.", tabelaId)
-		}
-
-		cursor, err := collection.Find(ctx, filter)
-		if err != nil {
-			return fmt.Errorf("erro ao buscar dados da tabela %d com filtro %v: %v", tabelaId, filter, err)
-		}
-		defer cursor.Close(ctx)
-
-		processedBrands := 0
-		for cursor.Next(ctx) {
-			var doc bson.M
-			if err := cursor.Decode(&doc); err != nil {
-				log.Printf("Erro ao decodificar documento da tabela %d: %v", tabelaId, err)
-				continue
-			}
-
-			brandCode, okBC := doc["brandCode"].(int32)
-			brandName, okBN := doc["brandName"].(string)
-			if !okBC || !okBN {
-				continue
-			}
-
-			processedBrands++
-			modelosDisponiveis := 0
-
-			log.Printf("Verificando se existe a marca %d", brandCode)
-
-			if _, exists := BrandInfo[brandCode]; !exists {
-				BrandInfo[brandCode] = struct {
-					Name string
-					Code int32
-				}{Name: brandName, Code: brandCode}
-			}
-
-			log.Printf("Tabela %s", tabelaRef)
-
-			if _, exists := targetStats[brandCode]; !exists {
-				targetStats[brandCode] = &models.BrandPeriodStats{
-					Ref:                tabelaRef,
-					TabelaId:           tabelaId,
-					ModelosEncontrados: make(map[int32]struct{}),
-					MenorPreco0km:      models.PriceInfo{Valor: math.Inf(1)},
-					MaiorPreco0km:      models.PriceInfo{Valor: math.Inf(-1)},
-				}
-			}
-			stats := targetStats[brandCode]
-
-			modelsRaw, exists := doc["models"]
-			if !exists {
-				continue
-			}
-			modelsList, ok := modelsRaw.(primitive.A)
-			if !ok {
-				continue
-			}
-
-			for _, modelRaw := range modelsList {
-				model, okM := modelRaw.(bson.M)
-				if !okM {
-					continue
-				}
-				modelCode, okMC := model["modelCode"].(int32)
-				if !okMC {
-					continue
-				}
-				modelName, okMN := model["modelName"].(string)
-				if !okMN {
-					continue
-				}
-
-				stats.ModelosEncontrados[modelCode] = struct{}{}
-
-				yearsRaw, existsY := model["years"]
-				if !existsY {
-					continue
-				}
-				years, okY := yearsRaw.(primitive.A)
-				if !okY {
-					continue
-				}
-
-				for _, yearRaw := range years {
-					yearData, okYD := yearRaw.(bson.M)
-					if !okYD {
-						continue
-					}
-					yearVal, okYV := yearData["year"].(int32)
-					priceStr, okPS := yearData["price"].(string)
-
-					if yearVal == models.AnoZeroKm {
-						modelosDisponiveis++
-					}
-
-					if okYV && yearVal == models.AnoZeroKm && okPS {
-						price, errP := utils.ParsePrice(priceStr)
-						if errP != nil {
-							continue
-						}
-						stats.TotalVeiculos0km++
-						stats.SomaValores0km += price
-						if price < stats.MenorPreco0km.Valor {
-							stats.MenorPreco0km = models.PriceInfo{Modelo: modelName, Valor: price, ValorFmt: utils.FormatPrice(price)}
-						}
-						if price > stats.MaiorPreco0km.Valor {
-							stats.MaiorPreco0km = models.PriceInfo{Modelo: modelName, Valor: price, ValorFmt: utils.FormatPrice(price)}
-						}
-						stats.Inicializado = true
-					}
-				}
-			}
-
-			stats.TotalModelos = modelosDisponiveis
-			if stats.TotalVeiculos0km > 0 {
-				stats.ValorMedio0km = stats.SomaValores0km / float64(stats.TotalVeiculos0km)
-				stats.ValorMedio0kmFmt = utils.FormatPrice(stats.ValorMedio0km)
-			} else {
-				stats.ValorMedio0km = math.NaN()
-				stats.ValorMedio0kmFmt = "N/A"
-			}
-			if !stats.Inicializado {
-				stats.MenorPreco0km = models.PriceInfo{Modelo: "N/A", ValorFmt: "N/A", Valor: math.NaN()}
-				stats.MaiorPreco0km = models.PriceInfo{Modelo: "N/A", ValorFmt: "N/A", Valor: math.NaN()}
-			}
-		}
-
-		log.Printf("Tabela %d: Processou %d marcas (documentos).", tabelaId, processedBrands)
-
-		if err := cursor.Err(); err != nil {
-			return fmt.Errorf("erro no cursor da tabela %d: %v", tabelaId, err)
-		}
-		return nil
+	filtro := services.FiltroEstatisticas{
+		Marca:              marcaIdFiltro,
+		Combustivel:        combustivel,
+		Segmento:           segmento,
+		ExcluirSinalizados: excluirSinalizados,
 	}
 
-	go func() { processErr1 = processTable(tabela1Id, tabela1Ref, statsTabela1, marcaIdFiltro) }()
-	go func() { processErr2 = processTable(tabela2Id, tabela2Ref, statsTabela2, marcaIdFiltro) }()
-	wgProcess.Wait()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
 
-	if processErr1 != nil {
-		log.Printf("Erro ao processar tabela 1 (%d): %v", tabela1Id, processErr1)
-	}
-	if processErr2 != nil {
-		log.Printf("Erro ao processar tabela 2 (%d): %v", tabela2Id, processErr2)
+	dashboardResult, err := services.DashboardMarcas(ctx, tabela1Id, tabela2Id, filtro)
+	if errors.Is(err, services.ErrNaoEncontrado) {
+		http.Error(w, "Marca não encontrada nos períodos especificados", http.StatusNotFound)
+		return
 	}
-
-	var dashboardResult []models.DashboardBrandEntry
-
-	if marcaIdFiltro != nil {
-		brandCode := *marcaIdFiltro
-		info, ok := BrandInfo[brandCode]
-		if !ok {
-			http.Error(w, "Marca não encontrada nos períodos especificados", http.StatusNotFound)
-			return
-		}
-
-		stats1, ok1 := statsTabela1[brandCode]
-		stats2, ok2 := statsTabela2[brandCode]
-
-		if !ok1 {
-			stats1 = &models.BrandPeriodStats{Ref: tabela1Ref, TabelaId: tabela1Id, ValorMedio0km: math.NaN()}
-			stats1.MenorPreco0km = models.PriceInfo{Modelo: "N/A", ValorFmt: "N/A", Valor: math.NaN()}
-			stats1.MaiorPreco0km = models.PriceInfo{Modelo: "N/A", ValorFmt: "N/A", Valor: math.NaN()}
-			stats1.ValorMedio0kmFmt = "N/A"
-		}
-		if !ok2 {
-			stats2 = &models.BrandPeriodStats{Ref: tabela2Ref, TabelaId: tabela2Id, ValorMedio0km: math.NaN()}
-			stats2.MenorPreco0km = models.PriceInfo{Modelo: "N/A", ValorFmt: "N/A", Valor: math.NaN()}
-			stats2.MaiorPreco0km = models.PriceInfo{Modelo: "N/A", ValorFmt: "N/A", Valor: math.NaN()}
-			stats2.ValorMedio0kmFmt = "N/A"
-		}
-		diffs := models.PercentageDiffs{}
-		if diffAvg, ok := utils.CalculatePercentageDiff(stats1.ValorMedio0km, stats2.ValorMedio0km); ok {
-			diffs.ValorMedio0km = diffAvg
-		}
-		if diffModels, ok := utils.CalculatePercentageDiff(float64(stats1.TotalModelos), float64(stats2.TotalModelos)); ok {
-			diffs.TotalModelos = diffModels
-		}
-		entry := models.DashboardBrandEntry{
-			BrandName:             info.Name,
-			BrandCode:             info.Code,
-			Periodo1:              *stats1,
-			Periodo2:              *stats2,
-			DiferencasPercentuais: diffs,
-		}
-		dashboardResult = append(dashboardResult, entry)
-	} else {
-		for brandCode, info := range BrandInfo {
-			stats1, ok1 := statsTabela1[brandCode]
-			stats2, ok2 := statsTabela2[brandCode]
-
-			if !ok1 {
-				stats1 = &models.BrandPeriodStats{Ref: tabela1Ref, TabelaId: tabela1Id, ValorMedio0km: math.NaN()}
-				stats1.MenorPreco0km = models.PriceInfo{Modelo: "N/A", ValorFmt: "N/A", Valor: math.NaN()}
-				stats1.MaiorPreco0km = models.PriceInfo{Modelo: "N/A", ValorFmt: "N/A", Valor: math.NaN()}
-				stats1.ValorMedio0kmFmt = "N/A"
-			}
-			if !ok2 {
-				stats2 = &models.BrandPeriodStats{Ref: tabela2Ref, TabelaId: tabela2Id, ValorMedio0km: math.NaN()}
-				stats2.MenorPreco0km = models.PriceInfo{Modelo: "N/A", ValorFmt: "N/A", Valor: math.NaN()}
-				stats2.MaiorPreco0km = models.PriceInfo{Modelo: "N/A", ValorFmt: "N/A", Valor: math.NaN()}
-				stats2.ValorMedio0kmFmt = "N/A"
-			}
-
-			diffs := models.PercentageDiffs{}
-			if diffAvg, ok := utils.CalculatePercentageDiff(stats1.ValorMedio0km, stats2.ValorMedio0km); ok {
-				diffs.ValorMedio0km = diffAvg
-			}
-			if diffModels, ok := utils.CalculatePercentageDiff(float64(stats1.TotalModelos), float64(stats2.TotalModelos)); ok {
-				diffs.TotalModelos = diffModels
-			}
-
-			entry := models.DashboardBrandEntry{
-				BrandName:             info.Name,
-				BrandCode:             info.Code,
-				Periodo1:              *stats1,
-				Periodo2:              *stats2,
-				DiferencasPercentuais: diffs,
-			}
-			dashboardResult = append(dashboardResult, entry)
-		}
+	if err != nil {
+		log.Printf("Erro ao montar dashboard das tabelas %d e %d: %v", tabela1Id, tabela2Id, err)
+		http.Error(w, "Erro interno ao montar dashboard", http.StatusInternalServerError)
+		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -591,9 +355,11 @@ func GetDashboardMarcas(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Erro ao encodar resposta JSON: %v", err)
 		http.Error(w, "Erro interno ao gerar resposta", http.StatusInternalServerError)
 	}
-	log.Printf("GetDashboardMarcas concluído com sucesso para tabelas: %d, %d e marca %d/n", tabela1Id, tabela2Id, *marcaIdFiltro)
+	log.Printf("GetDashboardMarcas concluído com sucesso para tabelas: %d, %d (%d marcas)", tabela1Id, tabela2Id, len(dashboardResult))
 }
 
+// GetVeiculosNovos lista os anos 0km de uma tabela. Como no dashboard, o
+// filtro 'combustivel' interpreta o yearCode quando fuel não está gravado.
 func GetVeiculosNovos(w http.ResponseWriter, r *http.Request) {
 
 	tabelaParam := r.URL.Query().Get("tabela")
@@ -602,61 +368,54 @@ func GetVeiculosNovos(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tabelaId, err := strconv.Atoi(tabelaParam)
+	tabelaId, ok := resolverTabelaParam(w, "tabela", tabelaParam)
+	if !ok {
+		return
+	}
+	combustivel, err := parseCombustivelParam(r)
 	if err != nil {
-		http.Error(w, "Parâmetro 'tabela' inválido", http.StatusBadRequest)
+		http.Error(w, "Parâmetro 'combustivel' inválido", http.StatusBadRequest)
+		return
+	}
+	segmento, err := parseSegmentoParam(r)
+	if err != nil {
+		http.Error(w, "Parâmetro 'segmento' inválido", http.StatusBadRequest)
 		return
 	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	collection := database.DB.Collection("Veiculos")
-
-	filter := bson.M{
-		"monthYearId": tabelaId,
+	classificador, err := segmentos.Atual(ctx)
+	if err != nil {
+		log.Printf("Erro ao carregar segmentos: %v", err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
+		return
 	}
 
-	cursor, err := collection.Find(ctx, filter)
+	entradas, err := services.EntradasZeroKm(ctx, tabelaId)
 	if err != nil {
-		log.Printf("Erro ao decodificar documento da tabela %d: %v", tabelaId, err)
+		log.Printf("Erro ao buscar veículos 0km da tabela %d: %v", tabelaId, err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
+		return
 	}
 
 	var selectedYears []bson.M
-	for cursor.Next(ctx) {
-		var doc bson.M
-		if err := cursor.Decode(&doc); err != nil {
-			log.Printf("Erro ao decodificar documento da tabela %d: %v", tabelaId, err)
+	for _, entrada := range entradas {
+		detalhes := anotarAno(entrada.Ano)
+		if !detalhes.ZeroKm {
 			continue
 		}
-
-		modelsRaw, exists := doc["models"]
-		if !exists {
+		if combustivel != models.CombustivelDesconhecido && detalhes.Combustivel != combustivel {
 			continue
 		}
-
-		modelsList, ok := modelsRaw.(primitive.A)
-		if !ok {
+		segmentoModelo := classificador.Classificar(entrada.BrandName, entrada.ModelName, entrada.ModelCode)
+		if segmento != "" && segmentoModelo != segmento {
 			continue
 		}
-
-		for _, model := range modelsList {
-			m, ok := model.(bson.M)
-			if ok {
-				if years, exists := m["years"].(primitive.A); exists {
-					for _, year := range years {
-						if yearMap, isMap := year.(bson.M); isMap {
-							if yearMap["year"] == int32(32000) {
-								yearMap["model"] = m["modelName"]
-								selectedYears = append(selectedYears, yearMap)
-							}
-						}
-					}
-				} else {
-					log.Printf("Campo 'years' não encontrado ou não é um array no modelo selecionado.")
-				}
-			}
-		}
+		entrada.Ano["model"] = entrada.ModelName
+		entrada.Ano["segmento"] = segmentoModelo
+		selectedYears = append(selectedYears, entrada.Ano)
 	}
 
 	if len(selectedYears) == 0 {
@@ -668,3 +427,45 @@ func GetVeiculosNovos(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(selectedYears)
 }
+
+// anotarAno acrescenta à entrada de ano os campos interpretados do código da
+// FIPE: "anoModelo", "combustivel" e "zeroKm".
+func anotarAno(yearMap bson.M) models.CodigoAno {
+	detalhes := services.DetalhesAno(yearMap)
+	yearMap["anoModelo"] = detalhes.AnoModelo
+	yearMap["combustivel"] = detalhes.Combustivel
+	yearMap["zeroKm"] = detalhes.ZeroKm
+	return detalhes
+}
+
+// parseCombustivelParam lê o filtro opcional 'combustivel' (flex, diesel,
+// eletrico, hibrido, gasolina, alcool, gnv ou o código numérico da FIPE).
+func parseCombustivelParam(r *http.Request) (models.Combustivel, error) {
+	param := r.URL.Query().Get("combustivel")
+	if param == "" {
+		return models.CombustivelDesconhecido, nil
+	}
+	return models.ParseCombustivel(param)
+}
+
+// resolverTabelaParam resolve um parâmetro de tabela: o código, o período ("2024-03")
+// ou "latest". Responde 400 se o valor for inválido e 404 se não houver
+// tabela correspondente.
+func resolverTabelaParam(w http.ResponseWriter, nome, valor string) (int, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tabelaId, err := services.ResolverTabela(ctx, valor)
+	switch {
+	case err == nil:
+		return tabelaId, true
+	case errors.Is(err, services.ErrTabelaInvalida):
+		http.Error(w, fmt.Sprintf("Parâmetro '%s' inválido: use o código, o mês (AAAA-MM) ou '%s'", nome, services.TabelaMaisRecente), http.StatusBadRequest)
+	case errors.Is(err, services.ErrNaoEncontrado):
+		http.Error(w, fmt.Sprintf("Tabela '%s' não encontrada", valor), http.StatusNotFound)
+	default:
+		log.Printf("Erro ao resolver tabela '%s': %v", valor, err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
+	}
+	return 0, false
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fipe_project/internal/models"
+	"fipe_project/internal/services"
+)
+
+// GetIndice calcula o índice de preços entre as tabelas 'de' e 'ate'.
+// Opcionais: 'pesos' (igual ou segmento), 'subindices' (lista separada por
+// vírgula de marca, segmento e idade), os filtros do dashboard ('marca',
+// 'combustivel', 'segmento') e 'formato=csv' para exportar as séries.
+func GetIndice(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("de") == "" || q.Get("ate") == "" {
+		http.Error(w, "Parâmetros 'de' e 'ate' são obrigatórios", http.StatusBadRequest)
+		return
+	}
+	de, ok := resolverTabelaParam(w, "de", q.Get("de"))
+	if !ok {
+		return
+	}
+	ate, ok := resolverTabelaParam(w, "ate", q.Get("ate"))
+	if !ok {
+		return
+	}
+	formato := q.Get("formato")
+	if formato != "" && formato != "json" && formato != "csv" {
+		http.Error(w, "Parâmetro 'formato' deve ser 'json' ou 'csv'", http.StatusBadRequest)
+		return
+	}
+
+	consulta := services.ConsultaIndice{De: de, Ate: ate, Pesos: q.Get("pesos")}
+	if v := q.Get("subindices"); v != "" {
+		for _, g := range strings.Split(v, ",") {
+			consulta.Subindices = append(consulta.Subindices, strings.TrimSpace(g))
+		}
+	}
+	if marcaParam := q.Get("marca"); marcaParam != "" {
+		marca, err := strconv.Atoi(marcaParam)
+		if err != nil {
+			http.Error(w, "Parâmetro 'marca' inválido", http.StatusBadRequest)
+			return
+		}
+		codigo := int32(marca)
+		consulta.Filtro.Marca = &codigo
+	}
+	var err error
+	if consulta.Filtro.Combustivel, err = parseCombustivelParam(r); err != nil {
+		http.Error(w, "Parâmetro 'combustivel' inválido", http.StatusBadRequest)
+		return
+	}
+	if consulta.Filtro.Segmento, err = parseSegmentoParam(r); err != nil {
+		http.Error(w, "Parâmetro 'segmento' inválido", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 120*time.Second)
+	defer cancel()
+
+	indice, err := services.CalcularIndice(ctx, consulta)
+	if errors.Is(err, services.ErrIndiceInvalido) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao calcular índice de %d a %d: %v", de, ate, err)
+		http.Error(w, "Erro interno ao calcular índice", http.StatusInternalServerError)
+		return
+	}
+
+	if formato == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="indice_%d_%d.csv"`, de, ate))
+		if err := escreverIndiceCSV(w, indice); err != nil {
+			log.Printf("Erro ao exportar índice em CSV: %v", err)
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(indice)
+}
+
+// escreverIndiceCSV exporta uma linha por série e tabela, com o índice geral
+// primeiro.
+func escreverIndiceCSV(w http.ResponseWriter, indice *models.IndicePrecos) error {
+	escritor := csv.NewWriter(w)
+	escritor.Write([]string{"grupo", "chave", "nome", "tabelaId", "ref", "indice", "variacao", "itens"})
+	for _, serie := range append([]models.SerieIndice{indice.Geral}, indice.Subindices...) {
+		for _, p := range serie.Pontos {
+			variacao := ""
+			if p.Variacao != nil {
+				variacao = strconv.FormatFloat(*p.Variacao, 'f', 2, 64)
+			}
+			escritor.Write([]string{
+				serie.Grupo,
+				serie.Chave,
+				serie.Nome,
+				strconv.Itoa(p.TabelaId),
+				strings.TrimSpace(p.Ref),
+				strconv.FormatFloat(p.Valor, 'f', 4, 64),
+				variacao,
+				strconv.Itoa(p.Itens),
+			})
+		}
+	}
+	escritor.Flush()
+	return escritor.Error()
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fipe_project/internal/ipva"
+	"fipe_project/internal/services"
+)
+
+// GetIPVA calcula o IPVA de um ano-modelo. Parâmetros: 'uf', 'modelo', 'ano'
+// e 'tabela' (de onde vem o valor FIPE); 'exercicio' é opcional e, por
+// padrão, é o ano da tabela.
+func GetIPVA(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	uf := q.Get("uf")
+	modeloParam := q.Get("modelo")
+	anoParam := q.Get("ano")
+	tabelaParam := q.Get("tabela")
+	if uf == "" || modeloParam == "" || anoParam == "" || tabelaParam == "" {
+		http.Error(w, "Parâmetros 'uf', 'modelo', 'ano' e 'tabela' são obrigatórios", http.StatusBadRequest)
+		return
+	}
+	modeloId, err := strconv.Atoi(modeloParam)
+	if err != nil {
+		http.Error(w, "Parâmetro 'modelo' inválido", http.StatusBadRequest)
+		return
+	}
+	tabelaId, ok := resolverTabelaParam(w, "tabela", tabelaParam)
+	if !ok {
+		return
+	}
+	ano, err := parseAnoParam(anoParam)
+	if err != nil {
+		http.Error(w, "Parâmetro 'ano' inválido", http.StatusBadRequest)
+		return
+	}
+	exercicio := 0
+	if v := q.Get("exercicio"); v != "" {
+		if exercicio, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Parâmetro 'exercicio' inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	calculo, err := services.CalcularIPVA(ctx, uf, tabelaId, modeloId, ano, exercicio)
+	if errors.Is(err, ipva.ErrUFInvalida) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrNaoEncontrado) {
+		http.Error(w, "Veículo não encontrado na tabela informada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao calcular IPVA (%s) do modelo %d/%d na tabela %d: %v", uf, modeloId, ano, tabelaId, err)
+		http.Error(w, "Erro interno ao calcular IPVA", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(calculo)
+}
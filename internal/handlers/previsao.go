@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fipe_project/internal/services"
+)
+
+// GetPrevisao projeta o preço de um ano-modelo para as próximas tabelas.
+// Parâmetros obrigatórios: 'modelo', 'ano' e 'tabela' (origem da projeção);
+// opcionais: 'meses' (1 a 12, padrão 3), 'historico' (tabelas usadas no
+// ajuste e no backtesting, padrão 36) e 'confianca' (0.8, 0.9, 0.95 ou 0.99).
+func GetPrevisao(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	modeloParam := q.Get("modelo")
+	anoParam := q.Get("ano")
+	tabelaParam := q.Get("tabela")
+	if modeloParam == "" || anoParam == "" || tabelaParam == "" {
+		http.Error(w, "Parâmetros 'modelo', 'ano' e 'tabela' são obrigatórios", http.StatusBadRequest)
+		return
+	}
+
+	var consulta services.ConsultaPrevisao
+	var err error
+	if consulta.ModeloId, err = strconv.Atoi(modeloParam); err != nil {
+		http.Error(w, "Parâmetro 'modelo' inválido", http.StatusBadRequest)
+		return
+	}
+	var ok bool
+	if consulta.TabelaId, ok = resolverTabelaParam(w, "tabela", tabelaParam); !ok {
+		return
+	}
+	if consulta.Ano, err = parseAnoParam(anoParam); err != nil {
+		http.Error(w, "Parâmetro 'ano' inválido", http.StatusBadRequest)
+		return
+	}
+	inteiros := []struct {
+		nome  string
+		valor *int
+	}{{"meses", &consulta.Meses}, {"historico", &consulta.Historico}}
+	for _, p := range inteiros {
+		if v := q.Get(p.nome); v != "" {
+			if *p.valor, err = strconv.Atoi(v); err != nil {
+				http.Error(w, fmt.Sprintf("Parâmetro '%s' inválido", p.nome), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if v := q.Get("confianca"); v != "" {
+		if consulta.Confianca, err = strconv.ParseFloat(v, 64); err != nil {
+			http.Error(w, "Parâmetro 'confianca' inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resultado, err := services.PreverPreco(ctx, consulta)
+	if errors.Is(err, services.ErrPrevisaoInvalida) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrNaoEncontrado) {
+		http.Error(w, "Veículo não encontrado na tabela informada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao prever preço do modelo %d/%d na tabela %d: %v", consulta.ModeloId, consulta.Ano, consulta.TabelaId, err)
+		http.Error(w, "Erro interno ao calcular previsão", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resultado)
+}
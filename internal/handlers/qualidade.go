@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fipe_project/internal/models"
+	"fipe_project/internal/services"
+)
+
+// GetQualidade devolve o relatório de qualidade de dados de uma tabela.
+// Opcionais: 'marca', 'tipo' (mostra só um tipo de ocorrência) e os limiares
+// 'fator' (razão para outliers na família e entre anos, padrão 3) e 'salto'
+// (variação mensal em %, padrão 30).
+func GetQualidade(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("tabela") == "" {
+		http.Error(w, "Parâmetro 'tabela' é obrigatório", http.StatusBadRequest)
+		return
+	}
+	tabelaId, ok := resolverTabelaParam(w, "tabela", q.Get("tabela"))
+	if !ok {
+		return
+	}
+	var marca *int32
+	if marcaParam := q.Get("marca"); marcaParam != "" {
+		m, err := strconv.Atoi(marcaParam)
+		if err != nil {
+			http.Error(w, "Parâmetro 'marca' inválido", http.StatusBadRequest)
+			return
+		}
+		codigo := int32(m)
+		marca = &codigo
+	}
+	tipo := models.TipoOcorrencia(q.Get("tipo"))
+	if tipo != "" && !tipo.Valido() {
+		http.Error(w, "Parâmetro 'tipo' inválido", http.StatusBadRequest)
+		return
+	}
+
+	limites := services.LimitesQualidadePadrao
+	if v := q.Get("fator"); v != "" {
+		fator, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			http.Error(w, "Parâmetro 'fator' inválido", http.StatusBadRequest)
+			return
+		}
+		limites.FatorFamilia, limites.FatorAnos = fator, fator
+	}
+	if v := q.Get("salto"); v != "" {
+		var err error
+		if limites.SaltoMensal, err = strconv.ParseFloat(v, 64); err != nil {
+			http.Error(w, "Parâmetro 'salto' inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	relatorio, err := services.AnalisarQualidade(ctx, tabelaId, marca, limites)
+	if errors.Is(err, services.ErrQualidadeInvalida) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao analisar qualidade da tabela %d: %v", tabelaId, err)
+		http.Error(w, "Erro interno ao analisar tabela", http.StatusInternalServerError)
+		return
+	}
+	if tipo != "" {
+		filtradas := []models.OcorrenciaQualidade{}
+		for _, o := range relatorio.Ocorrencias {
+			if o.Tipo == tipo {
+				filtradas = append(filtradas, o)
+			}
+		}
+		relatorio.Ocorrencias = filtradas
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(relatorio)
+}
+
+// parseExcluirSinalizados lê a opção 'excluirSinalizados' do dashboard.
+func parseExcluirSinalizados(r *http.Request) (bool, error) {
+	param := r.URL.Query().Get("excluirSinalizados")
+	if param == "" {
+		return false, nil
+	}
+	excluir, err := strconv.ParseBool(param)
+	if err != nil {
+		return false, fmt.Errorf("valor inválido para 'excluirSinalizados': '%s'", param)
+	}
+	return excluir, nil
+}
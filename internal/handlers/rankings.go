@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"fipe_project/internal/services"
+)
+
+// GetRankings devolve o top/bottom N de anos-modelo (ou marcas, com
+// nivel=marca) por preço, variação entre tabelas, depreciação ou volatilidade.
+// Ver services.ConsultaRanking para os parâmetros.
+func GetRankings(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("tabela") == "" {
+		http.Error(w, "Parâmetro 'tabela' é obrigatório", http.StatusBadRequest)
+		return
+	}
+	tabelaId, ok := resolverTabelaParam(w, "tabela", q.Get("tabela"))
+	if !ok {
+		return
+	}
+	consulta := services.ConsultaRanking{
+		TabelaId: tabelaId,
+		Criterio: q.Get("criterio"),
+		Ordem:    q.Get("ordem"),
+		Nivel:    q.Get("nivel"),
+	}
+
+	if base := q.Get("base"); base != "" {
+		if consulta.TabelaBaseId, ok = resolverTabelaParam(w, "base", base); !ok {
+			return
+		}
+	}
+	inteiros := []struct {
+		nome  string
+		valor *int
+	}{{"n", &consulta.N}, {"meses", &consulta.Meses}}
+	var err error
+	for _, p := range inteiros {
+		if v := q.Get(p.nome); v != "" {
+			if *p.valor, err = strconv.Atoi(v); err != nil {
+				http.Error(w, fmt.Sprintf("Parâmetro '%s' inválido", p.nome), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if marcaParam := q.Get("marca"); marcaParam != "" {
+		marca, err := strconv.Atoi(marcaParam)
+		if err != nil {
+			http.Error(w, "Parâmetro 'marca' inválido", http.StatusBadRequest)
+			return
+		}
+		codigo := int32(marca)
+		consulta.Filtro.Marca = &codigo
+	}
+	if consulta.Filtro.Combustivel, err = parseCombustivelParam(r); err != nil {
+		http.Error(w, "Parâmetro 'combustivel' inválido", http.StatusBadRequest)
+		return
+	}
+	if consulta.Filtro.Segmento, err = parseSegmentoParam(r); err != nil {
+		http.Error(w, "Parâmetro 'segmento' inválido", http.StatusBadRequest)
+		return
+	}
+	if consulta.IdadeMin, consulta.IdadeMax, err = parseFaixaIdade(q.Get("idade")); err != nil {
+		http.Error(w, "Parâmetro 'idade' inválido (use '3', '1-5', '8-' ou '-2')", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	ranking, err := services.Rankings(ctx, consulta)
+	if errors.Is(err, services.ErrRankingInvalido) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao calcular ranking: %v", err)
+		http.Error(w, "Erro interno ao calcular ranking", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ranking)
+}
+
+// parseFaixaIdade interpreta a faixa de idade em anos: "3", "1-5", "8-"
+// (8 ou mais) ou "-2" (até 2). Vazio não filtra.
+func parseFaixaIdade(faixa string) (*int, *int, error) {
+	if faixa == "" {
+		return nil, nil, nil
+	}
+	limite := func(s string) (*int, error) {
+		if s == "" {
+			return nil, nil
+		}
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 0 {
+			return nil, fmt.Errorf("idade inválida: '%s'", s)
+		}
+		return &v, nil
+	}
+	minStr, maxStr, intervalo := strings.Cut(faixa, "-")
+	if !intervalo {
+		maxStr = minStr
+	}
+	minimo, err := limite(minStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	maximo, err := limite(maxStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if minimo != nil && maximo != nil && *minimo > *maximo {
+		return nil, nil, fmt.Errorf("faixa de idade invertida: '%s'", faixa)
+	}
+	return minimo, maximo, nil
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fipe_project/internal/models"
+	"fipe_project/internal/report"
+	"fipe_project/internal/services"
+)
+
+// GetRelatorioVeiculo gera o laudo de avaliação em PDF de um ano-modelo
+// (parâmetros 'modelo', 'ano' e 'tabela'). O ano aceita "0km" ou 32000.
+func GetRelatorioVeiculo(w http.ResponseWriter, r *http.Request) {
+	modeloParam := r.URL.Query().Get("modelo")
+	anoParam := r.URL.Query().Get("ano")
+	tabelaParam := r.URL.Query().Get("tabela")
+	if modeloParam == "" || anoParam == "" || tabelaParam == "" {
+		http.Error(w, "Parâmetros 'modelo', 'ano' e 'tabela' são obrigatórios", http.StatusBadRequest)
+		return
+	}
+
+	modeloId, err := strconv.Atoi(modeloParam)
+	if err != nil {
+		http.Error(w, "Parâmetro 'modelo' inválido", http.StatusBadRequest)
+		return
+	}
+	tabelaId, ok := resolverTabelaParam(w, "tabela", tabelaParam)
+	if !ok {
+		return
+	}
+	ano, err := parseAnoParam(anoParam)
+	if err != nil {
+		http.Error(w, "Parâmetro 'ano' inválido", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	laudo, err := services.LaudoVeiculo(ctx, tabelaId, modeloId, ano)
+	if errors.Is(err, services.ErrNaoEncontrado) {
+		http.Error(w, "Veículo não encontrado na tabela informada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao montar laudo do modelo %d/%d na tabela %d: %v", modeloId, ano, tabelaId, err)
+		http.Error(w, "Erro interno ao gerar laudo", http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := report.LaudoPDF(&buf, laudo); err != nil {
+		log.Printf("Erro ao renderizar PDF do laudo: %v", err)
+		http.Error(w, "Erro interno ao gerar laudo", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="laudo_%d_%s_%d.pdf"`, modeloId, anoParam, tabelaId))
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("Erro ao enviar PDF do laudo: %v", err)
+	}
+}
+
+// parseAnoParam converte o parâmetro de ano-modelo, aceitando "0km" como
+// sinônimo de models.AnoZeroKm e códigos de ano da FIPE ("2019-1"). Ver
+// models.ParseAnoConsulta.
+func parseAnoParam(anoParam string) (int32, error) {
+	codigo, err := models.ParseAnoConsulta(anoParam)
+	if err != nil {
+		return 0, err
+	}
+	return codigo.AnoModelo, nil
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fipe_project/internal/services"
+)
+
+// GetValorResidual estima o valor de um ano-modelo em uma data futura e o
+// compara com a troca pelo mesmo modelo 0km. Parâmetros: 'modelo', 'ano',
+// 'tabela' e o horizonte em 'meses' ou 'data' (AAAA-MM).
+func GetValorResidual(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	modeloParam := q.Get("modelo")
+	anoParam := q.Get("ano")
+	tabelaParam := q.Get("tabela")
+	if modeloParam == "" || anoParam == "" || tabelaParam == "" {
+		http.Error(w, "Parâmetros 'modelo', 'ano' e 'tabela' são obrigatórios", http.StatusBadRequest)
+		return
+	}
+	if (q.Get("meses") == "") == (q.Get("data") == "") {
+		http.Error(w, "Informe 'meses' ou 'data' (AAAA-MM)", http.StatusBadRequest)
+		return
+	}
+
+	consulta := services.ConsultaResidual{DataAlvo: q.Get("data")}
+	var err error
+	if consulta.ModeloId, err = strconv.Atoi(modeloParam); err != nil {
+		http.Error(w, "Parâmetro 'modelo' inválido", http.StatusBadRequest)
+		return
+	}
+	var ok bool
+	if consulta.TabelaId, ok = resolverTabelaParam(w, "tabela", tabelaParam); !ok {
+		return
+	}
+	if consulta.Ano, err = parseAnoParam(anoParam); err != nil {
+		http.Error(w, "Parâmetro 'ano' inválido", http.StatusBadRequest)
+		return
+	}
+	if v := q.Get("meses"); v != "" {
+		if consulta.Meses, err = strconv.Atoi(v); err != nil {
+			http.Error(w, "Parâmetro 'meses' inválido", http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	resultado, err := services.CalcularResidual(ctx, consulta)
+	if errors.Is(err, services.ErrResidualInvalido) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrNaoEncontrado) {
+		http.Error(w, "Veículo não encontrado na tabela informada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Erro ao calcular valor residual do modelo %d/%d na tabela %d: %v", consulta.ModeloId, consulta.Ano, consulta.TabelaId, err)
+		http.Error(w, "Erro interno ao calcular valor residual", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resultado)
+}
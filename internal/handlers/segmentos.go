@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"fipe_project/internal/models"
+	"fipe_project/internal/segmentos"
+	"fipe_project/internal/services"
+)
+
+// GetSegmentos lista os segmentos, as regras do arquivo de regras (na ordem
+// em que são avaliadas) e os overrides manuais.
+func GetSegmentos(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	classificador, err := segmentos.Atual(ctx)
+	if err != nil {
+		log.Printf("Erro ao carregar segmentos: %v", err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
+		return
+	}
+	manuais, err := segmentos.ListarManuais(ctx)
+	if err != nil {
+		log.Printf("Erro ao listar segmentos manuais: %v", err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"segmentos": models.Segmentos,
+		"regras":    classificador.Regras(),
+		"manuais":   manuais,
+	})
+}
+
+// PutSegmentoManual fixa o segmento de um modelo (corpo {"segmento": "suv"}),
+// com prioridade sobre as regras.
+func PutSegmentoManual(w http.ResponseWriter, r *http.Request) {
+	modelCode, err := strconv.Atoi(mux.Vars(r)["modelo"])
+	if err != nil {
+		http.Error(w, "Código de modelo inválido", http.StatusBadRequest)
+		return
+	}
+	var manual models.SegmentoManual
+	if err := json.NewDecoder(r.Body).Decode(&manual); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+	manual.ModelCode = int32(modelCode)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := segmentos.DefinirManual(ctx, &manual); err != nil {
+		if errors.Is(err, segmentos.ErrSegmentoInvalido) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("Erro ao gravar segmento manual: %v", err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(manual)
+}
+
+func DeleteSegmentoManual(w http.ResponseWriter, r *http.Request) {
+	modelCode, err := strconv.Atoi(mux.Vars(r)["modelo"])
+	if err != nil {
+		http.Error(w, "Código de modelo inválido", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := segmentos.RemoverManual(ctx, int32(modelCode)); err != nil {
+		if errors.Is(err, segmentos.ErrManualNaoEncontrado) {
+			http.Error(w, "Modelo sem segmento manual", http.StatusNotFound)
+			return
+		}
+		log.Printf("Erro ao remover segmento manual: %v", err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetDashboardSegmentos compara, entre dois períodos, as estatísticas 0km de
+// cada segmento somando todas as marcas. Aceita os mesmos filtros opcionais do
+// dashboard de marcas ('marca', 'combustivel', 'segmento' e
+// 'excluirSinalizados').
+func GetDashboardSegmentos(w http.ResponseWriter, r *http.Request) {
+	tabela1Param, tabela2Param := r.URL.Query().Get("tabela1"), r.URL.Query().Get("tabela2")
+	if tabela1Param == "" || tabela2Param == "" {
+		http.Error(w, "Parâmetros 'tabela1' e 'tabela2' são obrigatórios", http.StatusBadRequest)
+		return
+	}
+	tabela1Id, ok := resolverTabelaParam(w, "tabela1", tabela1Param)
+	if !ok {
+		return
+	}
+	tabela2Id, ok := resolverTabelaParam(w, "tabela2", tabela2Param)
+	if !ok {
+		return
+	}
+	if tabela1Id == tabela2Id {
+		http.Error(w, "Os períodos de comparação devem ser diferentes", http.StatusBadRequest)
+		return
+	}
+
+	var filtro services.FiltroEstatisticas
+	if marcaParam := r.URL.Query().Get("marca"); marcaParam != "" {
+		marca, err := strconv.Atoi(marcaParam)
+		if err != nil {
+			http.Error(w, "Parâmetro 'marca' inválido", http.StatusBadRequest)
+			return
+		}
+		codigo := int32(marca)
+		filtro.Marca = &codigo
+	}
+	var err error
+	if filtro.Combustivel, err = parseCombustivelParam(r); err != nil {
+		http.Error(w, "Parâmetro 'combustivel' inválido", http.StatusBadRequest)
+		return
+	}
+	if filtro.Segmento, err = parseSegmentoParam(r); err != nil {
+		http.Error(w, "Parâmetro 'segmento' inválido", http.StatusBadRequest)
+		return
+	}
+	if filtro.ExcluirSinalizados, err = parseExcluirSinalizados(r); err != nil {
+		http.Error(w, "Parâmetro 'excluirSinalizados' inválido", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	ids := [2]int{tabela1Id, tabela2Id}
+	var refs [2]string
+	var stats [2]map[models.Segmento]*models.BrandPeriodStats
+	var errs [2]error
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if refs[i], errs[i] = services.TabelaRef(ctx, ids[i]); errs[i] != nil {
+				return
+			}
+			stats[i], errs[i] = services.EstatisticasSegmentos(ctx, ids[i], refs[i], filtro)
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			log.Printf("Erro ao processar segmentos da tabela %d: %v", ids[i], err)
+			http.Error(w, "Erro interno ao gerar dashboard", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	resultado := []models.DashboardSegmentoEntry{}
+	for _, segmento := range models.Segmentos {
+		s1, ok1 := stats[0][segmento]
+		s2, ok2 := stats[1][segmento]
+		if !ok1 && !ok2 {
+			continue
+		}
+		if !ok1 {
+			s1 = services.EstatisticasIndisponiveis(refs[0], ids[0])
+		}
+		if !ok2 {
+			s2 = services.EstatisticasIndisponiveis(refs[1], ids[1])
+		}
+		resultado = append(resultado, models.DashboardSegmentoEntry{
+			Segmento:              segmento,
+			Periodo1:              *s1,
+			Periodo2:              *s2,
+			DiferencasPercentuais: services.CompararEstatisticas(s1, s2),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resultado); err != nil {
+		log.Printf("Erro ao encodar resposta JSON: %v", err)
+	}
+}
+
+// anotarSegmento classifica um modelo lido como bson.M e grava o resultado no
+// campo "segmento".
+func anotarSegmento(c *segmentos.Classificador, brandName string, model bson.M) models.Segmento {
+	modelCode, _ := model["modelCode"].(int32)
+	modelName, _ := model["modelName"].(string)
+	segmento := c.Classificar(brandName, modelName, modelCode)
+	model["segmento"] = segmento
+	return segmento
+}
+
+// parseSegmentoParam lê o filtro opcional 'segmento'.
+func parseSegmentoParam(r *http.Request) (models.Segmento, error) {
+	segmento := models.Segmento(r.URL.Query().Get("segmento"))
+	if segmento == "" {
+		return "", nil
+	}
+	if !segmento.Valido() {
+		return "", fmt.Errorf("%w: '%s'", segmentos.ErrSegmentoInvalido, segmento)
+	}
+	return segmento, nil
+}
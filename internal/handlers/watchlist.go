@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"fipe_project/internal/alerts"
+	"fipe_project/internal/models"
+	"fipe_project/internal/services"
+)
+
+// GetWatchlist lista os itens da watchlist. Os segredos dos webhooks não são devolvidos.
+func GetWatchlist(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	itens, err := alerts.Listar(ctx)
+	if err != nil {
+		log.Printf("Erro ao listar watchlist: %v", err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
+		return
+	}
+	for i := range itens {
+		itens[i].Segredo = ""
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(itens)
+}
+
+// PostWatchlist cria um item na watchlist. A resposta traz o segredo usado
+// para assinar os webhooks; ele não é exibido novamente.
+func PostWatchlist(w http.ResponseWriter, r *http.Request) {
+	var item models.ItemWatchlist
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := alerts.Criar(ctx, &item); err != nil {
+		responderErroWatchlist(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(item)
+}
+
+func GetItemWatchlist(w http.ResponseWriter, r *http.Request) {
+	id, ok := watchIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	item, err := alerts.Buscar(ctx, id)
+	if err != nil {
+		responderErroWatchlist(w, err)
+		return
+	}
+	item.Segredo = ""
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+func PutItemWatchlist(w http.ResponseWriter, r *http.Request) {
+	id, ok := watchIdParam(w, r)
+	if !ok {
+		return
+	}
+	var item models.ItemWatchlist
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		http.Error(w, "JSON inválido", http.StatusBadRequest)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := alerts.Atualizar(ctx, id, &item); err != nil {
+		responderErroWatchlist(w, err)
+		return
+	}
+	item.Segredo = ""
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(item)
+}
+
+func DeleteItemWatchlist(w http.ResponseWriter, r *http.Request) {
+	id, ok := watchIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := alerts.Remover(ctx, id); err != nil {
+		responderErroWatchlist(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetEntregasWatchlist retorna o log de entregas de webhook, opcionalmente
+// filtrado pelo parâmetro 'watchId'.
+func GetEntregasWatchlist(w http.ResponseWriter, r *http.Request) {
+	var filtro *primitive.ObjectID
+	if watchParam := r.URL.Query().Get("watchId"); watchParam != "" {
+		id, err := primitive.ObjectIDFromHex(watchParam)
+		if err != nil {
+			http.Error(w, "Parâmetro 'watchId' inválido", http.StatusBadRequest)
+			return
+		}
+		filtro = &id
+	}
+	limite := int64(100)
+	if limiteParam := r.URL.Query().Get("limite"); limiteParam != "" {
+		l, err := strconv.Atoi(limiteParam)
+		if err != nil || l <= 0 {
+			http.Error(w, "Parâmetro 'limite' inválido", http.StatusBadRequest)
+			return
+		}
+		limite = int64(l)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	entregas, err := alerts.ListarEntregas(ctx, filtro, limite)
+	if err != nil {
+		log.Printf("Erro ao listar entregas: %v", err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entregas)
+}
+
+// PostAvaliarWatchlist avalia a watchlist contra a tabela informada, sem
+// esperar o monitor de novas tabelas. Útil para reprocessar uma tabela.
+func PostAvaliarWatchlist(w http.ResponseWriter, r *http.Request) {
+	tabelaParam := r.URL.Query().Get("tabela")
+	if tabelaParam == "" {
+		http.Error(w, "Parâmetro 'tabela' é obrigatório", http.StatusBadRequest)
+		return
+	}
+	tabelaId, ok := resolverTabelaParam(w, "tabela", tabelaParam)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	disparados, err := alerts.AvaliarTabela(ctx, tabelaId)
+	if err != nil {
+		log.Printf("Erro ao avaliar watchlist na tabela %d: %v", tabelaId, err)
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+	if disparados == nil {
+		disparados = []models.AlertaPreco{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(disparados)
+}
+
+// PostTesteWatchlist envia um evento de teste assinado ao webhook do item.
+func PostTesteWatchlist(w http.ResponseWriter, r *http.Request) {
+	id, ok := watchIdParam(w, r)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	item, err := alerts.Buscar(ctx, id)
+	if err != nil {
+		responderErroWatchlist(w, err)
+		return
+	}
+	alerta := models.AlertaPreco{
+		Evento:           "teste",
+		WatchId:          item.Id,
+		Nome:             item.Nome,
+		ModelCode:        item.ModelCode,
+		Ano:              item.Ano,
+		LimitePercentual: item.LimitePercentual,
+		DisparadoEm:      time.Now(),
+	}
+	entrega, err := alerts.Entregar(ctx, *item, 0, alerta)
+	if err != nil {
+		log.Printf("Erro ao enviar teste do item %s: %v", id.Hex(), err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(entrega)
+}
+
+func watchIdParam(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, bool) {
+	id, err := primitive.ObjectIDFromHex(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, "Identificador inválido", http.StatusBadRequest)
+		return id, false
+	}
+	return id, true
+}
+
+func responderErroWatchlist(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, alerts.ErrWatchInvalido):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, services.ErrNaoEncontrado):
+		http.Error(w, "Item da watchlist não encontrado", http.StatusNotFound)
+	default:
+		log.Printf("Erro na watchlist: %v", err)
+		http.Error(w, "Erro interno", http.StatusInternalServerError)
+	}
+}
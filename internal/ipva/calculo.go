@@ -0,0 +1,44 @@
+package ipva
+
+import (
+	"fmt"
+
+	"fipe_project/internal/models"
+)
+
+// Aplicar calcula o imposto sobre calculo.ValorBase com a regra da UF,
+// considerando calculo.Idade e calculo.Combustivel, e preenche alíquota,
+// desconto, isenções e valor. As isenções têm precedência sobre descontos.
+func Aplicar(regra models.RegraIPVA, calculo *models.CalculoIPVA) {
+	calculo.UF, calculo.NomeUF = regra.UF, regra.Nome
+	calculo.Aliquota = regra.Aliquota
+	calculo.Desconto = 0
+	calculo.Isento = false
+	calculo.Isencoes = []string{}
+	calculo.Valor = 0
+
+	if calculo.Combustivel == models.CombustivelDesconhecido {
+		calculo.Observacoes = append(calculo.Observacoes, "combustível desconhecido: aplicada a alíquota geral")
+	} else if aliquota, ok := regra.AliquotasCombustivel[calculo.Combustivel]; ok {
+		calculo.Aliquota = aliquota
+	}
+
+	if regra.IsencaoIdade > 0 && calculo.Idade >= regra.IsencaoIdade {
+		calculo.Isento = true
+		calculo.Isencoes = append(calculo.Isencoes, fmt.Sprintf("veículo com %d anos ou mais", regra.IsencaoIdade))
+	}
+	for _, c := range regra.IsentosCombustivel {
+		if c == calculo.Combustivel {
+			calculo.Isento = true
+			calculo.Isencoes = append(calculo.Isencoes, fmt.Sprintf("combustível %s", c))
+		}
+	}
+	if calculo.Isento {
+		calculo.ValorFmt = calculo.Valor.String()
+		return
+	}
+
+	calculo.Desconto = regra.DescontosCombustivel[calculo.Combustivel]
+	calculo.Valor = calculo.ValorBase.Mul(calculo.Aliquota / 100 * (1 - calculo.Desconto/100))
+	calculo.ValorFmt = calculo.Valor.String()
+}
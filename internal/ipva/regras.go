@@ -0,0 +1,196 @@
+// Package ipva calcula o IPVA de automóveis a partir do valor FIPE, com as
+// regras de cada UF (alíquotas, isenção por idade e tratamento por
+// combustível) lidas de um arquivo versionado por exercício.
+package ipva
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"fipe_project/internal/models"
+)
+
+// ArquivoRegrasPadrao é usado quando a variável IPVA_REGRAS não está definida.
+const ArquivoRegrasPadrao = "./config/ipva.json"
+
+var (
+	// ErrUFInvalida indica uma sigla fora de models.UFs.
+	ErrUFInvalida = errors.New("UF inválida")
+	// ErrSemRegras indica que o arquivo de regras não pôde ser carregado.
+	ErrSemRegras = errors.New("regras de IPVA indisponíveis")
+)
+
+// Regras é o conteúdo validado do arquivo, com as versões em ordem de
+// exercício.
+type Regras struct {
+	Fonte   string
+	versoes []models.VersaoIPVA
+}
+
+// NovasRegras valida o arquivo: cada versão precisa ter as 27 UFs, alíquotas
+// entre 0 e 100, combustíveis conhecidos e exemplos que batam com o cálculo.
+func NovasRegras(arquivo models.ArquivoIPVA) (*Regras, error) {
+	if len(arquivo.Versoes) == 0 {
+		return nil, fmt.Errorf("nenhuma versão de regras")
+	}
+	versoes := append([]models.VersaoIPVA(nil), arquivo.Versoes...)
+	sort.Slice(versoes, func(i, j int) bool { return versoes[i].Exercicio < versoes[j].Exercicio })
+	for _, v := range versoes {
+		porUF := make(map[string]bool, len(v.UFs))
+		for _, regra := range v.UFs {
+			if err := validarRegra(regra); err != nil {
+				return nil, fmt.Errorf("versão %s, %s: %v", v.Versao, regra.UF, err)
+			}
+			if porUF[regra.UF] {
+				return nil, fmt.Errorf("versão %s: UF %s repetida", v.Versao, regra.UF)
+			}
+			porUF[regra.UF] = true
+		}
+		var faltando []string
+		for _, uf := range models.UFs {
+			if !porUF[uf] {
+				faltando = append(faltando, uf)
+			}
+		}
+		if len(faltando) > 0 {
+			return nil, fmt.Errorf("versão %s: faltam as UFs %s", v.Versao, strings.Join(faltando, ", "))
+		}
+	}
+	return &Regras{Fonte: arquivo.Fonte, versoes: versoes}, nil
+}
+
+func validarRegra(regra models.RegraIPVA) error {
+	if !ufValida(regra.UF) {
+		return ErrUFInvalida
+	}
+	aliquotas := []float64{regra.Aliquota}
+	for c, a := range regra.AliquotasCombustivel {
+		if _, err := models.ParseCombustivel(string(c)); err != nil || c == models.CombustivelDesconhecido {
+			return fmt.Errorf("combustível desconhecido '%s'", c)
+		}
+		aliquotas = append(aliquotas, a)
+	}
+	for c, d := range regra.DescontosCombustivel {
+		if _, err := models.ParseCombustivel(string(c)); err != nil || c == models.CombustivelDesconhecido {
+			return fmt.Errorf("combustível desconhecido '%s'", c)
+		}
+		if d < 0 || d > 100 {
+			return fmt.Errorf("desconto fora de 0-100: %v", d)
+		}
+	}
+	for _, a := range aliquotas {
+		if a <= 0 || a > 100 {
+			return fmt.Errorf("alíquota fora de 0-100: %v", a)
+		}
+	}
+	if regra.IsencaoIdade < 0 {
+		return fmt.Errorf("idade de isenção negativa")
+	}
+	for i, ex := range regra.Exemplos {
+		calculo := models.CalculoIPVA{ValorBase: models.MoneyFromFloat(ex.Valor), Idade: ex.Idade, Combustivel: ex.Combustivel}
+		Aplicar(regra, &calculo)
+		if math.Abs(calculo.Valor.Float64()-ex.Esperado) > 0.01 {
+			return fmt.Errorf("exemplo %d: esperado %.2f, calculado %.2f", i+1, ex.Esperado, calculo.Valor.Float64())
+		}
+	}
+	return nil
+}
+
+// Regra retorna a regra da UF na versão vigente no exercício: a de maior
+// exercício que não o ultrapasse, ou a mais antiga se o exercício for
+// anterior a todas.
+func (r *Regras) Regra(uf string, exercicio int) (models.RegraIPVA, models.VersaoIPVA, error) {
+	uf = strings.ToUpper(strings.TrimSpace(uf))
+	if !ufValida(uf) {
+		return models.RegraIPVA{}, models.VersaoIPVA{}, fmt.Errorf("%w: '%s'", ErrUFInvalida, uf)
+	}
+	versao := r.versoes[0]
+	for _, v := range r.versoes {
+		if v.Exercicio <= exercicio {
+			versao = v
+		}
+	}
+	for _, regra := range versao.UFs {
+		if regra.UF == uf {
+			return regra, versao, nil
+		}
+	}
+	// NovasRegras garante todas as UFs em todas as versões.
+	return models.RegraIPVA{}, versao, fmt.Errorf("%w: '%s'", ErrUFInvalida, uf)
+}
+
+func ufValida(uf string) bool {
+	for _, u := range models.UFs {
+		if u == uf {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	mu             sync.Mutex
+	atual          *Regras
+	modArquivo     time.Time
+	caminhoArquivo = ArquivoRegrasPadrao
+)
+
+func init() {
+	if caminho := os.Getenv("IPVA_REGRAS"); caminho != "" {
+		caminhoArquivo = caminho
+	}
+}
+
+// Atual retorna as regras em uso, recarregando o arquivo quando ele muda. Se
+// a recarga falhar, as regras anteriores continuam em uso.
+func Atual() (*Regras, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	info, err := os.Stat(caminhoArquivo)
+	if err != nil {
+		if atual != nil {
+			return atual, nil
+		}
+		return nil, fmt.Errorf("%w: %v", ErrSemRegras, err)
+	}
+	if atual != nil && info.ModTime().Equal(modArquivo) {
+		return atual, nil
+	}
+
+	regras, err := lerArquivo(caminhoArquivo)
+	if err != nil {
+		if atual != nil {
+			log.Printf("Erro ao recarregar regras de IPVA, mantendo as anteriores: %v", err)
+			modArquivo = info.ModTime()
+			return atual, nil
+		}
+		return nil, fmt.Errorf("%w: %v", ErrSemRegras, err)
+	}
+	atual, modArquivo = regras, info.ModTime()
+	return atual, nil
+}
+
+func lerArquivo(caminho string) (*Regras, error) {
+	dados, err := os.ReadFile(caminho)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler regras de IPVA %s: %v", caminho, err)
+	}
+	var arquivo models.ArquivoIPVA
+	if err := json.Unmarshal(dados, &arquivo); err != nil {
+		return nil, fmt.Errorf("erro ao interpretar regras de IPVA %s: %v", caminho, err)
+	}
+	regras, err := NovasRegras(arquivo)
+	if err != nil {
+		return nil, fmt.Errorf("regras de IPVA %s inválidas: %v", caminho, err)
+	}
+	return regras, nil
+}
@@ -0,0 +1,180 @@
+package ipva
+
+import (
+	"math"
+	"testing"
+
+	"fipe_project/internal/models"
+)
+
+const arquivoTeste = "../../config/ipva.json"
+
+func carregarRegras(t *testing.T) *Regras {
+	t.Helper()
+	regras, err := lerArquivo(arquivoTeste)
+	if err != nil {
+		t.Fatalf("lerArquivo: %v", err)
+	}
+	return regras
+}
+
+func calcular(t *testing.T, regras *Regras, uf string, valor float64, idade int, combustivel models.Combustivel) models.CalculoIPVA {
+	t.Helper()
+	regra, _, err := regras.Regra(uf, 2025)
+	if err != nil {
+		t.Fatalf("Regra(%s): %v", uf, err)
+	}
+	calculo := models.CalculoIPVA{ValorBase: models.MoneyFromFloat(valor), Idade: idade, Combustivel: combustivel}
+	Aplicar(regra, &calculo)
+	return calculo
+}
+
+func conferirValor(t *testing.T, calculo models.CalculoIPVA, esperado float64) {
+	t.Helper()
+	if math.Abs(calculo.Valor.Float64()-esperado) > 0.01 {
+		t.Errorf("%s: valor %.2f, esperado %.2f", calculo.UF, calculo.Valor.Float64(), esperado)
+	}
+}
+
+// TestAliquotaEIsencaoPorUF confere, para as 27 UFs, a alíquota geral de um
+// carro flex e a isenção por idade no limite.
+func TestAliquotaEIsencaoPorUF(t *testing.T) {
+	casos := []struct {
+		uf           string
+		aliquota     float64
+		isencaoIdade int
+	}{
+		{"AC", 2.0, 15}, {"AL", 3.0, 15}, {"AP", 3.0, 10}, {"AM", 3.0, 10},
+		{"BA", 2.5, 15}, {"CE", 3.0, 15}, {"DF", 3.5, 15}, {"ES", 2.0, 20},
+		{"GO", 3.75, 15}, {"MA", 2.5, 15}, {"MT", 3.0, 18}, {"MS", 3.0, 15},
+		{"MG", 4.0, 20}, {"PA", 2.5, 15}, {"PB", 2.5, 15}, {"PR", 3.5, 20},
+		{"PE", 3.0, 15}, {"PI", 2.5, 15}, {"RJ", 4.0, 15}, {"RN", 3.0, 10},
+		{"RS", 3.0, 20}, {"RO", 3.0, 15}, {"RR", 3.0, 10}, {"SC", 2.0, 30},
+		{"SP", 4.0, 20}, {"SE", 2.5, 15}, {"TO", 2.5, 15},
+	}
+	if len(casos) != len(models.UFs) {
+		t.Fatalf("%d casos para %d UFs", len(casos), len(models.UFs))
+	}
+	regras := carregarRegras(t)
+	for _, c := range casos {
+		t.Run(c.uf, func(t *testing.T) {
+			novo := calcular(t, regras, c.uf, 100000, 1, models.CombustivelFlex)
+			if novo.Aliquota != c.aliquota || novo.Isento {
+				t.Errorf("alíquota %v (isento %v), esperada %v", novo.Aliquota, novo.Isento, c.aliquota)
+			}
+			conferirValor(t, novo, 1000*c.aliquota)
+
+			antesDaIsencao := calcular(t, regras, c.uf, 100000, c.isencaoIdade-1, models.CombustivelFlex)
+			if antesDaIsencao.Isento {
+				t.Errorf("isento com %d anos", c.isencaoIdade-1)
+			}
+			isento := calcular(t, regras, c.uf, 100000, c.isencaoIdade, models.CombustivelFlex)
+			if !isento.Isento || isento.Valor != 0 {
+				t.Errorf("com %d anos: isento %v, valor %s", c.isencaoIdade, isento.Isento, isento.Valor)
+			}
+		})
+	}
+}
+
+// TestCombustivel confere as alíquotas, descontos e isenções por combustível.
+func TestCombustivel(t *testing.T) {
+	casos := []struct {
+		uf          string
+		combustivel models.Combustivel
+		esperado    float64
+		isento      bool
+	}{
+		{"DF", models.CombustivelEletrico, 0, true},
+		{"MA", models.CombustivelEletrico, 0, true},
+		{"PE", models.CombustivelEletrico, 0, true},
+		{"PI", models.CombustivelEletrico, 0, true},
+		{"RN", models.CombustivelEletrico, 0, true},
+		{"MS", models.CombustivelHibrido, 2100, false},
+		{"RJ", models.CombustivelAlcool, 2000, false},
+		{"RJ", models.CombustivelGasNatural, 1500, false},
+		{"RJ", models.CombustivelHibrido, 1500, false},
+		{"RJ", models.CombustivelEletrico, 500, false},
+		{"SP", models.CombustivelAlcool, 3000, false},
+		{"SP", models.CombustivelGasNatural, 3000, false},
+		{"SP", models.CombustivelEletrico, 3000, false},
+		{"SP", models.CombustivelHibrido, 4000, false},
+		// Elétrico sem regra própria paga a alíquota geral.
+		{"MG", models.CombustivelEletrico, 4000, false},
+		// Combustível desconhecido também.
+		{"RJ", models.CombustivelDesconhecido, 4000, false},
+	}
+	regras := carregarRegras(t)
+	for _, c := range casos {
+		t.Run(c.uf+"/"+string(c.combustivel), func(t *testing.T) {
+			calculo := calcular(t, regras, c.uf, 100000, 1, c.combustivel)
+			if calculo.Isento != c.isento {
+				t.Errorf("isento %v, esperado %v", calculo.Isento, c.isento)
+			}
+			conferirValor(t, calculo, c.esperado)
+		})
+	}
+}
+
+func TestNovasRegrasInvalidas(t *testing.T) {
+	regra := models.RegraIPVA{UF: "SP", Aliquota: 4, IsencaoIdade: 20}
+	completa := func(altera func(*models.RegraIPVA)) models.ArquivoIPVA {
+		versao := models.VersaoIPVA{Versao: "teste", Exercicio: 2025}
+		for _, uf := range models.UFs {
+			r := regra
+			r.UF = uf
+			if uf == "SP" && altera != nil {
+				altera(&r)
+			}
+			versao.UFs = append(versao.UFs, r)
+		}
+		return models.ArquivoIPVA{Versoes: []models.VersaoIPVA{versao}}
+	}
+
+	if _, err := NovasRegras(completa(nil)); err != nil {
+		t.Fatalf("arquivo válido recusado: %v", err)
+	}
+	casos := map[string]func(*models.RegraIPVA){
+		"alíquota zero": func(r *models.RegraIPVA) { r.Aliquota = 0 },
+		"desconto acima de 100": func(r *models.RegraIPVA) {
+			r.DescontosCombustivel = map[models.Combustivel]float64{models.CombustivelFlex: 120}
+		},
+		"combustível inválido": func(r *models.RegraIPVA) { r.AliquotasCombustivel = map[models.Combustivel]float64{"querosene": 1} },
+		"idade negativa":       func(r *models.RegraIPVA) { r.IsencaoIdade = -1 },
+		"exemplo divergente": func(r *models.RegraIPVA) {
+			r.Exemplos = []models.ExemploIPVA{{Valor: 100000, Idade: 1, Combustivel: models.CombustivelFlex, Esperado: 3000}}
+		},
+	}
+	for nome, altera := range casos {
+		if _, err := NovasRegras(completa(altera)); err == nil {
+			t.Errorf("%s: aceito", nome)
+		}
+	}
+
+	faltando := completa(nil)
+	faltando.Versoes[0].UFs = faltando.Versoes[0].UFs[1:]
+	if _, err := NovasRegras(faltando); err == nil {
+		t.Error("versão sem todas as UFs aceita")
+	}
+}
+
+func TestRegraPorExercicio(t *testing.T) {
+	versao := func(nome string, exercicio int, aliquota float64) models.VersaoIPVA {
+		v := models.VersaoIPVA{Versao: nome, Exercicio: exercicio}
+		for _, uf := range models.UFs {
+			v.UFs = append(v.UFs, models.RegraIPVA{UF: uf, Aliquota: aliquota})
+		}
+		return v
+	}
+	regras, err := NovasRegras(models.ArquivoIPVA{Versoes: []models.VersaoIPVA{versao("2026", 2026, 3), versao("2024", 2024, 2)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for exercicio, esperada := range map[int]string{2023: "2024", 2024: "2024", 2025: "2024", 2026: "2026", 2030: "2026"} {
+		if _, v, _ := regras.Regra("sp", exercicio); v.Versao != esperada {
+			t.Errorf("exercício %d: versão %s, esperada %s", exercicio, v.Versao, esperada)
+		}
+	}
+	if _, _, err := regras.Regra("XX", 2025); err == nil {
+		t.Error("UF inválida aceita")
+	}
+}
@@ -0,0 +1,42 @@
+package migracoes
+
+import (
+	"context"
+
+	"fipe_project/internal/models"
+	"fipe_project/internal/services"
+)
+
+// aplicarCatalogoMarcas monta o catálogo de marcas das tabelas já ingeridas,
+// que até então só era atualizado na publicação de cada tabela. No relatório,
+// Documentos conta as tabelas e Entradas, as marcas catalogadas.
+func aplicarCatalogoMarcas(ctx context.Context, simular bool) (models.RelatorioMigracao, error) {
+	var relatorio models.RelatorioMigracao
+	tabelas, err := services.TabelasComVeiculos(ctx)
+	if err != nil {
+		return relatorio, err
+	}
+	for _, tabelaId := range tabelas {
+		relatorio.Documentos++
+		var marcas int
+		if simular {
+			catalogo, err := services.CalcularCatalogoTabela(ctx, tabelaId)
+			if err != nil {
+				return relatorio, err
+			}
+			marcas = len(catalogo)
+		} else if marcas, err = services.AtualizarCatalogoMarcas(ctx, tabelaId); err != nil {
+			return relatorio, err
+		}
+		relatorio.Alterados++
+		relatorio.Entradas += int64(marcas)
+	}
+	return relatorio, nil
+}
+
+// reverterCatalogoMarcas apaga os dois catálogos. Documentos conta o que
+// havia neles.
+func reverterCatalogoMarcas(ctx context.Context, simular bool) (models.RelatorioMigracao, error) {
+	n, err := services.ApagarCatalogoMarcas(ctx, simular)
+	return models.RelatorioMigracao{Documentos: n, Alterados: n}, err
+}
@@ -0,0 +1,109 @@
+package migracoes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+)
+
+// indice é um índice declarado de uma coleção da aplicação. As coleções da
+// fila e do agendador criam os próprios índices (fila.GarantirIndices e
+// agendador.GarantirIndices).
+type indice struct {
+	colecao string
+	nome    string
+	chaves  bson.D
+}
+
+var indices = []indice{
+	// Quase toda consulta filtra a tabela e a marca; serve também à busca da
+	// tabela mais recente.
+	{"Veiculos", "tabela_marca", bson.D{{Key: "monthYearId", Value: 1}, {Key: "brandCode", Value: 1}}},
+	// Preços e histórico de um modelo.
+	{"Veiculos", "modelo_tabela", bson.D{{Key: "models.modelCode", Value: 1}, {Key: "monthYearId", Value: 1}}},
+	// Listagem do catálogo de marcas por tabela e histórico de uma marca.
+	{"CatalogoMarcas", "tabela_marca", bson.D{{Key: "tabelaId", Value: 1}, {Key: "brandName", Value: 1}}},
+	{"CatalogoMarcas", "marca_tabela", bson.D{{Key: "brandCode", Value: 1}, {Key: "tabelaId", Value: 1}}},
+	{"TabelaReferencia", "codigo", bson.D{{Key: "codigo", Value: 1}}},
+	// Resolução de tabela=2024-03.
+	{"TabelaReferencia", "periodo", bson.D{{Key: "periodo", Value: 1}}},
+	{"Watchlist", "criado_em", bson.D{{Key: "criadoEm", Value: 1}}},
+	{"WebhookEntregas", "watch_tabela", bson.D{{Key: "watchId", Value: 1}, {Key: "tabelaId", Value: 1}}},
+	{"WebhookEntregas", "criado_em", bson.D{{Key: "criadoEm", Value: -1}}},
+}
+
+// GarantirIndices confere os índices declarados e cria os que faltam. Um
+// índice existente com as mesmas chaves conta como presente, qualquer que
+// seja o nome. Com simular, só informa os que faltam.
+func GarantirIndices(ctx context.Context, simular bool) ([]models.EstadoIndice, error) {
+	existentes := make(map[string]map[string]bool)
+	estados := make([]models.EstadoIndice, 0, len(indices))
+	for _, ind := range indices {
+		if _, ok := existentes[ind.colecao]; !ok {
+			chaves, err := chavesExistentes(ctx, ind.colecao)
+			if err != nil {
+				return estados, err
+			}
+			existentes[ind.colecao] = chaves
+		}
+		estado := models.EstadoIndice{Colecao: ind.colecao, Nome: ind.nome, Chaves: descreverChaves(ind.chaves)}
+		estado.Existe = existentes[ind.colecao][estado.Chaves]
+		if !estado.Existe && !simular {
+			modelo := mongo.IndexModel{Keys: ind.chaves, Options: options.Index().SetName(ind.nome)}
+			if _, err := database.DB.Collection(ind.colecao).Indexes().CreateOne(ctx, modelo); err != nil {
+				return estados, fmt.Errorf("erro ao criar índice %s em %s: %v", ind.nome, ind.colecao, err)
+			}
+			estado.Existe, estado.Criado = true, true
+		}
+		estados = append(estados, estado)
+	}
+	return estados, nil
+}
+
+// chavesExistentes retorna as chaves dos índices da coleção, no formato de
+// descreverChaves.
+func chavesExistentes(ctx context.Context, colecao string) (map[string]bool, error) {
+	specs, err := database.DB.Collection(colecao).Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar índices de %s: %v", colecao, err)
+	}
+	chaves := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		var d bson.D
+		if err := bson.Unmarshal(spec.KeysDocument, &d); err != nil {
+			return nil, fmt.Errorf("erro ao ler índice %s de %s: %v", spec.Name, colecao, err)
+		}
+		chaves[descreverChaves(d)] = true
+	}
+	return chaves, nil
+}
+
+// descreverChaves escreve as chaves como "campo:1,outro:-1". Direções
+// numéricas são comparadas pelo valor, qualquer que seja o tipo BSON.
+func descreverChaves(chaves bson.D) string {
+	partes := make([]string, len(chaves))
+	for i, c := range chaves {
+		var direcao string
+		switch v := c.Value.(type) {
+		case int32:
+			direcao = fmt.Sprint(v)
+		case int64:
+			direcao = fmt.Sprint(v)
+		case int:
+			direcao = fmt.Sprint(v)
+		case float64:
+			direcao = fmt.Sprint(int64(v))
+		default:
+			direcao = fmt.Sprint(v)
+		}
+		partes[i] = c.Key + ":" + direcao
+	}
+	return strings.Join(partes, ",")
+}
@@ -0,0 +1,344 @@
+// Package migracoes mantém o esquema do banco: cria e confere os índices das
+// coleções e aplica migrações de dados versionadas, registradas na coleção
+// Migracoes. As migrações rodam na subida do servidor e do worker (MIGRACOES)
+// ou pelo comando "fipectl migrar", que também simula e reverte.
+//
+// Cada migração é idempotente: só altera o que ainda não foi migrado, e pode
+// ser repetida depois de uma falha. O registro em Migracoes funciona como
+// trava, para que dois processos não apliquem a mesma migração ao mesmo tempo.
+package migracoes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+)
+
+const colecaoMigracoes = "Migracoes"
+
+// execucaoAbandonada é o tempo depois do qual uma migração que continua
+// "executando" é considerada abandonada por um processo que parou, e pode
+// ser retomada.
+const execucaoAbandonada = 30 * time.Minute
+
+var (
+	// ErrVersaoInvalida indica uma versão fora do catálogo.
+	ErrVersaoInvalida = errors.New("versão de migração inválida")
+)
+
+// Migracao é uma migração de dados. Aplicar e Reverter recebem simular=true
+// para só contar o que alterariam. Reverter é nil nas migrações que não podem
+// ser desfeitas.
+type Migracao struct {
+	Versao    int
+	Nome      string
+	Descricao string
+	Aplicar   func(ctx context.Context, simular bool) (models.RelatorioMigracao, error)
+	Reverter  func(ctx context.Context, simular bool) (models.RelatorioMigracao, error)
+}
+
+// Catalogo retorna as migrações em ordem de versão.
+func Catalogo() []Migracao {
+	catalogo := []Migracao{
+		{
+			Versao:    1,
+			Nome:      "precos_centavos",
+			Descricao: "grava o preço de cada ano-modelo em centavos (priceCents)",
+			Aplicar:   aplicarPrecosCentavos,
+			Reverter:  reverterPrecosCentavos,
+		},
+		{
+			Versao:    2,
+			Nome:      "combustivel_codigo_ano",
+			Descricao: "preenche e normaliza o combustível (fuel) a partir do código do ano",
+			Aplicar:   aplicarCombustivel,
+		},
+		{
+			Versao:    3,
+			Nome:      "codigos_fipe",
+			Descricao: "preenche o código FIPE dos anos-modelo com o código do mesmo modelo em outras tabelas",
+			Aplicar:   aplicarCodigosFipe,
+		},
+		{
+			Versao:    4,
+			Nome:      "catalogo_marcas",
+			Descricao: "monta o catálogo de marcas (CatalogoMarcas e CatalogoMarcasGlobal) das tabelas já ingeridas",
+			Aplicar:   aplicarCatalogoMarcas,
+			Reverter:  reverterCatalogoMarcas,
+		},
+		{
+			Versao:    5,
+			Nome:      "tabelas_publicadas",
+			Descricao: "marca como publicadas (publicadaEm) as tabelas já processadas pelo monitor",
+			Aplicar:   aplicarTabelasPublicadas,
+		},
+		{
+			Versao:    6,
+			Nome:      "periodo_tabelas",
+			Descricao: "grava o mês das tabelas de referência como data (ano, numeroMes e periodo AAAA-MM)",
+			Aplicar:   aplicarPeriodoTabelas,
+			Reverter:  reverterPeriodoTabelas,
+		},
+		{
+			Versao:    7,
+			Nome:      "contagem_tabelas",
+			Descricao: "grava nas tabelas de referência as contagens de marcas, modelos e anos-modelo (contagem)",
+			Aplicar:   aplicarContagemTabelas,
+			Reverter:  reverterContagemTabelas,
+		},
+	}
+	sort.Slice(catalogo, func(i, j int) bool { return catalogo[i].Versao < catalogo[j].Versao })
+	return catalogo
+}
+
+// AoIniciar é chamada na subida dos executáveis: garante os índices e, com
+// dados, aplica as migrações pendentes. As revertidas continuam revertidas.
+func AoIniciar(ctx context.Context, dados bool) error {
+	estados, err := GarantirIndices(ctx, false)
+	if err != nil {
+		return err
+	}
+	for _, e := range estados {
+		if e.Criado {
+			log.Printf("Migrações: índice %s criado em %s (%s)", e.Nome, e.Colecao, e.Chaves)
+		}
+	}
+	if !dados {
+		return nil
+	}
+	resultados, err := Aplicar(ctx, false, false)
+	for _, r := range resultados {
+		log.Printf("Migrações: %d %s %s (%d documentos alterados, %d entradas, %d ignoradas)",
+			r.Versao, r.Nome, r.Status, r.Relatorio.Alterados, r.Relatorio.Entradas, r.Relatorio.Ignoradas)
+	}
+	return err
+}
+
+// Situacao lista o catálogo com o registro de cada migração.
+func Situacao(ctx context.Context) ([]models.SituacaoMigracao, error) {
+	registros, err := carregarRegistros(ctx)
+	if err != nil {
+		return nil, err
+	}
+	catalogo := Catalogo()
+	situacoes := make([]models.SituacaoMigracao, len(catalogo))
+	for i, m := range catalogo {
+		situacoes[i] = models.SituacaoMigracao{Versao: m.Versao, Nome: m.Nome, Descricao: m.Descricao, Reversivel: m.Reverter != nil}
+		if r, ok := registros[m.Versao]; ok {
+			situacoes[i].Registro = &r
+		}
+	}
+	return situacoes, nil
+}
+
+// Aplicar executa, em ordem, as migrações ainda não aplicadas. Para na
+// primeira que falhar, já que as seguintes podem depender dela, e na primeira
+// em execução em outro processo. As revertidas só são aplicadas de novo com
+// reaplicarRevertidas (fipectl migrar); sem ele, aparecem no resultado como
+// revertidas. Com simular, nada é gravado, nem o registro.
+func Aplicar(ctx context.Context, simular, reaplicarRevertidas bool) ([]models.ResultadoMigracao, error) {
+	registros, err := carregarRegistros(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var resultados []models.ResultadoMigracao
+	for _, m := range Catalogo() {
+		r, ok := registros[m.Versao]
+		if ok && r.Status == models.StatusMigracaoAplicada {
+			continue
+		}
+		resultado := models.ResultadoMigracao{Versao: m.Versao, Nome: m.Nome}
+		if ok && r.Status == models.StatusMigracaoRevertida && !reaplicarRevertidas {
+			resultado.Status = models.StatusMigracaoRevertida
+			resultados = append(resultados, resultado)
+			continue
+		}
+		if simular {
+			resultado.Status = models.ResultadoMigracaoSimulada
+			resultado.Relatorio, err = m.Aplicar(ctx, true)
+			if err != nil {
+				resultado.Status, resultado.Erro = models.StatusMigracaoFalhou, err.Error()
+				return append(resultados, resultado), fmt.Errorf("erro ao simular migração %d (%s): %v", m.Versao, m.Nome, err)
+			}
+			resultados = append(resultados, resultado)
+			continue
+		}
+
+		assumida, err := assumir(ctx, m)
+		if err != nil {
+			return resultados, err
+		}
+		if !assumida {
+			// Em execução em outro processo: as seguintes ficam para ele.
+			resultado.Status = models.ResultadoMigracaoPulada
+			return append(resultados, resultado), nil
+		}
+		resultado.Relatorio, err = m.Aplicar(ctx, false)
+		resultado.Status = models.StatusMigracaoAplicada
+		if err != nil {
+			resultado.Status, resultado.Erro = models.StatusMigracaoFalhou, err.Error()
+		}
+		if errRegistro := finalizar(m.Versao, resultado.Status, resultado.Relatorio, err); errRegistro != nil {
+			log.Printf("Migrações: %v", errRegistro)
+		}
+		resultados = append(resultados, resultado)
+		if err != nil {
+			return resultados, fmt.Errorf("erro na migração %d (%s): %v", m.Versao, m.Nome, err)
+		}
+	}
+	return resultados, nil
+}
+
+// Reverter desfaz, da mais nova para a mais antiga, as migrações aplicadas
+// com versão maior que ate. As irreversíveis continuam aplicadas e aparecem
+// no resultado como tal; as migrações do catálogo só preenchem campos
+// derivados e não dependem umas das outras. Para na primeira que falhar; o
+// resultado informa o que foi revertido até ali. Com simular, só conta o que
+// seria desfeito.
+func Reverter(ctx context.Context, ate int, simular bool) ([]models.ResultadoMigracao, error) {
+	catalogo := Catalogo()
+	if ate < 0 || ate > catalogo[len(catalogo)-1].Versao {
+		return nil, fmt.Errorf("%w: %d", ErrVersaoInvalida, ate)
+	}
+	registros, err := carregarRegistros(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var resultados []models.ResultadoMigracao
+	for i := len(catalogo) - 1; i >= 0 && catalogo[i].Versao > ate; i-- {
+		m := catalogo[i]
+		if r, ok := registros[m.Versao]; !ok || r.Status != models.StatusMigracaoAplicada {
+			continue
+		}
+		resultado := models.ResultadoMigracao{Versao: m.Versao, Nome: m.Nome}
+		if m.Reverter == nil {
+			resultado.Status = models.ResultadoMigracaoIrreversivel
+			resultados = append(resultados, resultado)
+			continue
+		}
+		if simular {
+			resultado.Status = models.ResultadoMigracaoSimulada
+			resultado.Relatorio, err = m.Reverter(ctx, true)
+			if err != nil {
+				resultado.Status, resultado.Erro = models.StatusMigracaoFalhou, err.Error()
+				return append(resultados, resultado), fmt.Errorf("erro ao simular reversão da migração %d (%s): %v", m.Versao, m.Nome, err)
+			}
+			resultados = append(resultados, resultado)
+			continue
+		}
+
+		assumida, err := assumirReversao(ctx, m.Versao)
+		if err != nil {
+			return resultados, err
+		}
+		if !assumida {
+			resultado.Status = models.ResultadoMigracaoPulada
+			return append(resultados, resultado), nil
+		}
+		resultado.Relatorio, err = m.Reverter(ctx, false)
+		resultado.Status = models.StatusMigracaoRevertida
+		if err != nil {
+			// Revertida em parte: a migração volta a ficar pendente e a
+			// próxima aplicação completa o que falta.
+			resultado.Status, resultado.Erro = models.StatusMigracaoFalhou, err.Error()
+		}
+		if errRegistro := finalizar(m.Versao, resultado.Status, resultado.Relatorio, err); errRegistro != nil {
+			log.Printf("Migrações: %v", errRegistro)
+		}
+		resultados = append(resultados, resultado)
+		if err != nil {
+			return resultados, fmt.Errorf("erro ao reverter migração %d (%s): %v", m.Versao, m.Nome, err)
+		}
+	}
+	return resultados, nil
+}
+
+func carregarRegistros(ctx context.Context) (map[int]models.RegistroMigracao, error) {
+	cursor, err := database.DB.Collection(colecaoMigracoes).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar migrações aplicadas: %v", err)
+	}
+	var lista []models.RegistroMigracao
+	if err := cursor.All(ctx, &lista); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar migrações aplicadas: %v", err)
+	}
+	registros := make(map[int]models.RegistroMigracao, len(lista))
+	for _, r := range lista {
+		registros[r.Versao] = r
+	}
+	return registros, nil
+}
+
+// assumir marca a migração como em execução por este processo. Devolve false
+// se ela já foi aplicada ou está em execução em outro processo.
+func assumir(ctx context.Context, m Migracao) (bool, error) {
+	coll := database.DB.Collection(colecaoMigracoes)
+	agora := time.Now()
+	registro := models.RegistroMigracao{
+		Versao:     m.Versao,
+		Nome:       m.Nome,
+		Status:     models.StatusMigracaoExecutando,
+		Dono:       dono(),
+		IniciadaEm: agora,
+	}
+	_, err := coll.InsertOne(ctx, registro)
+	if err == nil {
+		return true, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return false, fmt.Errorf("erro ao registrar migração %d: %v", m.Versao, err)
+	}
+	filtro := bson.M{"_id": m.Versao, "$or": bson.A{
+		bson.M{"status": bson.M{"$in": bson.A{models.StatusMigracaoFalhou, models.StatusMigracaoRevertida}}},
+		bson.M{"status": models.StatusMigracaoExecutando, "iniciadaEm": bson.M{"$lt": agora.Add(-execucaoAbandonada)}},
+	}}
+	return retomar(ctx, m.Versao, filtro)
+}
+
+// assumirReversao marca uma migração aplicada como em execução.
+func assumirReversao(ctx context.Context, versao int) (bool, error) {
+	return retomar(ctx, versao, bson.M{"_id": versao, "status": models.StatusMigracaoAplicada})
+}
+
+func retomar(ctx context.Context, versao int, filtro bson.M) (bool, error) {
+	resultado, err := database.DB.Collection(colecaoMigracoes).UpdateOne(ctx, filtro, bson.M{
+		"$set":   bson.M{"status": models.StatusMigracaoExecutando, "dono": dono(), "iniciadaEm": time.Now()},
+		"$unset": bson.M{"concluidaEm": "", "erro": ""},
+	})
+	if err != nil {
+		return false, fmt.Errorf("erro ao registrar migração %d: %v", versao, err)
+	}
+	return resultado.ModifiedCount == 1, nil
+}
+
+// finalizar grava o resultado com um contexto próprio, para que o registro
+// não fique "executando" quando o contexto da migração expirou.
+func finalizar(versao int, status string, relatorio models.RelatorioMigracao, causa error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	set := bson.M{"status": status, "concluidaEm": time.Now(), "relatorio": relatorio}
+	if causa != nil {
+		set["erro"] = causa.Error()
+	}
+	filtro := bson.M{"_id": versao, "dono": dono(), "status": models.StatusMigracaoExecutando}
+	if _, err := database.DB.Collection(colecaoMigracoes).UpdateOne(ctx, filtro, bson.M{"$set": set}); err != nil {
+		return fmt.Errorf("erro ao gravar migração %d: %v", versao, err)
+	}
+	return nil
+}
+
+func dono() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}
@@ -0,0 +1,166 @@
+package migracoes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+	"fipe_project/internal/services"
+)
+
+// aplicarTabelasPublicadas marca como publicadas as tabelas que o monitor já
+// processou antes de publicadaEm existir, ou seja, as de código até a última
+// tabela registrada no estado do monitor (AlertasEstado). Como a data real
+// da publicação não foi guardada, vale a da migração.
+func aplicarTabelasPublicadas(ctx context.Context, simular bool) (models.RelatorioMigracao, error) {
+	var relatorio models.RelatorioMigracao
+	var estado struct {
+		UltimaTabela int `bson:"ultimaTabela"`
+	}
+	err := database.DB.Collection("AlertasEstado").FindOne(ctx, bson.M{"_id": "monitor"}).Decode(&estado)
+	if err == mongo.ErrNoDocuments {
+		return relatorio, nil
+	}
+	if err != nil {
+		return relatorio, fmt.Errorf("erro ao ler estado do monitor: %v", err)
+	}
+
+	coll := database.DB.Collection("TabelaReferencia")
+	filtro := bson.M{"codigo": bson.M{"$lte": estado.UltimaTabela}, "publicadaEm": bson.M{"$exists": false}}
+	if simular {
+		n, err := coll.CountDocuments(ctx, filtro)
+		if err != nil {
+			return relatorio, fmt.Errorf("erro ao contar tabelas: %v", err)
+		}
+		relatorio.Documentos, relatorio.Alterados = n, n
+		return relatorio, nil
+	}
+	res, err := coll.UpdateMany(ctx, filtro, bson.M{"$set": bson.M{"publicadaEm": time.Now()}})
+	if err != nil {
+		return relatorio, fmt.Errorf("erro ao marcar tabelas publicadas: %v", err)
+	}
+	relatorio.Documentos, relatorio.Alterados = res.MatchedCount, res.ModifiedCount
+	return relatorio, nil
+}
+
+// aplicarPeriodoTabelas grava o mês interpretado (ano, numeroMes e periodo)
+// nas tabelas que ainda não o têm. Meses ilegíveis contam como ignorados.
+func aplicarPeriodoTabelas(ctx context.Context, simular bool) (models.RelatorioMigracao, error) {
+	var relatorio models.RelatorioMigracao
+	coll := database.DB.Collection("TabelaReferencia")
+	cursor, err := coll.Find(ctx, bson.M{"periodo": bson.M{"$exists": false}})
+	if err != nil {
+		return relatorio, fmt.Errorf("erro ao buscar tabelas: %v", err)
+	}
+	var tabelas []models.TabelaReferencia
+	if err := cursor.All(ctx, &tabelas); err != nil {
+		return relatorio, fmt.Errorf("erro ao decodificar tabelas: %v", err)
+	}
+
+	var operacoes []mongo.WriteModel
+	for _, t := range tabelas {
+		relatorio.Documentos++
+		data, ok := models.MesReferencia(t.Mes)
+		if !ok {
+			relatorio.Ignoradas++
+			continue
+		}
+		relatorio.Alterados++
+		operacoes = append(operacoes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"codigo": t.Codigo, "mes": t.Mes, "periodo": bson.M{"$exists": false}}).
+			SetUpdate(bson.M{"$set": bson.M{
+				"ano":       data.Year(),
+				"numeroMes": int(data.Month()),
+				"periodo":   data.Format(models.FormatoPeriodo),
+			}}))
+	}
+	if simular || len(operacoes) == 0 {
+		return relatorio, nil
+	}
+	res, err := coll.BulkWrite(ctx, operacoes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return relatorio, fmt.Errorf("erro ao gravar períodos: %v", err)
+	}
+	relatorio.Alterados = res.ModifiedCount
+	return relatorio, nil
+}
+
+// reverterPeriodoTabelas remove os campos gravados por aplicarPeriodoTabelas.
+// Tabelas publicadas depois da migração também os perdem; voltam a tê-los na
+// próxima publicação ou aplicação.
+func reverterPeriodoTabelas(ctx context.Context, simular bool) (models.RelatorioMigracao, error) {
+	var relatorio models.RelatorioMigracao
+	coll := database.DB.Collection("TabelaReferencia")
+	filtro := bson.M{"periodo": bson.M{"$exists": true}}
+	n, err := coll.CountDocuments(ctx, filtro)
+	if err != nil {
+		return relatorio, fmt.Errorf("erro ao contar tabelas: %v", err)
+	}
+	relatorio.Documentos, relatorio.Alterados = n, n
+	if simular {
+		return relatorio, nil
+	}
+	res, err := coll.UpdateMany(ctx, filtro, bson.M{"$unset": bson.M{"ano": "", "numeroMes": "", "periodo": ""}})
+	if err != nil {
+		return relatorio, fmt.Errorf("erro ao remover períodos: %v", err)
+	}
+	relatorio.Alterados = res.ModifiedCount
+	return relatorio, nil
+}
+
+// aplicarContagemTabelas grava a contagem das tabelas que ainda não a têm,
+// uma tabela por vez. Uma tabela em ingestão durante a migração fica com a
+// contagem daquele momento até ser publicada.
+func aplicarContagemTabelas(ctx context.Context, simular bool) (models.RelatorioMigracao, error) {
+	var relatorio models.RelatorioMigracao
+	cursor, err := database.DB.Collection("TabelaReferencia").Find(ctx,
+		bson.M{"contagem": bson.M{"$exists": false}},
+		options.Find().SetProjection(bson.M{"codigo": 1}),
+	)
+	if err != nil {
+		return relatorio, fmt.Errorf("erro ao buscar tabelas: %v", err)
+	}
+	var tabelas []models.TabelaReferencia
+	if err := cursor.All(ctx, &tabelas); err != nil {
+		return relatorio, fmt.Errorf("erro ao decodificar tabelas: %v", err)
+	}
+	for _, t := range tabelas {
+		relatorio.Documentos++
+		relatorio.Alterados++
+		if simular {
+			continue
+		}
+		if err := services.AtualizarContagemTabela(ctx, t.Codigo); err != nil {
+			return relatorio, err
+		}
+	}
+	return relatorio, nil
+}
+
+// reverterContagemTabelas remove a contagem gravada nas tabelas. Sem ela,
+// /api/tabelas volta a contar em Veiculos.
+func reverterContagemTabelas(ctx context.Context, simular bool) (models.RelatorioMigracao, error) {
+	var relatorio models.RelatorioMigracao
+	coll := database.DB.Collection("TabelaReferencia")
+	filtro := bson.M{"contagem": bson.M{"$exists": true}}
+	n, err := coll.CountDocuments(ctx, filtro)
+	if err != nil {
+		return relatorio, fmt.Errorf("erro ao contar tabelas: %v", err)
+	}
+	relatorio.Documentos, relatorio.Alterados = n, n
+	if simular {
+		return relatorio, nil
+	}
+	res, err := coll.UpdateMany(ctx, filtro, bson.M{"$unset": bson.M{"contagem": ""}})
+	if err != nil {
+		return relatorio, fmt.Errorf("erro ao remover contagens: %v", err)
+	}
+	relatorio.Alterados = res.ModifiedCount
+	return relatorio, nil
+}
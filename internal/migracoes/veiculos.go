@@ -0,0 +1,252 @@
+package migracoes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+)
+
+// tamanhoLote é o número de documentos gravados por BulkWrite.
+const tamanhoLote = 200
+
+type documentoVeiculos struct {
+	Id           primitive.ObjectID `bson:"_id"`
+	models.Marca `bson:",inline"`
+}
+
+// alteracaoAno são os campos a gravar (set) e a remover (unset) numa entrada
+// de ano. Ignorada marca uma entrada que a migração não conseguiu tratar.
+type alteracaoAno struct {
+	set      bson.M
+	unset    []string
+	ignorada bool
+}
+
+type funcaoAno func(marca *models.Marca, modelo models.Modelo, ano models.AnoModelo) alteracaoAno
+
+// percorrerAnos aplica f a cada entrada de ano de Veiculos e grava as
+// alterações, um update por documento. O filtro de cada update confere o
+// modelo e o ano nas posições alteradas, para não gravar num documento que
+// mudou desde a leitura (por exemplo, numa reingestão); esses documentos
+// ficam para a próxima execução.
+func percorrerAnos(ctx context.Context, simular bool, f funcaoAno) (models.RelatorioMigracao, error) {
+	var relatorio models.RelatorioMigracao
+	coll := database.DB.Collection("Veiculos")
+	cursor, err := coll.Find(ctx, bson.M{})
+	if err != nil {
+		return relatorio, fmt.Errorf("erro ao buscar documentos de Veiculos: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var lote []mongo.WriteModel
+	gravar := func() error {
+		if len(lote) == 0 {
+			return nil
+		}
+		resultado, err := coll.BulkWrite(ctx, lote, options.BulkWrite().SetOrdered(false))
+		if err != nil {
+			return fmt.Errorf("erro ao gravar documentos de Veiculos: %v", err)
+		}
+		relatorio.Alterados += resultado.ModifiedCount
+		lote = lote[:0]
+		return nil
+	}
+
+	for cursor.Next(ctx) {
+		var doc documentoVeiculos
+		if err := cursor.Decode(&doc); err != nil {
+			log.Printf("Migrações: erro ao decodificar documento de Veiculos: %v", err)
+			relatorio.Ignoradas++
+			continue
+		}
+		relatorio.Documentos++
+
+		filtro := bson.M{"_id": doc.Id}
+		set, unset := bson.M{}, bson.M{}
+		for i, modelo := range doc.Models {
+			for j, ano := range modelo.Years {
+				alteracao := f(&doc.Marca, modelo, ano)
+				if alteracao.ignorada {
+					relatorio.Ignoradas++
+				}
+				if len(alteracao.set) == 0 && len(alteracao.unset) == 0 {
+					continue
+				}
+				relatorio.Entradas++
+				caminho := "models." + strconv.Itoa(i) + ".years." + strconv.Itoa(j) + "."
+				filtro["models."+strconv.Itoa(i)+".modelCode"] = modelo.ModelCode
+				filtro[caminho+"year"] = ano.Year
+				for campo, valor := range alteracao.set {
+					set[caminho+campo] = valor
+				}
+				for _, campo := range alteracao.unset {
+					unset[caminho+campo] = ""
+				}
+			}
+		}
+		if len(set) == 0 && len(unset) == 0 {
+			continue
+		}
+		if simular {
+			relatorio.Alterados++
+			continue
+		}
+		update := bson.M{}
+		if len(set) > 0 {
+			update["$set"] = set
+		}
+		if len(unset) > 0 {
+			update["$unset"] = unset
+		}
+		lote = append(lote, mongo.NewUpdateOneModel().SetFilter(filtro).SetUpdate(update))
+		if len(lote) >= tamanhoLote {
+			if err := gravar(); err != nil {
+				return relatorio, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return relatorio, fmt.Errorf("erro no cursor de Veiculos: %v", err)
+	}
+	return relatorio, gravar()
+}
+
+// aplicarPrecosCentavos grava priceCents nas entradas sem ele e corrige os
+// que divergem do texto do preço, removendo os de entradas sem preço.
+func aplicarPrecosCentavos(ctx context.Context, simular bool) (models.RelatorioMigracao, error) {
+	return percorrerAnos(ctx, simular, func(_ *models.Marca, _ models.Modelo, ano models.AnoModelo) alteracaoAno {
+		preco, err := models.ParseMoney(ano.Price)
+		if errors.Is(err, models.ErrPrecoAusente) {
+			if ano.PrecoCentavos != nil {
+				return alteracaoAno{unset: []string{"priceCents"}}
+			}
+			return alteracaoAno{}
+		}
+		if err != nil {
+			return alteracaoAno{ignorada: ano.PrecoCentavos == nil}
+		}
+		if ano.PrecoCentavos != nil && *ano.PrecoCentavos == preco {
+			return alteracaoAno{}
+		}
+		return alteracaoAno{set: bson.M{"priceCents": preco}}
+	})
+}
+
+// reverterPrecosCentavos remove priceCents. O campo é derivado do preço em
+// texto, que continua no documento.
+func reverterPrecosCentavos(ctx context.Context, simular bool) (models.RelatorioMigracao, error) {
+	return percorrerAnos(ctx, simular, func(_ *models.Marca, _ models.Modelo, ano models.AnoModelo) alteracaoAno {
+		if ano.PrecoCentavos == nil {
+			return alteracaoAno{}
+		}
+		return alteracaoAno{unset: []string{"priceCents"}}
+	})
+}
+
+// aplicarCombustivel grava em fuel o combustível do código do ano e, nas
+// entradas sem código, troca nomes e códigos numéricos ("Flex", "5") pelo
+// identificador da API ("flex"). Não é reversível: o valor antigo não é
+// guardado.
+func aplicarCombustivel(ctx context.Context, simular bool) (models.RelatorioMigracao, error) {
+	return percorrerAnos(ctx, simular, func(_ *models.Marca, _ models.Modelo, ano models.AnoModelo) alteracaoAno {
+		if ano.CodigoAno != "" {
+			codigo, err := models.ParseCodigoAno(ano.CodigoAno)
+			if err != nil {
+				return alteracaoAno{ignorada: true}
+			}
+			if combustivel, ok := ano.CombustivelDoCodigo(); ok {
+				return alteracaoAno{set: bson.M{"fuel": combustivel}}
+			}
+			if codigo.Combustivel != models.CombustivelDesconhecido {
+				return alteracaoAno{}
+			}
+		}
+		if ano.Combustivel == models.CombustivelDesconhecido {
+			return alteracaoAno{}
+		}
+		combustivel, err := models.ParseCombustivel(string(ano.Combustivel))
+		if err != nil {
+			return alteracaoAno{ignorada: true}
+		}
+		if combustivel == ano.Combustivel {
+			return alteracaoAno{}
+		}
+		return alteracaoAno{set: bson.M{"fuel": combustivel}}
+	})
+}
+
+type chaveModelo struct {
+	Marca  int32 `bson:"marca"`
+	Modelo int32 `bson:"modelo"`
+}
+
+// aplicarCodigosFipe preenche o código FIPE dos anos-modelo sem código com o
+// código gravado no mesmo modelo, em qualquer tabela. O código FIPE é do
+// modelo, igual em todos os anos. Modelos com mais de um código são deixados
+// como estão e suas entradas sem código contam como ignoradas. Não é
+// reversível.
+func aplicarCodigosFipe(ctx context.Context, simular bool) (models.RelatorioMigracao, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$unwind", Value: "$models"}},
+		{{Key: "$unwind", Value: "$models.years"}},
+		{{Key: "$match", Value: bson.M{"models.years.fipeCode": bson.M{"$nin": bson.A{nil, ""}}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     bson.M{"marca": "$brandCode", "modelo": "$models.modelCode"},
+			"codigos": bson.M{"$addToSet": "$models.years.fipeCode"},
+		}}},
+	}
+	cursor, err := database.DB.Collection("Veiculos").Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return models.RelatorioMigracao{}, fmt.Errorf("erro ao agrupar códigos FIPE: %v", err)
+	}
+	var grupos []struct {
+		Id      chaveModelo `bson:"_id"`
+		Codigos []string    `bson:"codigos"`
+	}
+	if err := cursor.All(ctx, &grupos); err != nil {
+		return models.RelatorioMigracao{}, fmt.Errorf("erro ao decodificar códigos FIPE: %v", err)
+	}
+
+	// Um código vazio no mapa marca um modelo com códigos conflitantes.
+	codigos := make(map[chaveModelo]string, len(grupos))
+	for _, g := range grupos {
+		distintos := make(map[string]bool)
+		for _, c := range g.Codigos {
+			if c = strings.TrimSpace(c); c != "" {
+				distintos[c] = true
+			}
+		}
+		for c := range distintos {
+			if len(distintos) == 1 {
+				codigos[g.Id] = c
+			} else {
+				codigos[g.Id] = ""
+			}
+		}
+	}
+
+	return percorrerAnos(ctx, simular, func(marca *models.Marca, modelo models.Modelo, ano models.AnoModelo) alteracaoAno {
+		if strings.TrimSpace(ano.CodigoFipe) != "" {
+			return alteracaoAno{}
+		}
+		codigo, ok := codigos[chaveModelo{marca.BrandCode, modelo.ModelCode}]
+		if !ok {
+			return alteracaoAno{}
+		}
+		if codigo == "" {
+			return alteracaoAno{ignorada: true}
+		}
+		return alteracaoAno{set: bson.M{"fipeCode": codigo}}
+	})
+}
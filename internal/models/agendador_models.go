@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefinicaoJob é um job do agendador com sua expressão cron. Os jobs
+// conhecidos vêm do código; config/agendador.json pode mudar Cron e Ativo.
+type DefinicaoJob struct {
+	Nome      string `json:"nome"`
+	Descricao string `json:"descricao"`
+	Cron      string `json:"cron"`
+	Ativo     bool   `json:"ativo"`
+}
+
+// ConfigAgendador é o arquivo config/agendador.json. Campos omitidos mantêm
+// o padrão do job.
+type ConfigAgendador struct {
+	Jobs []AjusteJob `json:"jobs"`
+}
+
+type AjusteJob struct {
+	Nome  string `json:"nome"`
+	Cron  string `json:"cron,omitempty"`
+	Ativo *bool  `json:"ativo,omitempty"`
+}
+
+const (
+	OrigemAgendada = "agendada"
+	OrigemManual   = "manual"
+)
+
+const (
+	StatusExecucaoPendente   = "pendente"
+	StatusExecucaoExecutando = "executando"
+	StatusExecucaoConcluida  = "concluida"
+	StatusExecucaoFalhou     = "falhou"
+	// StatusExecucaoIgnorada indica que outra execução do mesmo job estava em
+	// andamento.
+	StatusExecucaoIgnorada = "ignorada"
+)
+
+// ExecucaoJob é um documento do histórico de execuções (ExecucoesJobs).
+// Execuções agendadas são únicas por job e AgendadaPara; as manuais são
+// criadas pendentes pela API e assumidas por um worker.
+type ExecucaoJob struct {
+	Id           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Job          string             `json:"job" bson:"job"`
+	Origem       string             `json:"origem" bson:"origem"`
+	AgendadaPara *time.Time         `json:"agendadaPara,omitempty" bson:"agendadaPara,omitempty"`
+	Status       string             `json:"status" bson:"status"`
+	Worker       string             `json:"worker,omitempty" bson:"worker,omitempty"`
+	CriadaEm     time.Time          `json:"criadaEm" bson:"criadaEm"`
+	IniciadaEm   *time.Time         `json:"iniciadaEm,omitempty" bson:"iniciadaEm,omitempty"`
+	FinalizadaEm *time.Time         `json:"finalizadaEm,omitempty" bson:"finalizadaEm,omitempty"`
+	DuracaoMs    int64              `json:"duracaoMs,omitempty" bson:"duracaoMs,omitempty"`
+	Erro         string             `json:"erro,omitempty" bson:"erro,omitempty"`
+	// Contagens são os números que o job reporta (tabela verificada,
+	// ocorrências encontradas...).
+	Contagens map[string]int64 `json:"contagens,omitempty" bson:"contagens,omitempty"`
+}
+
+// ResumoJob é um job com a próxima execução agendada e a última execução.
+type ResumoJob struct {
+	DefinicaoJob
+	Proxima        *time.Time   `json:"proxima,omitempty"`
+	UltimaExecucao *ExecucaoJob `json:"ultimaExecucao,omitempty"`
+}
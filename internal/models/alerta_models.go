@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ItemWatchlist é um ano-modelo observado: quando o preço variar mais que
+// LimitePercentual entre duas tabelas consecutivas, um webhook é disparado.
+type ItemWatchlist struct {
+	Id               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Nome             string             `json:"nome,omitempty" bson:"nome,omitempty"`
+	ModelCode        int32              `json:"modelCode" bson:"modelCode"`
+	Ano              int32              `json:"ano" bson:"ano"`
+	LimitePercentual float64            `json:"limitePercentual" bson:"limitePercentual"`
+	WebhookURL       string             `json:"webhookUrl" bson:"webhookUrl"`
+	Segredo          string             `json:"segredo,omitempty" bson:"segredo"`
+	Ativo            bool               `json:"ativo" bson:"ativo"`
+	CriadoEm         time.Time          `json:"criadoEm" bson:"criadoEm"`
+	AtualizadoEm     time.Time          `json:"atualizadoEm" bson:"atualizadoEm"`
+}
+
+// PrecoAlerta é o preço do ano-modelo em uma das tabelas comparadas.
+type PrecoAlerta struct {
+	TabelaId int    `json:"tabelaId" bson:"tabelaId"`
+	Ref      string `json:"ref" bson:"ref"`
+	Periodo  string `json:"periodo,omitempty" bson:"periodo,omitempty"`
+	Valor    Money  `json:"valor" bson:"valor"`
+	ValorFmt string `json:"valorFmt" bson:"valorFmt"`
+}
+
+// AlertaPreco é o corpo enviado ao webhook quando um alerta dispara.
+type AlertaPreco struct {
+	Evento             string             `json:"evento" bson:"evento"`
+	WatchId            primitive.ObjectID `json:"watchId" bson:"watchId"`
+	Nome               string             `json:"nome,omitempty" bson:"nome,omitempty"`
+	BrandName          string             `json:"brandName" bson:"brandName"`
+	ModelCode          int32              `json:"modelCode" bson:"modelCode"`
+	ModelName          string             `json:"modelName" bson:"modelName"`
+	Ano                int32              `json:"ano" bson:"ano"`
+	TabelaAnterior     PrecoAlerta        `json:"tabelaAnterior" bson:"tabelaAnterior"`
+	TabelaAtual        PrecoAlerta        `json:"tabelaAtual" bson:"tabelaAtual"`
+	VariacaoPercentual float64            `json:"variacaoPercentual" bson:"variacaoPercentual"`
+	LimitePercentual   float64            `json:"limitePercentual" bson:"limitePercentual"`
+	DisparadoEm        time.Time          `json:"disparadoEm" bson:"disparadoEm"`
+}
+
+const (
+	StatusEntregaPendente = "pendente"
+	StatusEntregaEntregue = "entregue"
+	StatusEntregaFalhou   = "falhou"
+)
+
+type TentativaEntrega struct {
+	Em         time.Time `json:"em" bson:"em"`
+	StatusCode int       `json:"statusCode,omitempty" bson:"statusCode,omitempty"`
+	Erro       string    `json:"erro,omitempty" bson:"erro,omitempty"`
+	DuracaoMs  int64     `json:"duracaoMs" bson:"duracaoMs"`
+}
+
+// EntregaWebhook registra cada alerta enviado e suas tentativas de entrega
+// (coleção WebhookEntregas).
+type EntregaWebhook struct {
+	Id         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	WatchId    primitive.ObjectID `json:"watchId" bson:"watchId"`
+	TabelaId   int                `json:"tabelaId" bson:"tabelaId"`
+	URL        string             `json:"url" bson:"url"`
+	Evento     string             `json:"evento" bson:"evento"`
+	Payload    AlertaPreco        `json:"payload" bson:"payload"`
+	Status     string             `json:"status" bson:"status"`
+	Tentativas []TentativaEntrega `json:"tentativas" bson:"tentativas"`
+	CriadoEm   time.Time          `json:"criadoEm" bson:"criadoEm"`
+}
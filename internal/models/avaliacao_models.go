@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ItemAvaliacao é um veículo enviado para avaliação em lote. Pode ser
+// identificado pelo código FIPE ou por marca/modelo (nome ou código) e ano.
+type ItemAvaliacao struct {
+	CodigoFipe string `json:"codigoFipe,omitempty" bson:"codigoFipe,omitempty"`
+	Marca      string `json:"marca,omitempty" bson:"marca,omitempty"`
+	Modelo     string `json:"modelo,omitempty" bson:"modelo,omitempty"`
+	Ano        string `json:"ano,omitempty" bson:"ano,omitempty"`
+}
+
+// ResultadoItemAvaliacao é o resultado da avaliação de uma linha do lote.
+// Confianca vai de 0 a 1: 1 para correspondência por código, menor quando a
+// marca ou o modelo foram localizados por semelhança de nome.
+type ResultadoItemAvaliacao struct {
+	Linha      int           `json:"linha" bson:"linha"`
+	Entrada    ItemAvaliacao `json:"entrada" bson:"entrada"`
+	BrandName  string        `json:"brandName,omitempty" bson:"brandName,omitempty"`
+	BrandCode  int32         `json:"brandCode,omitempty" bson:"brandCode,omitempty"`
+	ModelName  string        `json:"modelName,omitempty" bson:"modelName,omitempty"`
+	ModelCode  int32         `json:"modelCode,omitempty" bson:"modelCode,omitempty"`
+	Ano        int32         `json:"ano,omitempty" bson:"ano,omitempty"`
+	CodigoFipe string        `json:"codigoFipe,omitempty" bson:"codigoFipe,omitempty"`
+	Valor      Money         `json:"valor,omitempty" bson:"valor,omitempty"`
+	ValorFmt   string        `json:"valorFmt,omitempty" bson:"valorFmt,omitempty"`
+	Confianca  float64       `json:"confianca" bson:"confianca"`
+	Erro       string        `json:"erro,omitempty" bson:"erro,omitempty"`
+}
+
+type TotaisAvaliacao struct {
+	Veiculos      int    `json:"veiculos" bson:"veiculos"`
+	Avaliados     int    `json:"avaliados" bson:"avaliados"`
+	Erros         int    `json:"erros" bson:"erros"`
+	ValorTotal    Money  `json:"valorTotal" bson:"valorTotal"`
+	ValorTotalFmt string `json:"valorTotalFmt" bson:"valorTotalFmt"`
+	ValorMedioFmt string `json:"valorMedioFmt" bson:"valorMedioFmt"`
+}
+
+type ResultadoAvaliacaoLote struct {
+	TabelaId int                      `json:"tabelaId" bson:"tabelaId"`
+	Ref      string                   `json:"ref" bson:"ref"`
+	Periodo  string                   `json:"periodo,omitempty" bson:"periodo,omitempty"`
+	Itens    []ResultadoItemAvaliacao `json:"itens" bson:"itens"`
+	Totais   TotaisAvaliacao          `json:"totais" bson:"totais"`
+}
+
+const (
+	StatusJobPendente    = "pendente"
+	StatusJobProcessando = "processando"
+	StatusJobConcluido   = "concluido"
+	StatusJobErro        = "erro"
+)
+
+// JobAvaliacaoLote é um lote grande processado de forma assíncrona, persistido
+// na coleção AvaliacaoLote.
+type JobAvaliacaoLote struct {
+	Id          primitive.ObjectID      `json:"id" bson:"_id,omitempty"`
+	Status      string                  `json:"status" bson:"status"`
+	TabelaId    int                     `json:"tabelaId" bson:"tabelaId"`
+	Total       int                     `json:"total" bson:"total"`
+	Itens       []ItemAvaliacao         `json:"-" bson:"itens"`
+	CriadoEm    time.Time               `json:"criadoEm" bson:"criadoEm"`
+	ConcluidoEm *time.Time              `json:"concluidoEm,omitempty" bson:"concluidoEm,omitempty"`
+	Erro        string                  `json:"erro,omitempty" bson:"erro,omitempty"`
+	Resultado   *ResultadoAvaliacaoLote `json:"resultado,omitempty" bson:"resultado,omitempty"`
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Combustivel é o tipo de combustível de um ano-modelo, como codificado pela
+// FIPE no sufixo do código do ano ("2019-1", "32000-5").
+type Combustivel string
+
+const (
+	CombustivelDesconhecido Combustivel = ""
+	CombustivelGasolina     Combustivel = "gasolina"
+	CombustivelAlcool       Combustivel = "alcool"
+	CombustivelDiesel       Combustivel = "diesel"
+	CombustivelEletrico     Combustivel = "eletrico"
+	CombustivelFlex         Combustivel = "flex"
+	CombustivelHibrido      Combustivel = "hibrido"
+	CombustivelGasNatural   Combustivel = "gnv"
+)
+
+// combustivelPorCodigo segue a numeração usada pela FIPE nos códigos de ano.
+var combustivelPorCodigo = map[int]Combustivel{
+	1: CombustivelGasolina,
+	2: CombustivelAlcool,
+	3: CombustivelDiesel,
+	4: CombustivelEletrico,
+	5: CombustivelFlex,
+	6: CombustivelHibrido,
+	7: CombustivelGasNatural,
+}
+
+// Combustiveis retorna os combustíveis conhecidos, na ordem dos códigos da
+// FIPE.
+func Combustiveis() []Combustivel {
+	lista := make([]Combustivel, 0, len(combustivelPorCodigo))
+	for codigo := 1; codigo <= len(combustivelPorCodigo); codigo++ {
+		lista = append(lista, combustivelPorCodigo[codigo])
+	}
+	return lista
+}
+
+// ErrCombustivelInvalido indica um combustível ou código de ano não reconhecido.
+var ErrCombustivelInvalido = errors.New("combustível inválido")
+
+// ParseCombustivel aceita o código numérico da FIPE ("5"), o identificador
+// usado na API ("flex") ou o nome por extenso ("Elétrico", "Álcool").
+func ParseCombustivel(s string) (Combustivel, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if codigo, err := strconv.Atoi(s); err == nil {
+		if c, ok := combustivelPorCodigo[codigo]; ok {
+			return c, nil
+		}
+		return CombustivelDesconhecido, fmt.Errorf("%w: '%s'", ErrCombustivelInvalido, s)
+	}
+	switch s {
+	case "gasolina":
+		return CombustivelGasolina, nil
+	case "alcool", "álcool", "etanol":
+		return CombustivelAlcool, nil
+	case "diesel":
+		return CombustivelDiesel, nil
+	case "eletrico", "elétrico":
+		return CombustivelEletrico, nil
+	case "flex":
+		return CombustivelFlex, nil
+	case "hibrido", "híbrido":
+		return CombustivelHibrido, nil
+	case "gnv", "gas natural", "gás natural":
+		return CombustivelGasNatural, nil
+	}
+	return CombustivelDesconhecido, fmt.Errorf("%w: '%s'", ErrCombustivelInvalido, s)
+}
+
+// CodigoAno é a interpretação de uma entrada de ano da FIPE.
+type CodigoAno struct {
+	Codigo      string      `json:"yearCode,omitempty"`
+	AnoModelo   int32       `json:"anoModelo"`
+	Combustivel Combustivel `json:"combustivel,omitempty"`
+	ZeroKm      bool        `json:"zeroKm"`
+}
+
+// ParseCodigoAno interpreta um código de ano da FIPE no formato
+// "<ano>-<combustível>" ("2019-1", "32000-5"). Um ano sem sufixo ("2019")
+// é aceito, com combustível desconhecido.
+func ParseCodigoAno(codigo string) (CodigoAno, error) {
+	codigo = strings.TrimSpace(codigo)
+	anoStr, combStr, temComb := strings.Cut(codigo, "-")
+	ano, err := strconv.Atoi(anoStr)
+	if err != nil || ano <= 0 || ano > AnoZeroKm {
+		return CodigoAno{}, fmt.Errorf("código de ano inválido: '%s'", codigo)
+	}
+	resultado := CodigoAno{Codigo: codigo, AnoModelo: int32(ano), ZeroKm: ano == AnoZeroKm}
+	if temComb {
+		n, err := strconv.Atoi(combStr)
+		if err != nil {
+			return CodigoAno{}, fmt.Errorf("código de ano inválido: '%s'", codigo)
+		}
+		c, ok := combustivelPorCodigo[n]
+		if !ok {
+			return CodigoAno{}, fmt.Errorf("%w: código de ano '%s'", ErrCombustivelInvalido, codigo)
+		}
+		resultado.Combustivel = c
+	}
+	return resultado, nil
+}
+
+// ParseAnoConsulta interpreta o ano informado em consultas: "0km", sinônimo
+// de AnoZeroKm, ou o que ParseCodigoAno aceita, o ano numérico ou o código de
+// ano da FIPE com o combustível ("2019-1").
+func ParseAnoConsulta(ano string) (CodigoAno, error) {
+	ano = strings.TrimSpace(ano)
+	if strings.EqualFold(ano, "0km") {
+		return CodigoAno{AnoModelo: AnoZeroKm, ZeroKm: true}, nil
+	}
+	return ParseCodigoAno(ano)
+}
+
+// NovoAnoModelo monta a entrada de ano a partir do código e do preço
+// retornados pela FIPE, já com o ano e o combustível separados. É o formato
+// que quem grava documentos de Veiculos deve usar: a ingestão mensal, que
+// fica fora deste repositório, e o gerador de dados do fipebench. A leitura
+// não depende dele: Detalhes interpreta também as entradas antigas, e a
+// etapa pós-ingestão (GravarCombustivelTabela, em services) e a migração
+// combustivel_codigo_ano completam fuel nas já gravadas.
+func NovoAnoModelo(codigo, preco string) (AnoModelo, error) {
+	c, err := ParseCodigoAno(codigo)
+	if err != nil {
+		return AnoModelo{}, err
+	}
+	return AnoModelo{Year: c.AnoModelo, Price: preco, CodigoAno: c.Codigo, Combustivel: c.Combustivel}, nil
+}
+
+// CombustivelDoCodigo retorna o combustível do código do ano quando ele é
+// conhecido e difere do gravado em fuel, ou seja, quando fuel deve ser
+// gravado. Entradas sem código, com código inválido ou já corretas devolvem
+// false.
+func (a AnoModelo) CombustivelDoCodigo() (Combustivel, bool) {
+	if a.CodigoAno == "" {
+		return CombustivelDesconhecido, false
+	}
+	c, err := ParseCodigoAno(a.CodigoAno)
+	if err != nil || c.Combustivel == CombustivelDesconhecido || c.Combustivel == a.Combustivel {
+		return CombustivelDesconhecido, false
+	}
+	return c.Combustivel, true
+}
+
+// Detalhes retorna ano, combustível e indicador de 0km da entrada. Usa o
+// código do ano quando gravado; documentos antigos, só com "year", ficam com
+// o combustível gravado em "fuel" ou desconhecido.
+func (a AnoModelo) Detalhes() CodigoAno {
+	combustivel, err := ParseCombustivel(string(a.Combustivel))
+	if err != nil {
+		combustivel = CombustivelDesconhecido
+	}
+	if a.CodigoAno != "" {
+		if c, err := ParseCodigoAno(a.CodigoAno); err == nil {
+			if c.Combustivel == CombustivelDesconhecido {
+				c.Combustivel = combustivel
+			}
+			return c
+		}
+	}
+	return CodigoAno{AnoModelo: a.Year, Combustivel: combustivel, ZeroKm: a.Year == AnoZeroKm}
+}
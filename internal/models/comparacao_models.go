@@ -0,0 +1,112 @@
+package models
+
+// ParVeiculo identifica um ano-modelo a ser comparado.
+type ParVeiculo struct {
+	ModelCode int32 `json:"modelCode"`
+	Ano       int32 `json:"ano"`
+}
+
+type TabelaComparada struct {
+	TabelaId int    `json:"tabelaId"`
+	Ref      string `json:"ref"`
+	Periodo  string `json:"periodo,omitempty"`
+}
+
+// PrecoComparado é o preço de um veículo em uma das tabelas comparadas, com a
+// variação em relação à primeira tabela.
+type PrecoComparado struct {
+	TabelaId   int      `json:"tabelaId"`
+	Valor      Money    `json:"valor,omitempty"`
+	ValorFmt   string   `json:"valorFmt"`
+	Disponivel bool     `json:"disponivel"`
+	Variacao   *float64 `json:"variacao,omitempty"`
+}
+
+// Posicao é a colocação por preço (1 = mais caro) dentro de um grupo.
+type Posicao struct {
+	Posicao int `json:"posicao"`
+	Total   int `json:"total"`
+}
+
+// VeiculoComparado traz os preços de um ano-modelo nas tabelas comparadas.
+// PosicaoMarca e PosicaoSegmento são as colocações por preço entre os modelos
+// da marca e entre os modelos da marca no mesmo segmento.
+type VeiculoComparado struct {
+	BrandName       string           `json:"brandName"`
+	BrandCode       int32            `json:"brandCode"`
+	ModelName       string           `json:"modelName"`
+	ModelCode       int32            `json:"modelCode"`
+	Ano             int32            `json:"ano"`
+	Segmento        Segmento         `json:"segmento,omitempty"`
+	Precos          []PrecoComparado `json:"precos"`
+	Depreciacao     *float64         `json:"depreciacao,omitempty"`
+	PosicaoMarca    *Posicao         `json:"posicaoMarca,omitempty"`
+	PosicaoSegmento *Posicao         `json:"posicaoSegmento,omitempty"`
+	Erro            string           `json:"erro,omitempty"`
+}
+
+// Comparacao é a matriz normalizada devolvida por /api/comparar. Os preços de
+// cada veículo seguem a ordem de Tabelas; Diferencas[i][j] é a diferença
+// percentual do veículo i em relação ao veículo j na primeira tabela.
+type Comparacao struct {
+	Tabelas    []TabelaComparada  `json:"tabelas"`
+	Veiculos   []VeiculoComparado `json:"veiculos"`
+	Diferencas [][]*float64       `json:"diferencas"`
+}
+
+// ModeloCatalogo identifica um modelo no catálogo de uma tabela.
+type ModeloCatalogo struct {
+	ModelCode   int32    `json:"modelCode"`
+	ModelName   string   `json:"modelName"`
+	CodigosFipe []string `json:"codigosFipe,omitempty"`
+	Anos        []int32  `json:"anos,omitempty"`
+}
+
+// RenomeacaoModelo é um modelo que mudou de nome entre duas tabelas: o mesmo
+// código de modelo com outro nome, ou um modelo substituído por outro com o
+// mesmo código FIPE.
+type RenomeacaoModelo struct {
+	Anterior     ModeloCatalogo `json:"anterior"`
+	Atual        ModeloCatalogo `json:"atual"`
+	CodigoFipe   string         `json:"codigoFipe,omitempty"`
+	Similaridade float64        `json:"similaridade"`
+}
+
+// NovosAnosModelo lista os anos-modelo que passaram a existir em um modelo já
+// presente na tabela anterior.
+type NovosAnosModelo struct {
+	ModelCode int32   `json:"modelCode"`
+	ModelName string  `json:"modelName"`
+	Anos      []int32 `json:"anos"`
+}
+
+type CicloVidaMarca struct {
+	BrandCode      int32              `json:"brandCode"`
+	BrandName      string             `json:"brandName"`
+	Lancamentos    []ModeloCatalogo   `json:"lancamentos"`
+	Descontinuados []ModeloCatalogo   `json:"descontinuados"`
+	Renomeados     []RenomeacaoModelo `json:"renomeados"`
+	NovosAnos      []NovosAnosModelo  `json:"novosAnos"`
+}
+
+type TotaisCicloVida struct {
+	Lancamentos    int `json:"lancamentos"`
+	Descontinuados int `json:"descontinuados"`
+	Renomeados     int `json:"renomeados"`
+	NovosAnos      int `json:"novosAnos"`
+}
+
+// CicloVidaPeriodo traz as mudanças de catálogo entre duas tabelas
+// consecutivas, por marca. Marcas sem mudanças não são listadas.
+type CicloVidaPeriodo struct {
+	TabelaAnterior TabelaComparada  `json:"tabelaAnterior"`
+	TabelaAtual    TabelaComparada  `json:"tabelaAtual"`
+	Marcas         []CicloVidaMarca `json:"marcas"`
+	Totais         TotaisCicloVida  `json:"totais"`
+}
+
+// CicloVida é a resposta de /api/ciclo-vida: um período para cada par de
+// tabelas consecutivas, em ordem cronológica.
+type CicloVida struct {
+	Periodos []CicloVidaPeriodo `json:"periodos"`
+}
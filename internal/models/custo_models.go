@@ -0,0 +1,93 @@
+package models
+
+// ConfigCustos são as premissas do custo total de posse (config/custos.json).
+// SeguroPercentual é o prêmio anual em % do valor FIPE, com valores próprios
+// por segmento em SeguroSegmento.
+type ConfigCustos struct {
+	SeguroPercentual float64                          `json:"seguroPercentual"`
+	SeguroSegmento   map[Segmento]float64             `json:"seguroSegmento,omitempty"`
+	Combustiveis     map[Combustivel]CustoCombustivel `json:"combustiveis"`
+	Manutencao       PremissasManutencao              `json:"manutencao"`
+}
+
+// CustoCombustivel é o preço por unidade (litro, kWh ou m³) e o consumo
+// médio em km por unidade.
+type CustoCombustivel struct {
+	Preco   float64 `json:"preco"`
+	Unidade string  `json:"unidade"`
+	Consumo float64 `json:"consumo"`
+}
+
+// PremissasManutencao: Base é o custo anual em reais de um veículo novo, que
+// cresce CrescimentoAnual % a cada ano de idade.
+type PremissasManutencao struct {
+	Base             float64 `json:"base"`
+	CrescimentoAnual float64 `json:"crescimentoAnual"`
+}
+
+// CustosPosse separa o custo de um período por componente.
+type CustosPosse struct {
+	Depreciacao    Money  `json:"depreciacao"`
+	DepreciacaoFmt string `json:"depreciacaoFmt"`
+	IPVA           Money  `json:"ipva"`
+	IPVAFmt        string `json:"ipvaFmt"`
+	Seguro         Money  `json:"seguro"`
+	SeguroFmt      string `json:"seguroFmt"`
+	Combustivel    Money  `json:"combustivel"`
+	CombustivelFmt string `json:"combustivelFmt"`
+	Manutencao     Money  `json:"manutencao"`
+	ManutencaoFmt  string `json:"manutencaoFmt"`
+	Total          Money  `json:"total"`
+	TotalFmt       string `json:"totalFmt"`
+}
+
+// CustoAnual é o custo de um ano de posse. ValorInicial é o valor FIPE
+// estimado no início do ano, base do IPVA e do seguro.
+type CustoAnual struct {
+	Ano             int    `json:"ano"`
+	Exercicio       int    `json:"exercicio"`
+	Idade           int    `json:"idade"`
+	ValorInicial    Money  `json:"valorInicial"`
+	ValorInicialFmt string `json:"valorInicialFmt"`
+	ValorFinal      Money  `json:"valorFinal"`
+	ValorFinalFmt   string `json:"valorFinalFmt"`
+	IPVAIsento      bool   `json:"ipvaIsento"`
+	CustosPosse
+}
+
+type CustoTotalVeiculo struct {
+	BrandName          string      `json:"brandName"`
+	ModelCode          int32       `json:"modelCode"`
+	ModelName          string      `json:"modelName"`
+	Ano                CodigoAno   `json:"ano"`
+	Segmento           Segmento    `json:"segmento"`
+	Idade              int         `json:"idade"`
+	PrecoAtual         Money       `json:"precoAtual"`
+	PrecoAtualFmt      string      `json:"precoAtualFmt"`
+	SeguroPercentual   float64     `json:"seguroPercentual"`
+	Combustivel        Combustivel `json:"combustivel"`
+	PrecoCombustivel   float64     `json:"precoCombustivel"`
+	UnidadeCombustivel string      `json:"unidadeCombustivel"`
+	Consumo            float64     `json:"consumo"`
+	// Extrapolado indica que a depreciação saiu da faixa de idades com preço
+	// na tabela.
+	Extrapolado    bool         `json:"extrapolado"`
+	Anos           []CustoAnual `json:"anos"`
+	Total          CustosPosse  `json:"total"`
+	CustoMensal    Money        `json:"custoMensal"`
+	CustoMensalFmt string       `json:"custoMensalFmt"`
+	CustoKm        Money        `json:"custoKm"`
+	CustoKmFmt     string       `json:"custoKmFmt"`
+}
+
+// CustoTotal é o resultado de /api/custo-total.
+type CustoTotal struct {
+	TabelaId   int                 `json:"tabelaId"`
+	Ref        string              `json:"ref"`
+	Periodo    string              `json:"periodo,omitempty"`
+	UF         string              `json:"uf"`
+	VersaoIPVA string              `json:"versaoIpva"`
+	Anos       int                 `json:"anos"`
+	KmAnual    int                 `json:"kmAnual"`
+	Veiculos   []CustoTotalVeiculo `json:"veiculos"`
+}
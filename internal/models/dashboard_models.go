@@ -3,21 +3,22 @@ package models
 const AnoZeroKm = 32000 
 
 type PriceInfo struct {
-	Modelo   string  `json:"modelo"`
-	Valor    float64 `json:"-"`
-	ValorFmt string  `json:"valorFmt"`
+	Modelo   string `json:"modelo"`
+	Valor    Money  `json:"-"`
+	ValorFmt string `json:"valorFmt"`
 }
 
 type BrandPeriodStats struct {
 	Ref                string             `json:"ref"`
+	Periodo            string             `json:"periodo,omitempty"`
 	TabelaId           int                `json:"-"`
 	MenorPreco0km      PriceInfo          `json:"menorPreco0km"`
 	MaiorPreco0km      PriceInfo          `json:"maiorPreco0km"`
-	ValorMedio0km      float64            `json:"-"`
+	ValorMedio0km      Money              `json:"-"`
 	ValorMedio0kmFmt   string             `json:"valorMedio0kmFmt"`
 	TotalModelos       int                `json:"totalModelos"`
 	TotalVeiculos0km   int                `json:"totalVeiculos0km"`
-	SomaValores0km     float64            `json:"-"` // Exemplo, tornando explícito que é interno
+	SomaValores0km     Money              `json:"-"` // Exemplo, tornando explícito que é interno
 	ModelosEncontrados map[int32]struct{} `json:"-"` // Exemplo
 	Inicializado       bool               `json:"-"` // Exemplo
 }
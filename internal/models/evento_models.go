@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Evento é uma notificação publicada na coleção Eventos e transmitida em
+// /api/eventos. Id é sequencial e serve como Last-Event-ID.
+type Evento struct {
+	Id       int64     `json:"id" bson:"_id"`
+	Tipo     string    `json:"tipo" bson:"tipo"`
+	Dados    bson.M    `json:"dados" bson:"dados"`
+	CriadoEm time.Time `json:"criadoEm" bson:"criadoEm"`
+}
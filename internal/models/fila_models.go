@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tipos de tarefa executados pelo worker.
+const (
+	TarefaAvaliacaoLote = "avaliacao_lote"
+)
+
+const (
+	StatusTarefaPendente   = "pendente"
+	StatusTarefaExecutando = "executando"
+	StatusTarefaConcluida  = "concluida"
+	StatusTarefaFalhou     = "falhou"
+)
+
+// Tarefa é um documento da coleção Tarefas, a fila de trabalho dos workers.
+// Uma tarefa em execução pertence ao worker que a reservou até LeaseAte; se
+// o lease vencer sem renovação, outro worker pode reservá-la de novo.
+type Tarefa struct {
+	Id    primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Tipo  string             `json:"tipo" bson:"tipo"`
+	Chave string             `json:"chave,omitempty" bson:"chave,omitempty"`
+	Dados bson.M             `json:"dados,omitempty" bson:"dados,omitempty"`
+
+	Status        string     `json:"status" bson:"status"`
+	Tentativas    int        `json:"tentativas" bson:"tentativas"`
+	MaxTentativas int        `json:"maxTentativas" bson:"maxTentativas"`
+	DisponivelEm  time.Time  `json:"disponivelEm" bson:"disponivelEm"`
+	LeaseAte      *time.Time `json:"leaseAte,omitempty" bson:"leaseAte,omitempty"`
+	Worker        string     `json:"worker,omitempty" bson:"worker,omitempty"`
+	Erro          string     `json:"erro,omitempty" bson:"erro,omitempty"`
+
+	CriadaEm    time.Time  `json:"criadaEm" bson:"criadaEm"`
+	IniciadaEm  *time.Time `json:"iniciadaEm,omitempty" bson:"iniciadaEm,omitempty"`
+	ConcluidaEm *time.Time `json:"concluidaEm,omitempty" bson:"concluidaEm,omitempty"`
+	// ExpiraEm é preenchido ao final; o índice TTL remove a tarefa depois.
+	ExpiraEm *time.Time `json:"-" bson:"expiraEm,omitempty"`
+}
@@ -0,0 +1,58 @@
+package models
+
+const (
+	SistemaPrice = "price"
+	SistemaSAC   = "sac"
+)
+
+// ParcelaFinanciamento é uma linha da tabela de amortização. Saldo é o saldo
+// devedor após o pagamento.
+type ParcelaFinanciamento struct {
+	Numero      int   `json:"numero"`
+	Parcela     Money `json:"parcela"`
+	Juros       Money `json:"juros"`
+	Amortizacao Money `json:"amortizacao"`
+	Saldo       Money `json:"saldo"`
+}
+
+// SimulacaoFinanciamento é o resultado de /api/financiamento/simulacao. As
+// taxas são percentuais. ValorFinanciado é o valor do veículo menos a
+// entrada; Principal soma a ele o IOF e as tarifas quando financiados. O CET
+// (custo efetivo total) considera o valor efetivamente liberado e as parcelas.
+type SimulacaoFinanciamento struct {
+	TabelaId           int       `json:"tabelaId"`
+	Ref                string    `json:"ref"`
+	Periodo            string    `json:"periodo,omitempty"`
+	BrandName          string    `json:"brandName"`
+	ModelCode          int32     `json:"modelCode"`
+	ModelName          string    `json:"modelName"`
+	Ano                CodigoAno `json:"ano"`
+	Sistema            string    `json:"sistema"`
+	Prazo              int       `json:"prazo"`
+	TaxaMensal         float64   `json:"taxaMensal"`
+	TaxaAnual          float64   `json:"taxaAnual"`
+	CustosFinanciados  bool      `json:"custosFinanciados"`
+	ValorVeiculo       Money     `json:"valorVeiculo"`
+	ValorVeiculoFmt    string    `json:"valorVeiculoFmt"`
+	Entrada            Money     `json:"entrada"`
+	EntradaFmt         string    `json:"entradaFmt"`
+	ValorFinanciado    Money     `json:"valorFinanciado"`
+	ValorFinanciadoFmt string    `json:"valorFinanciadoFmt"`
+	IOF                Money     `json:"iof"`
+	IOFFmt             string    `json:"iofFmt"`
+	Tarifas            Money     `json:"tarifas"`
+	TarifasFmt         string    `json:"tarifasFmt"`
+	Principal          Money     `json:"principal"`
+	PrincipalFmt       string    `json:"principalFmt"`
+	TotalParcelas      Money     `json:"totalParcelas"`
+	TotalParcelasFmt   string    `json:"totalParcelasFmt"`
+	TotalJuros         Money     `json:"totalJuros"`
+	TotalJurosFmt      string    `json:"totalJurosFmt"`
+	// CustoTotal é tudo o que o comprador desembolsa: entrada, parcelas e os
+	// custos pagos à vista.
+	CustoTotal    Money                  `json:"custoTotal"`
+	CustoTotalFmt string                 `json:"custoTotalFmt"`
+	CETMensal     float64                `json:"cetMensal"`
+	CETAnual      float64                `json:"cetAnual"`
+	Parcelas      []ParcelaFinanciamento `json:"parcelas"`
+}
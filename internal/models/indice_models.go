@@ -0,0 +1,48 @@
+package models
+
+const (
+	PesoIgual    = "igual"
+	PesoSegmento = "segmento"
+
+	GrupoGeral    = "geral"
+	GrupoMarca    = "marca"
+	GrupoSegmento = "segmento"
+	GrupoIdade    = "idade"
+)
+
+// ConfigIndice é o arquivo de pesos do índice (config/indice.json). Os pesos
+// por segmento são relativos: só a proporção entre eles importa.
+type ConfigIndice struct {
+	PesosSegmento map[Segmento]float64 `json:"pesosSegmento"`
+}
+
+// PontoIndice é o valor do índice em uma tabela. Variacao é a variação
+// percentual em relação à tabela anterior; Itens, quantos itens da cesta
+// tinham preço nas duas tabelas.
+type PontoIndice struct {
+	TabelaId int      `json:"tabelaId"`
+	Ref      string   `json:"ref"`
+	Periodo  string   `json:"periodo,omitempty"`
+	Valor    float64  `json:"valor"`
+	Variacao *float64 `json:"variacao,omitempty"`
+	Itens    int      `json:"itens"`
+}
+
+// SerieIndice é o índice geral ou um subíndice. Grupo é "geral", "marca",
+// "segmento" ou "idade"; Chave identifica o subíndice dentro do grupo (código
+// da marca, segmento ou faixa de idade) e Nome é o rótulo para exibição.
+type SerieIndice struct {
+	Grupo      string        `json:"grupo"`
+	Chave      string        `json:"chave"`
+	Nome       string        `json:"nome"`
+	ItensCesta int           `json:"itensCesta"`
+	Pontos     []PontoIndice `json:"pontos"`
+}
+
+type IndicePrecos struct {
+	Base       TabelaComparada `json:"base"`
+	Pesos      string          `json:"pesos"`
+	ItensCesta int             `json:"itensCesta"`
+	Geral      SerieIndice     `json:"geral"`
+	Subindices []SerieIndice   `json:"subindices"`
+}
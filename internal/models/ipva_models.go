@@ -0,0 +1,70 @@
+package models
+
+// UFs são as 27 unidades da federação, todas obrigatórias em cada versão das
+// regras de IPVA.
+var UFs = []string{
+	"AC", "AL", "AP", "AM", "BA", "CE", "DF", "ES", "GO", "MA", "MT", "MS", "MG", "PA",
+	"PB", "PR", "PE", "PI", "RJ", "RN", "RS", "RO", "RR", "SC", "SP", "SE", "TO",
+}
+
+// ArquivoIPVA é o arquivo de regras (config/ipva.json). Cada versão vale a
+// partir do exercício indicado até a versão seguinte.
+type ArquivoIPVA struct {
+	Fonte   string       `json:"fonte"`
+	Versoes []VersaoIPVA `json:"versoes"`
+}
+
+type VersaoIPVA struct {
+	Versao    string      `json:"versao"`
+	Exercicio int         `json:"exercicio"`
+	UFs       []RegraIPVA `json:"ufs"`
+}
+
+// RegraIPVA são as regras de automóveis de uma UF. Alíquotas e descontos são
+// percentuais; IsencaoIdade é a idade, em anos, a partir da qual o veículo é
+// isento (zero se a UF não isenta por idade).
+type RegraIPVA struct {
+	UF                   string                  `json:"uf"`
+	Nome                 string                  `json:"nome"`
+	Aliquota             float64                 `json:"aliquota"`
+	AliquotasCombustivel map[Combustivel]float64 `json:"aliquotasCombustivel,omitempty"`
+	DescontosCombustivel map[Combustivel]float64 `json:"descontosCombustivel,omitempty"`
+	IsentosCombustivel   []Combustivel           `json:"isentosCombustivel,omitempty"`
+	IsencaoIdade         int                     `json:"isencaoIdade"`
+	// Exemplos são casos conferidos a cada carga do arquivo.
+	Exemplos []ExemploIPVA `json:"exemplos,omitempty"`
+}
+
+type ExemploIPVA struct {
+	Valor       float64     `json:"valor"`
+	Idade       int         `json:"idade"`
+	Combustivel Combustivel `json:"combustivel"`
+	Esperado    float64     `json:"esperado"`
+}
+
+type CalculoIPVA struct {
+	UF           string      `json:"uf"`
+	NomeUF       string      `json:"nomeUf"`
+	Versao       string      `json:"versao"`
+	Exercicio    int         `json:"exercicio"`
+	TabelaId     int         `json:"tabelaId"`
+	Ref          string      `json:"ref"`
+	Periodo      string      `json:"periodo,omitempty"`
+	BrandName    string      `json:"brandName"`
+	ModelCode    int32       `json:"modelCode"`
+	ModelName    string      `json:"modelName"`
+	Ano          CodigoAno   `json:"ano"`
+	Combustivel  Combustivel `json:"combustivel,omitempty"`
+	Idade        int         `json:"idade"`
+	ValorBase    Money       `json:"valorBase"`
+	ValorBaseFmt string      `json:"valorBaseFmt"`
+	// Aliquota é a alíquota aplicada (a do combustível, se houver); Desconto,
+	// o percentual abatido do imposto.
+	Aliquota    float64  `json:"aliquota"`
+	Desconto    float64  `json:"desconto"`
+	Isento      bool     `json:"isento"`
+	Isencoes    []string `json:"isencoes"`
+	Valor       Money    `json:"valor"`
+	ValorFmt    string   `json:"valorFmt"`
+	Observacoes []string `json:"observacoes,omitempty"`
+}
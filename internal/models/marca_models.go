@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// FaixaPreco é o menor e o maior preço válido de um conjunto de anos-modelo.
+type FaixaPreco struct {
+	Minimo    Money  `json:"minimo" bson:"minimo"`
+	MinimoFmt string `json:"minimoFmt" bson:"minimoFmt"`
+	Maximo    Money  `json:"maximo" bson:"maximo"`
+	MaximoFmt string `json:"maximoFmt" bson:"maximoFmt"`
+}
+
+// Incluir amplia a faixa com o preço informado. Uma faixa nil é criada.
+func (f *FaixaPreco) Incluir(preco Money) *FaixaPreco {
+	if f == nil {
+		return &FaixaPreco{Minimo: preco, MinimoFmt: preco.String(), Maximo: preco, MaximoFmt: preco.String()}
+	}
+	if preco < f.Minimo {
+		f.Minimo, f.MinimoFmt = preco, preco.String()
+	}
+	if preco > f.Maximo {
+		f.Maximo, f.MaximoFmt = preco, preco.String()
+	}
+	return f
+}
+
+// MarcaCatalogo é o resumo de uma marca em uma tabela, gravado na coleção
+// CatalogoMarcas na publicação da tabela.
+type MarcaCatalogo struct {
+	Id        string `json:"-" bson:"_id"`
+	TabelaId  int    `json:"tabelaId" bson:"tabelaId"`
+	Ref       string `json:"ref" bson:"ref"`
+	Periodo   string `json:"periodo,omitempty" bson:"periodo,omitempty"`
+	BrandCode int32  `json:"brandCode" bson:"brandCode"`
+	BrandName string `json:"brandName" bson:"brandName"`
+	// Modelos e AnosModelo contam todos os modelos e entradas de ano;
+	// Modelos0km, os modelos com entrada 0km.
+	Modelos    int `json:"modelos" bson:"modelos"`
+	AnosModelo int `json:"anosModelo" bson:"anosModelo"`
+	Modelos0km int `json:"modelos0km" bson:"modelos0km"`
+	// Precos considera todos os anos-modelo com preço válido; Precos0km, só
+	// os 0km. Ficam nil quando não há nenhum preço.
+	Precos       *FaixaPreco `json:"precos,omitempty" bson:"precos,omitempty"`
+	Precos0km    *FaixaPreco `json:"precos0km,omitempty" bson:"precos0km,omitempty"`
+	AtualizadoEm time.Time   `json:"-" bson:"atualizadoEm"`
+}
+
+// MarcaGlobal é uma marca em todas as tabelas, gravada na coleção
+// CatalogoMarcasGlobal. Atual é o resumo da tabela mais recente em que a
+// marca aparece.
+type MarcaGlobal struct {
+	BrandCode      int32         `json:"brandCode" bson:"_id"`
+	BrandName      string        `json:"brandName" bson:"brandName"`
+	PrimeiraTabela int           `json:"primeiraTabela" bson:"primeiraTabela"`
+	UltimaTabela   int           `json:"ultimaTabela" bson:"ultimaTabela"`
+	Tabelas        int           `json:"tabelas" bson:"tabelas"`
+	Atual          MarcaCatalogo `json:"atual" bson:"atual"`
+}
+
+// DetalheMarca é a resposta de /api/marcas/{marca}.
+type DetalheMarca struct {
+	Marca MarcaGlobal `json:"marca"`
+	// Tabela é o resumo na tabela pedida, ou na mais recente.
+	Tabela MarcaCatalogo `json:"tabela"`
+	// Historico traz o resumo da marca em cada tabela catalogada, da mais
+	// antiga para a mais recente.
+	Historico []MarcaCatalogo `json:"historico"`
+}
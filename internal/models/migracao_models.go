@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// Situação de uma migração de dados na coleção Migracoes.
+const (
+	StatusMigracaoExecutando = "executando"
+	StatusMigracaoAplicada   = "aplicada"
+	StatusMigracaoFalhou     = "falhou"
+	StatusMigracaoRevertida  = "revertida"
+)
+
+// Resultado de uma migração numa execução de Aplicar ou Reverter. Além dos
+// status acima, uma migração pode ser simulada, pulada (em execução em outro
+// processo) ou irreversível.
+const (
+	ResultadoMigracaoSimulada     = "simulada"
+	ResultadoMigracaoPulada       = "pulada"
+	ResultadoMigracaoIrreversivel = "irreversivel"
+)
+
+// RelatorioMigracao conta o que uma migração de dados alterou (ou alteraria,
+// numa simulação).
+type RelatorioMigracao struct {
+	// Documentos lidos e documentos alterados.
+	Documentos int64 `json:"documentos" bson:"documentos"`
+	Alterados  int64 `json:"alterados" bson:"alterados"`
+	// Entradas são os anos-modelo alterados; Ignoradas, os que não puderam
+	// ser migrados (preço ou código inválido, código FIPE ambíguo).
+	Entradas  int64 `json:"entradas" bson:"entradas"`
+	Ignoradas int64 `json:"ignoradas" bson:"ignoradas"`
+}
+
+// RegistroMigracao é o documento de uma migração na coleção Migracoes.
+type RegistroMigracao struct {
+	Versao      int               `json:"versao" bson:"_id"`
+	Nome        string            `json:"nome" bson:"nome"`
+	Status      string            `json:"status" bson:"status"`
+	Dono        string            `json:"dono" bson:"dono"`
+	IniciadaEm  time.Time         `json:"iniciadaEm" bson:"iniciadaEm"`
+	ConcluidaEm *time.Time        `json:"concluidaEm,omitempty" bson:"concluidaEm,omitempty"`
+	Relatorio   RelatorioMigracao `json:"relatorio" bson:"relatorio"`
+	Erro        string            `json:"erro,omitempty" bson:"erro,omitempty"`
+}
+
+// SituacaoMigracao descreve uma migração do catálogo e seu registro, se já
+// foi executada.
+type SituacaoMigracao struct {
+	Versao     int               `json:"versao"`
+	Nome       string            `json:"nome"`
+	Descricao  string            `json:"descricao"`
+	Reversivel bool              `json:"reversivel"`
+	Registro   *RegistroMigracao `json:"registro,omitempty"`
+}
+
+// ResultadoMigracao é o resultado de uma migração numa execução.
+type ResultadoMigracao struct {
+	Versao    int               `json:"versao"`
+	Nome      string            `json:"nome"`
+	Status    string            `json:"status"`
+	Relatorio RelatorioMigracao `json:"relatorio"`
+	Erro      string            `json:"erro,omitempty"`
+}
+
+// EstadoIndice indica se um índice declarado existe na coleção.
+type EstadoIndice struct {
+	Colecao string `json:"colecao"`
+	Nome    string `json:"nome"`
+	Chaves  string `json:"chaves"`
+	Existe  bool   `json:"existe"`
+	// Criado indica que o índice foi criado nesta execução.
+	Criado bool `json:"criado"`
+}
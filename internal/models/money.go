@@ -0,0 +1,232 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// Money é um valor em reais guardado em centavos inteiros. Somas e médias
+// sobre milhares de preços não acumulam erro de arredondamento, e as regras
+// de arredondamento ficam explícitas nas operações que dividem ou multiplicam.
+//
+// Em JSON é serializado como número com duas casas decimais (12345.67); no
+// BSON, como int64 em centavos.
+type Money int64
+
+var (
+	// ErrPrecoAusente indica um preço vazio ou zerado ("R$ 0,00"), que a FIPE
+	// usa para anos-modelo sem cotação.
+	ErrPrecoAusente = errors.New("preço ausente")
+	// ErrPrecoInvalido indica um texto que não é um preço reconhecível.
+	ErrPrecoInvalido = errors.New("preço inválido")
+)
+
+// ParseMoney interpreta os formatos de preço da FIPE: "R$ 1.234,56",
+// "R$ 1234,56", "1.234", com ou sem aspas e espaços não separáveis. A vírgula
+// é o separador decimal; pontos são separadores de milhar, exceto quando não
+// há vírgula e o ponto é seguido por uma ou duas casas ("1234.5").
+func ParseMoney(s string) (Money, error) {
+	limpo := strings.NewReplacer("R$", "", "\"", "", " ", "", "\u00a0", "").Replace(strings.TrimSpace(s))
+	if limpo == "" || limpo == "-" {
+		return 0, ErrPrecoAusente
+	}
+
+	negativo := strings.HasPrefix(limpo, "-")
+	limpo = strings.TrimPrefix(limpo, "-")
+
+	inteiro, decimal := limpo, ""
+	if i := strings.LastIndexByte(limpo, ','); i >= 0 {
+		inteiro, decimal = limpo[:i], limpo[i+1:]
+	} else if i := strings.LastIndexByte(limpo, '.'); i >= 0 && len(limpo)-i-1 <= 2 {
+		inteiro, decimal = limpo[:i], limpo[i+1:]
+	}
+	inteiro = strings.ReplaceAll(inteiro, ".", "")
+	if inteiro == "" {
+		inteiro = "0"
+	}
+	if len(decimal) > 2 || !apenasDigitos(inteiro) || !apenasDigitos(decimal) {
+		return 0, fmt.Errorf("%w: '%s'", ErrPrecoInvalido, s)
+	}
+	for len(decimal) < 2 {
+		decimal += "0"
+	}
+
+	reais, err := strconv.ParseInt(inteiro, 10, 64)
+	if err != nil || reais > math.MaxInt64/100-1 {
+		return 0, fmt.Errorf("%w: '%s'", ErrPrecoInvalido, s)
+	}
+	centavos, _ := strconv.ParseInt(decimal, 10, 64)
+	valor := Money(reais*100 + centavos)
+	if valor == 0 {
+		return 0, ErrPrecoAusente
+	}
+	if negativo {
+		valor = -valor
+	}
+	return valor, nil
+}
+
+func apenasDigitos(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// MoneyFromFloat converte um valor em reais, arredondando para o centavo mais
+// próximo (meio centavo afasta do zero).
+func MoneyFromFloat(reais float64) Money {
+	return Money(math.Round(reais * 100))
+}
+
+// Centavos retorna o valor em centavos.
+func (m Money) Centavos() int64 { return int64(m) }
+
+// Float64 retorna o valor em reais, para cálculos de razão e percentual.
+func (m Money) Float64() float64 { return float64(m) / 100 }
+
+func (m Money) Add(o Money) Money { return m + o }
+
+func (m Money) Sub(o Money) Money { return m - o }
+
+// Mul multiplica por um fator (taxas, alíquotas, pesos), arredondando o
+// resultado para o centavo com meio centavo afastando do zero.
+func (m Money) Mul(fator float64) Money {
+	return Money(math.Round(float64(m) * fator))
+}
+
+// Div divide o valor em n partes iguais, arredondando meio centavo para o par
+// mais próximo (arredondamento bancário), o que não enviesa médias de muitos valores.
+func (m Money) Div(n int64) Money {
+	if n == 0 {
+		return 0
+	}
+	negativo := (m < 0) != (n < 0)
+	a, b := int64(m), n
+	if a < 0 {
+		a = -a
+	}
+	if b < 0 {
+		b = -b
+	}
+	q, r := a/b, a%b
+	if 2*r > b || (2*r == b && q%2 == 1) {
+		q++
+	}
+	if negativo {
+		q = -q
+	}
+	return Money(q)
+}
+
+// Media retorna a média dos valores (ver Div para o arredondamento) e false
+// se a lista estiver vazia.
+func Media(valores []Money) (Money, bool) {
+	if len(valores) == 0 {
+		return 0, false
+	}
+	var soma Money
+	for _, v := range valores {
+		soma += v
+	}
+	return soma.Div(int64(len(valores))), true
+}
+
+// String formata no padrão brasileiro: "R$ 1.234,56".
+func (m Money) String() string {
+	sinal := ""
+	c := int64(m)
+	if c < 0 {
+		sinal = "-"
+		c = -c
+	}
+	reais := strconv.FormatInt(c/100, 10)
+	var b strings.Builder
+	for i, d := range reais {
+		if i > 0 && (len(reais)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(d)
+	}
+	return fmt.Sprintf("R$ %s%s,%02d", sinal, b.String(), c%100)
+}
+
+// decimal formata como número com duas casas e ponto decimal ("1234.56").
+func (m Money) decimal() string {
+	c := int64(m)
+	sinal := ""
+	if c < 0 {
+		sinal = "-"
+		c = -c
+	}
+	return fmt.Sprintf("%s%d.%02d", sinal, c/100, c%100)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.decimal()), nil
+}
+
+// UnmarshalJSON aceita um número em reais (1234.56) ou um texto em qualquer
+// formato aceito por ParseMoney.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := strings.TrimSpace(string(data))
+	if s == "null" {
+		return nil
+	}
+	if strings.HasPrefix(s, "\"") {
+		texto, err := strconv.Unquote(s)
+		if err != nil {
+			return err
+		}
+		v, err := ParseMoney(texto)
+		if err != nil && !errors.Is(err, ErrPrecoAusente) {
+			return err
+		}
+		*m = v
+		return nil
+	}
+	reais, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrPrecoInvalido, s)
+	}
+	*m = MoneyFromFloat(reais)
+	return nil
+}
+
+func (m Money) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bsontype.Int64, bsoncore.AppendInt64(nil, int64(m)), nil
+}
+
+// UnmarshalBSONValue lê centavos gravados como inteiro; valores double ou
+// texto (documentos antigos) são convertidos como reais.
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	raw := bson.RawValue{Type: t, Value: data}
+	switch t {
+	case bsontype.Int64:
+		*m = Money(raw.Int64())
+	case bsontype.Int32:
+		*m = Money(raw.Int32())
+	case bsontype.Double:
+		*m = MoneyFromFloat(raw.Double())
+	case bsontype.String:
+		v, err := ParseMoney(raw.StringValue())
+		if err != nil && !errors.Is(err, ErrPrecoAusente) {
+			return err
+		}
+		*m = v
+	case bsontype.Null, bsontype.Undefined:
+		*m = 0
+	default:
+		return fmt.Errorf("tipo BSON %s não pode ser convertido em Money", t)
+	}
+	return nil
+}
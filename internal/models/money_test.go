@@ -0,0 +1,94 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestParseMoney confere os formatos de preço da FIPE e os erros de preço
+// ausente e inválido.
+func TestParseMoney(t *testing.T) {
+	casos := []struct {
+		entrada  string
+		esperado Money
+		erro     error
+	}{
+		{"R$ 1.234,56", 123456, nil},
+		{"R$ 1234,56", 123456, nil},
+		{"R$ 1.234.567,89", 123456789, nil},
+		{"\"R$ 45.990,00\"", 4599000, nil},
+		{"R$ 12.345,6", 1234560, nil},
+		{"1.234", 123400, nil},
+		{"1234.5", 123450, nil},
+		{"R$ 0,01", 1, nil},
+		{"-R$ 1.234,56", -123456, nil},
+		{"R$ -99,90", -9990, nil},
+		{"", 0, ErrPrecoAusente},
+		{"   ", 0, ErrPrecoAusente},
+		{"-", 0, ErrPrecoAusente},
+		{"R$ 0,00", 0, ErrPrecoAusente},
+		{"R$ 1.234,567", 0, ErrPrecoInvalido},
+		{"R$ 12a,00", 0, ErrPrecoInvalido},
+		{"abc", 0, ErrPrecoInvalido},
+		{"R$ 99999999999999999999,00", 0, ErrPrecoInvalido},
+	}
+	for _, c := range casos {
+		t.Run(c.entrada, func(t *testing.T) {
+			valor, err := ParseMoney(c.entrada)
+			if c.erro != nil {
+				if !errors.Is(err, c.erro) {
+					t.Fatalf("ParseMoney(%q): erro %v, esperado %v", c.entrada, err, c.erro)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseMoney(%q): %v", c.entrada, err)
+			}
+			if valor != c.esperado {
+				t.Errorf("ParseMoney(%q) = %d, esperado %d", c.entrada, valor, c.esperado)
+			}
+		})
+	}
+}
+
+// TestDiv confere o arredondamento bancário: meio centavo vai para o par mais
+// próximo, inclusive em valores negativos.
+func TestDiv(t *testing.T) {
+	casos := []struct {
+		valor    Money
+		n        int64
+		esperado Money
+	}{
+		{10, 2, 5},
+		{5, 2, 2},
+		{15, 2, 8},
+		{25, 2, 12},
+		{35, 2, 18},
+		{-5, 2, -2},
+		{-15, 2, -8},
+		{15, -2, -8},
+		{-15, -2, 8},
+		{10, 3, 3},
+		{20, 3, 7},
+		{-20, 3, -7},
+		{100, 0, 0},
+	}
+	for _, c := range casos {
+		if got := c.valor.Div(c.n); got != c.esperado {
+			t.Errorf("Money(%d).Div(%d) = %d, esperado %d", c.valor, c.n, got, c.esperado)
+		}
+	}
+}
+
+// TestMedia confere que a média usa o mesmo arredondamento de Div.
+func TestMedia(t *testing.T) {
+	if _, ok := Media(nil); ok {
+		t.Error("Media(nil): esperado false")
+	}
+	if media, ok := Media([]Money{100, 101}); !ok || media != 100 {
+		t.Errorf("Media(100, 101) = %d, %v, esperado 100", media, ok)
+	}
+	if media, ok := Media([]Money{101, 102}); !ok || media != 102 {
+		t.Errorf("Media(101, 102) = %d, %v, esperado 102", media, ok)
+	}
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// FormatoPeriodo é o formato ISO do mês de uma tabela ("2024-03"), usado em
+// TabelaReferencia.Periodo, nas respostas e no parâmetro 'tabela'.
+const FormatoPeriodo = "2006-01"
+
+// ErrPeriodoInvalido indica um período fora do formato "2024-03".
+var ErrPeriodoInvalido = errors.New("período inválido")
+
+var mesesPorNome = map[string]time.Month{
+	"janeiro": time.January, "fevereiro": time.February, "marco": time.March,
+	"abril": time.April, "maio": time.May, "junho": time.June,
+	"julho": time.July, "agosto": time.August, "setembro": time.September,
+	"outubro": time.October, "novembro": time.November, "dezembro": time.December,
+}
+
+// MesReferencia interpreta o mês de referência da FIPE ("março/2024 ") como o
+// primeiro dia do mês, em UTC.
+func MesReferencia(mes string) (time.Time, bool) {
+	partes := strings.FieldsFunc(strings.ToLower(mes), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(partes) != 2 {
+		return time.Time{}, false
+	}
+	numeroMes, ok := mesesPorNome[strings.ReplaceAll(partes[0], "ç", "c")]
+	ano, err := strconv.Atoi(partes[1])
+	if !ok || err != nil {
+		return time.Time{}, false
+	}
+	return time.Date(ano, numeroMes, 1, 0, 0, 0, 0, time.UTC), true
+}
+
+// PeriodoDoMes converte o mês de referência da FIPE para "2024-03". Devolve
+// "" se o mês não puder ser lido.
+func PeriodoDoMes(mes string) string {
+	data, ok := MesReferencia(mes)
+	if !ok {
+		return ""
+	}
+	return data.Format(FormatoPeriodo)
+}
+
+// ParsePeriodo lê um período "2024-03" como o primeiro dia do mês, em UTC.
+func ParsePeriodo(s string) (time.Time, error) {
+	data, err := time.Parse(FormatoPeriodo, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: '%s'", ErrPeriodoInvalido, s)
+	}
+	return data, nil
+}
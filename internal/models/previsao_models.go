@@ -0,0 +1,58 @@
+package models
+
+// PontoPrevisto é o preço projetado para Meses tabelas após a atual, com o
+// intervalo de confiança.
+type PontoPrevisto struct {
+	Meses     int    `json:"meses"`
+	TabelaId  int    `json:"tabelaId"`
+	Valor     Money  `json:"valor"`
+	ValorFmt  string `json:"valorFmt"`
+	Minimo    Money  `json:"minimo"`
+	MinimoFmt string `json:"minimoFmt"`
+	Maximo    Money  `json:"maximo"`
+	MaximoFmt string `json:"maximoFmt"`
+}
+
+// MetricasBacktest mede a precisão de um método nas tabelas históricas. MAE e
+// RMSE estão em reais; MAPE e Cobertura (preços reais dentro do intervalo), em
+// percentual.
+type MetricasBacktest struct {
+	Previsoes int     `json:"previsoes"`
+	MAE       float64 `json:"mae"`
+	MAPE      float64 `json:"mape"`
+	RMSE      float64 `json:"rmse"`
+	Cobertura float64 `json:"cobertura"`
+}
+
+type MetodoPrevisao struct {
+	Metodo     string            `json:"metodo"`
+	Descricao  string            `json:"descricao"`
+	Disponivel bool              `json:"disponivel"`
+	Motivo     string            `json:"motivo,omitempty"`
+	Pontos     []PontoPrevisto   `json:"pontos"`
+	Backtest   *MetricasBacktest `json:"backtest,omitempty"`
+}
+
+// PrevisaoPreco é a resposta de /api/previsao. Recomendado é o método com
+// menor MAPE no backtesting.
+type PrevisaoPreco struct {
+	TabelaId      int       `json:"tabelaId"`
+	Ref           string    `json:"ref"`
+	Periodo       string    `json:"periodo,omitempty"`
+	BrandCode     int32     `json:"brandCode"`
+	BrandName     string    `json:"brandName"`
+	ModelCode     int32     `json:"modelCode"`
+	ModelName     string    `json:"modelName"`
+	Ano           CodigoAno `json:"ano"`
+	PrecoAtual    Money     `json:"precoAtual"`
+	PrecoAtualFmt string    `json:"precoAtualFmt"`
+	Horizonte     int       `json:"horizonte"`
+	Confianca     float64   `json:"confianca"`
+	// Depreciação anual (%) entre anos-modelo vizinhos usada pelo método
+	// "depreciacao"; TaxaPadrao indica que o modelo não tinha anos suficientes.
+	DepreciacaoAnual float64          `json:"depreciacaoAnual"`
+	TaxaPadrao       bool             `json:"taxaPadrao"`
+	Historico        []PontoHistorico `json:"historico"`
+	Metodos          []MetodoPrevisao `json:"metodos"`
+	Recomendado      string           `json:"recomendado,omitempty"`
+}
@@ -0,0 +1,77 @@
+package models
+
+// TipoOcorrencia classifica um problema de qualidade encontrado em uma tabela.
+type TipoOcorrencia string
+
+const (
+	// Preço que não é vazio nem zerado, mas não pôde ser interpretado.
+	OcorrenciaPrecoInvalido TipoOcorrencia = "preco_invalido"
+	// Preço muito distante da mediana da família (mesma marca, mesmo primeiro
+	// nome de modelo e mesmo ano-modelo).
+	OcorrenciaOutlierFamilia TipoOcorrencia = "outlier_familia"
+	// Preço muito acima ou abaixo dos dois anos-modelo vizinhos do mesmo modelo.
+	OcorrenciaOutlierAnos TipoOcorrencia = "outlier_anos"
+	// Ano-modelo usado mais caro que o mesmo modelo 0km.
+	OcorrenciaAcima0km TipoOcorrencia = "acima_0km"
+	// Variação em relação à tabela anterior acima do limite.
+	OcorrenciaSaltoMensal TipoOcorrencia = "salto_mensal"
+	// Marca presente na tabela anterior e ausente nesta.
+	OcorrenciaMarcaAusente TipoOcorrencia = "marca_ausente"
+)
+
+var TiposOcorrencia = []TipoOcorrencia{
+	OcorrenciaPrecoInvalido,
+	OcorrenciaOutlierFamilia,
+	OcorrenciaOutlierAnos,
+	OcorrenciaAcima0km,
+	OcorrenciaSaltoMensal,
+	OcorrenciaMarcaAusente,
+}
+
+func (t TipoOcorrencia) Valido() bool {
+	for _, tipo := range TiposOcorrencia {
+		if t == tipo {
+			return true
+		}
+	}
+	return false
+}
+
+// LimitesQualidade são os limiares das verificações. FatorFamilia e FatorAnos
+// são razões (3 = três vezes maior ou menor); SaltoMensal é percentual.
+type LimitesQualidade struct {
+	FatorFamilia float64 `json:"fatorFamilia"`
+	MinFamilia   int     `json:"minFamilia"`
+	FatorAnos    float64 `json:"fatorAnos"`
+	SaltoMensal  float64 `json:"saltoMensal"`
+}
+
+// OcorrenciaQualidade é um problema encontrado. Referencia é o valor com que o
+// preço foi comparado (mediana da família, vizinhos, 0km ou tabela anterior)
+// e Razao, o preço dividido por ela.
+type OcorrenciaQualidade struct {
+	Tipo          TipoOcorrencia `json:"tipo"`
+	BrandCode     int32          `json:"brandCode"`
+	BrandName     string         `json:"brandName"`
+	ModelCode     int32          `json:"modelCode,omitempty"`
+	ModelName     string         `json:"modelName,omitempty"`
+	Ano           *CodigoAno     `json:"ano,omitempty"`
+	PrecoTexto    string         `json:"precoTexto,omitempty"`
+	Preco         *Money         `json:"preco,omitempty"`
+	Referencia    *Money         `json:"referencia,omitempty"`
+	ReferenciaFmt string         `json:"referenciaFmt,omitempty"`
+	Razao         float64        `json:"razao,omitempty"`
+	Detalhe       string         `json:"detalhe"`
+}
+
+type RelatorioQualidade struct {
+	TabelaId       int                    `json:"tabelaId"`
+	Ref            string                 `json:"ref"`
+	Periodo        string                 `json:"periodo,omitempty"`
+	TabelaAnterior *TabelaComparada       `json:"tabelaAnterior,omitempty"`
+	Limites        LimitesQualidade       `json:"limites"`
+	TotalEntradas  int                    `json:"totalEntradas"`
+	Sinalizadas    int                    `json:"sinalizadas"`
+	Totais         map[TipoOcorrencia]int `json:"totais"`
+	Ocorrencias    []OcorrenciaQualidade  `json:"ocorrencias"`
+}
@@ -0,0 +1,54 @@
+package models
+
+// Critérios aceitos por /api/rankings.
+const (
+	CriterioPreco            = "preco"
+	CriterioVariacao         = "variacao"
+	CriterioVariacaoAbsoluta = "variacaoAbsoluta"
+	CriterioDepreciacao      = "depreciacao"
+	CriterioVolatilidade     = "volatilidade"
+)
+
+const (
+	OrdemMaiores = "maiores"
+	OrdemMenores = "menores"
+
+	NivelModelo = "modelo"
+	NivelMarca  = "marca"
+)
+
+// ItemRanking é uma posição do ranking. Valor é a métrica do critério: preço
+// ou variação absoluta em reais, variação e volatilidade em %, depreciação
+// em % ao ano. No nível de marca, Valor e Preco são médias de Amostras
+// anos-modelo e os campos de modelo ficam vazios.
+type ItemRanking struct {
+	Posicao      int         `json:"posicao"`
+	BrandCode    int32       `json:"brandCode"`
+	BrandName    string      `json:"brandName"`
+	ModelCode    int32       `json:"modelCode,omitempty"`
+	ModelName    string      `json:"modelName,omitempty"`
+	Ano          int32       `json:"ano,omitempty"`
+	Combustivel  Combustivel `json:"combustivel,omitempty"`
+	Segmento     Segmento    `json:"segmento,omitempty"`
+	Idade        *int        `json:"idade,omitempty"`
+	Preco        Money       `json:"preco"`
+	PrecoFmt     string      `json:"precoFmt"`
+	PrecoBase    *Money      `json:"precoBase,omitempty"`
+	PrecoBaseFmt string      `json:"precoBaseFmt,omitempty"`
+	Valor        float64     `json:"valor"`
+	ValorFmt     string      `json:"valorFmt"`
+	Amostras     int         `json:"amostras,omitempty"`
+}
+
+// Ranking é a resposta de /api/rankings. TabelaBase é a tabela de comparação
+// dos critérios de variação; Tabelas é a janela usada na volatilidade.
+type Ranking struct {
+	Criterio   string           `json:"criterio"`
+	Ordem      string           `json:"ordem"`
+	Nivel      string           `json:"nivel"`
+	Tabela     TabelaComparada  `json:"tabela"`
+	TabelaBase *TabelaComparada `json:"tabelaBase,omitempty"`
+	Tabelas    int              `json:"tabelas,omitempty"`
+	Elegiveis  int              `json:"elegiveis"`
+	Itens      []ItemRanking    `json:"itens"`
+}
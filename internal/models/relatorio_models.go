@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// PontoHistorico é o preço de um ano-modelo em uma tabela de referência.
+type PontoHistorico struct {
+	TabelaId   int    `json:"tabelaId"`
+	Ref        string `json:"ref"`
+	Periodo    string `json:"periodo,omitempty"`
+	Valor      Money  `json:"-"`
+	ValorFmt   string `json:"valorFmt"`
+	Disponivel bool   `json:"disponivel"`
+}
+
+// LaudoVeiculo reúne os dados apresentados no laudo de avaliação de um ano-modelo.
+type LaudoVeiculo struct {
+	TabelaId  int
+	Ref       string
+	EmitidoEm time.Time
+	BrandName string
+	BrandCode int32
+	ModelName string
+	ModelCode int32
+	Ano       int32
+	Preco     PriceInfo
+	Historico []PontoHistorico
+
+	// Variação percentual do ano-modelo entre o primeiro e o último ponto do histórico.
+	VariacaoModelo *float64
+	// Variação percentual do valor médio 0km da marca no mesmo intervalo.
+	VariacaoMarca *float64
+	// Diferença percentual entre o preço do ano-modelo e o do mesmo modelo 0km.
+	DepreciacaoVs0km *float64
+
+	EstatisticasMarca BrandPeriodStats
+	Similares         []PriceInfo
+}
@@ -0,0 +1,60 @@
+package models
+
+// EstimativaResidual é o valor futuro estimado por um método. TaxaAnual é a
+// depreciação anual equivalente, em %; Extrapolado indica que a idade futura
+// ficou fora dos anos-modelo conhecidos.
+type EstimativaResidual struct {
+	Metodo      string  `json:"metodo"`
+	Valor       Money   `json:"valor"`
+	ValorFmt    string  `json:"valorFmt"`
+	TaxaAnual   float64 `json:"taxaAnual"`
+	Extrapolado bool    `json:"extrapolado"`
+}
+
+// OpcaoPosse é o resultado de ficar com um veículo até a data alvo.
+type OpcaoPosse struct {
+	ValorInicial     Money  `json:"valorInicial"`
+	ValorInicialFmt  string `json:"valorInicialFmt"`
+	ValorResidual    Money  `json:"valorResidual"`
+	ValorResidualFmt string `json:"valorResidualFmt"`
+	Depreciacao      Money  `json:"depreciacao"`
+	DepreciacaoFmt   string `json:"depreciacaoFmt"`
+	CustoMensal      Money  `json:"custoMensal"`
+	CustoMensalFmt   string `json:"custoMensalFmt"`
+}
+
+// ComparacaoTroca compara manter o veículo com trocá-lo agora pelo mesmo
+// modelo 0km. Desembolso é a diferença paga na troca; DepreciacaoExtra, quanto
+// a troca perde a mais (ou a menos, se negativa) até a data alvo.
+type ComparacaoTroca struct {
+	Preco0km            Money      `json:"preco0km"`
+	Preco0kmFmt         string     `json:"preco0kmFmt"`
+	Desembolso          Money      `json:"desembolso"`
+	DesembolsoFmt       string     `json:"desembolsoFmt"`
+	Novo                OpcaoPosse `json:"novo"`
+	DepreciacaoExtra    Money      `json:"depreciacaoExtra"`
+	DepreciacaoExtraFmt string     `json:"depreciacaoExtraFmt"`
+}
+
+type ValorResidual struct {
+	TabelaId      int                  `json:"tabelaId"`
+	Ref           string               `json:"ref"`
+	Periodo       string               `json:"periodo,omitempty"`
+	BrandCode     int32                `json:"brandCode"`
+	BrandName     string               `json:"brandName"`
+	ModelCode     int32                `json:"modelCode"`
+	ModelName     string               `json:"modelName"`
+	Ano           CodigoAno            `json:"ano"`
+	Segmento      Segmento             `json:"segmento"`
+	Idade         int                  `json:"idade"`
+	Meses         int                  `json:"meses"`
+	DataAlvo      string               `json:"dataAlvo,omitempty"`
+	PrecoAtual    Money                `json:"precoAtual"`
+	PrecoAtualFmt string               `json:"precoAtualFmt"`
+	Estimativas   []EstimativaResidual `json:"estimativas"`
+	Residual      Money                `json:"residual"`
+	ResidualFmt   string               `json:"residualFmt"`
+	Manter        OpcaoPosse           `json:"manter"`
+	// Troca é nulo quando o modelo não tem preço 0km na tabela.
+	Troca *ComparacaoTroca `json:"troca,omitempty"`
+}
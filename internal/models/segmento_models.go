@@ -0,0 +1,61 @@
+package models
+
+import "time"
+
+// Segmento é a categoria de carroceria/uso de um modelo, usada para agrupar
+// modelos comparáveis (um Mobi não deve entrar na média de uma Toro).
+type Segmento string
+
+const (
+	SegmentoHatch     Segmento = "hatch"
+	SegmentoSedan     Segmento = "sedan"
+	SegmentoSUV       Segmento = "suv"
+	SegmentoPicape    Segmento = "picape"
+	SegmentoVan       Segmento = "van"
+	SegmentoEsportivo Segmento = "esportivo"
+	SegmentoEletrico  Segmento = "eletrico"
+	SegmentoOutros    Segmento = "outros"
+)
+
+// Segmentos lista os segmentos conhecidos, na ordem de exibição.
+var Segmentos = []Segmento{
+	SegmentoHatch, SegmentoSedan, SegmentoSUV, SegmentoPicape,
+	SegmentoVan, SegmentoEsportivo, SegmentoEletrico, SegmentoOutros,
+}
+
+// Valido informa se s é um dos segmentos conhecidos.
+func (s Segmento) Valido() bool {
+	for _, seg := range Segmentos {
+		if s == seg {
+			return true
+		}
+	}
+	return false
+}
+
+// RegraSegmento é uma regra do arquivo de regras de segmento: o modelo recebe
+// Segmento se o nome normalizado casar com algum dos Padroes (expressões
+// regulares) e, quando Marcas for informado, a marca estiver na lista.
+type RegraSegmento struct {
+	Segmento Segmento `json:"segmento"`
+	Marcas   []string `json:"marcas,omitempty"`
+	Padroes  []string `json:"padroes"`
+}
+
+// SegmentoManual fixa o segmento de um modelo, com prioridade sobre as regras
+// (coleção SegmentosManuais).
+type SegmentoManual struct {
+	ModelCode    int32     `json:"modelCode" bson:"modelCode"`
+	Segmento     Segmento  `json:"segmento" bson:"segmento"`
+	Observacao   string    `json:"observacao,omitempty" bson:"observacao,omitempty"`
+	AtualizadoEm time.Time `json:"atualizadoEm" bson:"atualizadoEm"`
+}
+
+// DashboardSegmentoEntry compara as estatísticas 0km de um segmento, somando
+// todas as marcas, entre dois períodos.
+type DashboardSegmentoEntry struct {
+	Segmento              Segmento         `json:"segmento"`
+	Periodo1              BrandPeriodStats `json:"periodo1"`
+	Periodo2              BrandPeriodStats `json:"periodo2"`
+	DiferencasPercentuais PercentageDiffs  `json:"diferencasPercentuais"`
+}
@@ -0,0 +1,133 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// TabelaReferencia representa um documento da coleção TabelaReferencia.
+type TabelaReferencia struct {
+	Codigo int    `bson:"codigo" json:"codigo"`
+	Mes    string `bson:"mes" json:"mes"`
+	// Ano, NumeroMes e Periodo ("2024-03") são o mês interpretado, gravados na
+	// etapa pós-ingestão. Ficam vazios se o mês não puder ser lido.
+	Ano       int    `bson:"ano,omitempty" json:"ano,omitempty"`
+	NumeroMes int    `bson:"numeroMes,omitempty" json:"numeroMes,omitempty"`
+	Periodo   string `bson:"periodo,omitempty" json:"periodo,omitempty"`
+	// PublicadaEm é gravado na etapa pós-ingestão; fica vazio enquanto a
+	// tabela está sendo carregada ou antes de ser processada.
+	PublicadaEm *time.Time `bson:"publicadaEm,omitempty" json:"publicadaEm,omitempty"`
+	// Contagem é gravada na etapa pós-ingestão, para que /api/tabelas não
+	// percorra Veiculos; fica nil nas tabelas ainda não contadas.
+	Contagem *ContagemTabela `bson:"contagem,omitempty" json:"contagem,omitempty"`
+}
+
+// ContagemTabela são os totais de uma tabela em Veiculos: documentos de marca,
+// modelos e entradas de ano.
+type ContagemTabela struct {
+	Marcas     int `bson:"marcas" json:"marcas"`
+	Modelos    int `bson:"modelos" json:"modelos"`
+	AnosModelo int `bson:"anosModelo" json:"anosModelo"`
+}
+
+// Situação de uma tabela na ingestão.
+const (
+	// StatusTabelaPendente: há veículos, mas a etapa pós-ingestão ainda não
+	// rodou (carga em andamento ou tabela anterior ao catálogo de marcas).
+	StatusTabelaPendente = "pendente"
+	// StatusTabelaPublicada: a etapa pós-ingestão já rodou.
+	StatusTabelaPublicada = "publicada"
+)
+
+// TabelaResumo é uma tabela de referência com veículos, como listada em
+// /api/tabelas. Ano, NumeroMes e Periodo ficam vazios se o mês não puder ser
+// lido.
+type TabelaResumo struct {
+	Codigo      int        `json:"codigo" bson:"_id"`
+	Mes         string     `json:"mes" bson:"mes"`
+	Ano         int        `json:"ano" bson:"ano"`
+	NumeroMes   int        `json:"numeroMes" bson:"numeroMes"`
+	Periodo     string     `json:"periodo" bson:"periodo"`
+	Marcas      int        `json:"marcas" bson:"marcas"`
+	Modelos     int        `json:"modelos" bson:"modelos"`
+	AnosModelo  int        `json:"anosModelo" bson:"anosModelo"`
+	Status      string     `json:"status" bson:"-"`
+	PublicadaEm *time.Time `json:"publicadaEm,omitempty" bson:"publicadaEm,omitempty"`
+}
+
+// Marca representa um documento da coleção Veiculos: todos os modelos de uma
+// marca dentro de uma tabela de referência.
+type Marca struct {
+	MonthYearId int      `bson:"monthYearId" json:"monthYearId"`
+	BrandCode   int32    `bson:"brandCode" json:"brandCode"`
+	BrandName   string   `bson:"brandName" json:"brandName"`
+	Models      []Modelo `bson:"models" json:"models"`
+}
+
+type Modelo struct {
+	ModelCode int32       `bson:"modelCode" json:"modelCode"`
+	ModelName string      `bson:"modelName" json:"modelName"`
+	Years     []AnoModelo `bson:"years" json:"years"`
+}
+
+// AnoModelo é uma entrada de ano de um modelo. Year guarda só o ano-modelo
+// (32000 para 0km); o combustível vem do código de ano da FIPE ("2019-1"),
+// gravado em yearCode e fuel pela ingestão. Ver Detalhes. PrecoCentavos é o
+// preço já interpretado, gravado pela migração de preços (ver Valor).
+type AnoModelo struct {
+	Year          int32       `bson:"year" json:"year"`
+	Price         string      `bson:"price" json:"price"`
+	PrecoCentavos *Money      `bson:"priceCents,omitempty" json:"-"`
+	CodigoFipe    string      `bson:"fipeCode,omitempty" json:"fipeCode,omitempty"`
+	CodigoAno     string      `bson:"yearCode,omitempty" json:"yearCode,omitempty"`
+	Combustivel   Combustivel `bson:"fuel,omitempty" json:"fuel,omitempty"`
+}
+
+// Valor retorna o preço da entrada: o texto interpretado por ParseMoney ou,
+// quando o texto está num formato inválido, o valor em centavos gravado. Se
+// os dois divergem, vale o texto, já que priceCents pode ter ficado de uma
+// versão anterior do preço.
+func (a AnoModelo) Valor() (Money, error) {
+	preco, err := ParseMoney(a.Price)
+	if errors.Is(err, ErrPrecoInvalido) && a.PrecoCentavos != nil {
+		return *a.PrecoCentavos, nil
+	}
+	return preco, err
+}
+
+// Ano retorna a entrada do ano-modelo informado, se existir.
+func (m Modelo) Ano(ano int32) (AnoModelo, bool) {
+	for _, y := range m.Years {
+		if y.Year == ano {
+			return y, true
+		}
+	}
+	return AnoModelo{}, false
+}
+
+// AnoCodigo retorna a entrada do ano-modelo e combustível informados. Sem
+// combustível, equivale a Ano. Com combustível, uma entrada de outro
+// combustível nunca é escolhida; entradas antigas, sem combustível
+// conhecido, só servem quando nenhuma do ano tem o combustível pedido.
+func (m Modelo) AnoCodigo(codigo CodigoAno) (AnoModelo, bool) {
+	if codigo.Combustivel == CombustivelDesconhecido {
+		return m.Ano(codigo.AnoModelo)
+	}
+	var semCombustivel *AnoModelo
+	for i, y := range m.Years {
+		detalhes := y.Detalhes()
+		if detalhes.AnoModelo != codigo.AnoModelo {
+			continue
+		}
+		if detalhes.Combustivel == codigo.Combustivel {
+			return y, true
+		}
+		if detalhes.Combustivel == CombustivelDesconhecido && semCombustivel == nil {
+			semCombustivel = &m.Years[i]
+		}
+	}
+	if semCombustivel != nil {
+		return *semCombustivel, true
+	}
+	return AnoModelo{}, false
+}
@@ -0,0 +1,210 @@
+package previsao
+
+import (
+	"math"
+)
+
+// TendenciaLinear ajusta uma reta aos preços.
+type TendenciaLinear struct{}
+
+func (TendenciaLinear) Nome() string { return "linear" }
+
+func (TendenciaLinear) Descricao() string {
+	return "Reta ajustada por mínimos quadrados sobre o histórico"
+}
+
+func (TendenciaLinear) MinObservacoes() int { return 3 }
+
+func (TendenciaLinear) Prever(obs []Observacao, horizonte int, z float64) ([]Ponto, error) {
+	x, y := eixos(obs, false)
+	r, err := ajustar(x, y)
+	if err != nil {
+		return nil, err
+	}
+	ultimo := obs[len(obs)-1].T
+	pontos := make([]Ponto, horizonte)
+	for h := 1; h <= horizonte; h++ {
+		valor, margem := r.estimar(float64(ultimo+h), z)
+		pontos[h-1] = Ponto{H: h, Valor: math.Max(valor, 0), Minimo: math.Max(valor-margem, 0), Maximo: math.Max(valor+margem, 0)}
+	}
+	return pontos, nil
+}
+
+// TendenciaExponencial ajusta uma reta ao logaritmo dos preços, ou seja, uma
+// taxa de variação mensal constante.
+type TendenciaExponencial struct{}
+
+func (TendenciaExponencial) Nome() string { return "exponencial" }
+
+func (TendenciaExponencial) Descricao() string {
+	return "Taxa de variação mensal constante ajustada sobre o logaritmo dos preços"
+}
+
+func (TendenciaExponencial) MinObservacoes() int { return 3 }
+
+func (TendenciaExponencial) Prever(obs []Observacao, horizonte int, z float64) ([]Ponto, error) {
+	x, y := eixos(obs, true)
+	if y == nil {
+		return nil, ErrPrecoNaoPositivo
+	}
+	r, err := ajustar(x, y)
+	if err != nil {
+		return nil, err
+	}
+	ultimo := obs[len(obs)-1].T
+	pontos := make([]Ponto, horizonte)
+	for h := 1; h <= horizonte; h++ {
+		valor, margem := r.estimar(float64(ultimo+h), z)
+		pontos[h-1] = Ponto{H: h, Valor: math.Exp(valor), Minimo: math.Exp(valor - margem), Maximo: math.Exp(valor + margem)}
+	}
+	return pontos, nil
+}
+
+// Sazonal decompõe o logaritmo dos preços em tendência linear e um efeito por
+// mês do ano (a média dos resíduos da tendência em cada mês). Exige dois anos
+// de histórico para que cada mês tenha ao menos duas observações.
+type Sazonal struct{}
+
+func (Sazonal) Nome() string { return "sazonal" }
+
+func (Sazonal) Descricao() string {
+	return "Tendência exponencial com efeito sazonal por mês do ano"
+}
+
+func (Sazonal) MinObservacoes() int { return 24 }
+
+func (s Sazonal) Prever(obs []Observacao, horizonte int, z float64) ([]Ponto, error) {
+	if len(obs) < s.MinObservacoes() {
+		return nil, ErrDadosInsuficientes
+	}
+	x, y := eixos(obs, true)
+	if y == nil {
+		return nil, ErrPrecoNaoPositivo
+	}
+	tendencia, err := ajustar(x, y)
+	if err != nil {
+		return nil, err
+	}
+
+	var soma [12]float64
+	var contagem [12]int
+	for i := range x {
+		mes := fase(obs[i].T)
+		soma[mes] += y[i] - (tendencia.a + tendencia.b*x[i])
+		contagem[mes]++
+	}
+	var efeito [12]float64
+	for mes := range efeito {
+		if contagem[mes] > 0 {
+			efeito[mes] = soma[mes] / float64(contagem[mes])
+		}
+	}
+
+	// A série dessazonalizada define a tendência e o erro do intervalo.
+	ajustado := make([]float64, len(y))
+	for i := range y {
+		ajustado[i] = y[i] - efeito[fase(obs[i].T)]
+	}
+	r, err := ajustar(x, ajustado)
+	if err != nil {
+		return nil, err
+	}
+	ultimo := obs[len(obs)-1].T
+	pontos := make([]Ponto, horizonte)
+	for h := 1; h <= horizonte; h++ {
+		valor, margem := r.estimar(float64(ultimo+h), z)
+		valor += efeito[fase(ultimo+h)]
+		pontos[h-1] = Ponto{H: h, Valor: math.Exp(valor), Minimo: math.Exp(valor - margem), Maximo: math.Exp(valor + margem)}
+	}
+	return pontos, nil
+}
+
+// DepreciacaoIdade projeta o último preço com a depreciação anual observada
+// entre anos-modelo vizinhos na tabela atual: um carro um ano mais velho vale,
+// em média, (1 - TaxaAnual) do preço. A incerteza vem da dispersão das
+// variações mensais em torno dessa taxa.
+//
+// Taxas traz a taxa da curva em cada tabela, indexada por T; quando
+// informado, Prever usa a taxa da última observação, de modo que o backtesting
+// em cada origem só enxerga a curva daquela tabela. Sem Taxas, vale TaxaAnual.
+type DepreciacaoIdade struct {
+	TaxaAnual float64
+	Taxas     map[int]float64
+}
+
+func (DepreciacaoIdade) Nome() string { return "depreciacao" }
+
+func (DepreciacaoIdade) Descricao() string {
+	return "Último preço depreciado pela curva de idade dos anos-modelo vizinhos"
+}
+
+func (DepreciacaoIdade) MinObservacoes() int { return 3 }
+
+func (d DepreciacaoIdade) Prever(obs []Observacao, horizonte int, z float64) ([]Ponto, error) {
+	if len(obs) < d.MinObservacoes() {
+		return nil, ErrDadosInsuficientes
+	}
+	taxa := d.TaxaAnual
+	if d.Taxas != nil {
+		var ok bool
+		if taxa, ok = d.Taxas[obs[len(obs)-1].T]; !ok {
+			return nil, ErrTaxaInvalida
+		}
+	}
+	if taxa >= 1 {
+		return nil, ErrTaxaInvalida
+	}
+	mensal := math.Log(1-taxa) / 12
+
+	// Desvio das variações mensais observadas em relação à taxa da curva.
+	var somaQuad float64
+	n := 0
+	for i := 1; i < len(obs); i++ {
+		if obs[i].Valor <= 0 || obs[i-1].Valor <= 0 {
+			continue
+		}
+		meses := float64(obs[i].T - obs[i-1].T)
+		desvio := math.Log(obs[i].Valor/obs[i-1].Valor)/meses - mensal
+		somaQuad += desvio * desvio * meses
+		n++
+	}
+	if n == 0 {
+		return nil, ErrPrecoNaoPositivo
+	}
+	sigma := math.Sqrt(somaQuad / float64(n))
+
+	ultimo := obs[len(obs)-1]
+	if ultimo.Valor <= 0 {
+		return nil, ErrPrecoNaoPositivo
+	}
+	base := math.Log(ultimo.Valor)
+	pontos := make([]Ponto, horizonte)
+	for h := 1; h <= horizonte; h++ {
+		valor := base + mensal*float64(h)
+		margem := z * sigma * math.Sqrt(float64(h))
+		pontos[h-1] = Ponto{H: h, Valor: math.Exp(valor), Minimo: math.Exp(valor - margem), Maximo: math.Exp(valor + margem)}
+	}
+	return pontos, nil
+}
+
+// eixos separa T e valores; com logaritmo, devolve y nil se houver preço não
+// positivo.
+func eixos(obs []Observacao, logaritmo bool) ([]float64, []float64) {
+	x := make([]float64, len(obs))
+	y := make([]float64, len(obs))
+	for i, o := range obs {
+		x[i] = float64(o.T)
+		y[i] = o.Valor
+		if logaritmo {
+			if o.Valor <= 0 {
+				return x, nil
+			}
+			y[i] = math.Log(o.Valor)
+		}
+	}
+	return x, y
+}
+
+func fase(t int) int {
+	return ((t % 12) + 12) % 12
+}
@@ -0,0 +1,157 @@
+// Package previsao projeta preços FIPE para as próximas tabelas com métodos
+// simples e explicáveis (tendência linear e exponencial, sazonalidade e
+// depreciação por idade) e mede a precisão de cada um por backtesting.
+//
+// O pacote só faz contas: as séries vêm prontas de quem chama, em reais, com
+// T sendo o índice mensal da tabela (tabelas ausentes deixam lacunas em T).
+package previsao
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	// ErrDadosInsuficientes indica uma série curta demais para o método.
+	ErrDadosInsuficientes = errors.New("histórico insuficiente para o método")
+	// ErrVarianciaNula indica observações todas no mesmo T, sem eixo de
+	// tempo para ajustar a tendência.
+	ErrVarianciaNula = errors.New("variância nula: as observações são todas da mesma tabela")
+	// ErrPrecoNaoPositivo indica preço zero ou negativo numa série tratada
+	// em logaritmo.
+	ErrPrecoNaoPositivo = errors.New("há preço zero ou negativo no histórico")
+	// ErrTaxaInvalida indica uma taxa de depreciação ausente ou de 100% ou
+	// mais ao ano.
+	ErrTaxaInvalida = errors.New("taxa de depreciação inválida")
+)
+
+// Observacao é o preço de uma tabela; T cresce de 1 a cada mês.
+type Observacao struct {
+	T     int
+	Valor float64
+}
+
+// Ponto é a projeção para H meses após a última observação, com o intervalo
+// de confiança [Minimo, Maximo].
+type Ponto struct {
+	H      int
+	Valor  float64
+	Minimo float64
+	Maximo float64
+}
+
+// Metodo é um método de previsão.
+type Metodo interface {
+	Nome() string
+	Descricao() string
+	// MinObservacoes é o tamanho mínimo da série para Prever.
+	MinObservacoes() int
+	// Prever projeta os próximos horizonte meses. z é o quantil da normal
+	// para o nível de confiança do intervalo.
+	Prever(obs []Observacao, horizonte int, z float64) ([]Ponto, error)
+}
+
+// Quantis da normal para os níveis de confiança aceitos.
+var Quantis = map[float64]float64{
+	0.80: 1.2816,
+	0.90: 1.6449,
+	0.95: 1.9600,
+	0.99: 2.5758,
+}
+
+// Metricas resume o backtesting de um método: cada observação da série, a
+// partir da menor janela de treino possível, é usada como origem de uma
+// previsão, e as projeções são comparadas aos preços que vieram depois.
+type Metricas struct {
+	Previsoes int     `json:"previsoes"`
+	MAE       float64 `json:"mae"`
+	MAPE      float64 `json:"mape"`
+	RMSE      float64 `json:"rmse"`
+	// Cobertura é o percentual de preços reais dentro do intervalo previsto.
+	Cobertura float64 `json:"cobertura"`
+}
+
+// Backtest avalia o método na própria série, com origens móveis.
+func Backtest(m Metodo, obs []Observacao, horizonte int, z float64) Metricas {
+	var met Metricas
+	var somaAbs, somaPct, somaQuad float64
+	dentro := 0
+	for k := m.MinObservacoes(); k < len(obs); k++ {
+		pontos, err := m.Prever(obs[:k], horizonte, z)
+		if err != nil {
+			continue
+		}
+		origem := obs[k-1].T
+		for _, real := range obs[k:] {
+			h := real.T - origem
+			if h > horizonte {
+				break
+			}
+			p := pontos[h-1]
+			erro := p.Valor - real.Valor
+			somaAbs += math.Abs(erro)
+			somaQuad += erro * erro
+			if real.Valor != 0 {
+				somaPct += math.Abs(erro / real.Valor)
+			}
+			if real.Valor >= p.Minimo && real.Valor <= p.Maximo {
+				dentro++
+			}
+			met.Previsoes++
+		}
+	}
+	if met.Previsoes == 0 {
+		return met
+	}
+	n := float64(met.Previsoes)
+	met.MAE = somaAbs / n
+	met.MAPE = somaPct / n * 100
+	met.RMSE = math.Sqrt(somaQuad / n)
+	met.Cobertura = float64(dentro) / n * 100
+	return met
+}
+
+// regressao ajusta y = a + b·x por mínimos quadrados e devolve também o erro
+// padrão dos resíduos, a média de x e a soma dos quadrados de x centrado.
+type regressao struct {
+	a, b, sigma, mediaX, sxx float64
+	n                        int
+}
+
+func ajustar(x, y []float64) (regressao, error) {
+	n := len(x)
+	if n < 3 {
+		return regressao{}, ErrDadosInsuficientes
+	}
+	var mx, my float64
+	for i := range x {
+		mx += x[i]
+		my += y[i]
+	}
+	mx /= float64(n)
+	my /= float64(n)
+	var sxx, sxy float64
+	for i := range x {
+		sxx += (x[i] - mx) * (x[i] - mx)
+		sxy += (x[i] - mx) * (y[i] - my)
+	}
+	if sxx == 0 {
+		return regressao{}, ErrVarianciaNula
+	}
+	r := regressao{b: sxy / sxx, mediaX: mx, sxx: sxx, n: n}
+	r.a = my - r.b*mx
+	var sse float64
+	for i := range x {
+		e := y[i] - (r.a + r.b*x[i])
+		sse += e * e
+	}
+	r.sigma = math.Sqrt(sse / float64(n-2))
+	return r, nil
+}
+
+// estimar devolve o valor ajustado em x e a meia-largura do intervalo de
+// predição.
+func (r regressao) estimar(x, z float64) (float64, float64) {
+	erro := r.sigma * math.Sqrt(1+1/float64(r.n)+(x-r.mediaX)*(x-r.mediaX)/r.sxx)
+	return r.a + r.b*x, z * erro
+}
@@ -0,0 +1,138 @@
+// Package report gera os documentos impressos (PDF) a partir dos dados da FIPE.
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/go-pdf/fpdf"
+
+	"fipe_project/internal/models"
+)
+
+const (
+	larguraUtil = 190.0
+	alturaLinha = 7.0
+)
+
+// LaudoPDF escreve em w o laudo de avaliação de um veículo em formato PDF.
+func LaudoPDF(w io.Writer, laudo *models.LaudoVeiculo) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle("Laudo de Avaliação FIPE", true)
+	pdf.SetMargins(10, 12, 10)
+	pdf.SetAutoPageBreak(true, 15)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.CellFormat(0, 5, tr(fmt.Sprintf("Emitido em %s - página %d", laudo.EmitidoEm.Format("02/01/2006 15:04"), pdf.PageNo())), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(larguraUtil, 10, tr("Laudo de Avaliação - Tabela FIPE"), "", 1, "C", false, 0, "")
+	pdf.SetFont("Helvetica", "", 11)
+	pdf.CellFormat(larguraUtil, alturaLinha, tr(fmt.Sprintf("Mês de referência: %s (tabela %d)", laudo.Ref, laudo.TabelaId)), "", 1, "C", false, 0, "")
+	pdf.Ln(4)
+
+	secao(pdf, tr, "Veículo")
+	campo(pdf, tr, "Marca", laudo.BrandName)
+	campo(pdf, tr, "Modelo", fmt.Sprintf("%s (código %d)", laudo.ModelName, laudo.ModelCode))
+	campo(pdf, tr, "Ano-modelo", formatarAno(laudo.Ano))
+	pdf.SetFont("Helvetica", "B", 13)
+	pdf.CellFormat(50, 9, tr("Preço FIPE:"), "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, 9, tr(laudo.Preco.ValorFmt), "", 1, "L", false, 0, "")
+	pdf.Ln(3)
+
+	secao(pdf, tr, "Histórico dos últimos 12 meses")
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetFillColor(230, 230, 230)
+	pdf.CellFormat(80, alturaLinha, tr("Mês de referência"), "1", 0, "L", true, 0, "")
+	pdf.CellFormat(55, alturaLinha, tr("Preço"), "1", 0, "R", true, 0, "")
+	pdf.CellFormat(55, alturaLinha, tr("Variação mensal"), "1", 1, "R", true, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	var anterior *models.PontoHistorico
+	for i := range laudo.Historico {
+		ponto := laudo.Historico[i]
+		valor, variacao := "Indisponível", "-"
+		if ponto.Disponivel {
+			valor = ponto.ValorFmt
+			if anterior != nil && anterior.Disponivel && anterior.Valor != 0 {
+				variacao = formatarPercentual(((ponto.Valor.Float64() / anterior.Valor.Float64()) - 1) * 100)
+			}
+		}
+		pdf.CellFormat(80, alturaLinha, tr(ponto.Ref), "1", 0, "L", false, 0, "")
+		pdf.CellFormat(55, alturaLinha, tr(valor), "1", 0, "R", false, 0, "")
+		pdf.CellFormat(55, alturaLinha, tr(variacao), "1", 1, "R", false, 0, "")
+		anterior = &laudo.Historico[i]
+	}
+	pdf.Ln(4)
+
+	secao(pdf, tr, "Depreciação")
+	campo(pdf, tr, "Variação do modelo no período", formatarPercentualOpcional(laudo.VariacaoModelo))
+	campo(pdf, tr, "Variação média 0km da marca", formatarPercentualOpcional(laudo.VariacaoMarca))
+	if laudo.Ano != models.AnoZeroKm {
+		campo(pdf, tr, "Diferença para o modelo 0km", formatarPercentualOpcional(laudo.DepreciacaoVs0km))
+	}
+	campo(pdf, tr, "Valor médio 0km da marca", laudo.EstatisticasMarca.ValorMedio0kmFmt)
+	campo(pdf, tr, "Modelos 0km da marca", fmt.Sprintf("%d", laudo.EstatisticasMarca.TotalModelos))
+	pdf.Ln(3)
+
+	secao(pdf, tr, "Modelos similares ("+formatarAno(laudo.Ano)+")")
+	if len(laudo.Similares) == 0 {
+		pdf.SetFont("Helvetica", "I", 10)
+		pdf.CellFormat(larguraUtil, alturaLinha, tr("Nenhum modelo da marca em faixa de preço similar."), "", 1, "L", false, 0, "")
+	} else {
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(120, alturaLinha, tr("Modelo"), "1", 0, "L", true, 0, "")
+		pdf.CellFormat(35, alturaLinha, tr("Preço"), "1", 0, "R", true, 0, "")
+		pdf.CellFormat(35, alturaLinha, tr("Diferença"), "1", 1, "R", true, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		for _, s := range laudo.Similares {
+			diff := "-"
+			if laudo.Preco.Valor != 0 {
+				diff = formatarPercentual(((s.Valor.Float64() / laudo.Preco.Valor.Float64()) - 1) * 100)
+			}
+			pdf.CellFormat(120, alturaLinha, tr(s.Modelo), "1", 0, "L", false, 0, "")
+			pdf.CellFormat(35, alturaLinha, tr(s.ValorFmt), "1", 0, "R", false, 0, "")
+			pdf.CellFormat(35, alturaLinha, tr(diff), "1", 1, "R", false, 0, "")
+		}
+	}
+
+	pdf.Ln(6)
+	pdf.SetFont("Helvetica", "I", 8)
+	pdf.MultiCell(larguraUtil, 4, tr("Valores de referência da Tabela FIPE. O preço médio de mercado pode variar conforme estado de conservação, quilometragem e região."), "", "L", false)
+
+	return pdf.Output(w)
+}
+
+func secao(pdf *fpdf.Fpdf, tr func(string) string, titulo string) {
+	pdf.SetFont("Helvetica", "B", 12)
+	pdf.CellFormat(larguraUtil, 8, tr(titulo), "B", 1, "L", false, 0, "")
+	pdf.Ln(1)
+}
+
+func campo(pdf *fpdf.Fpdf, tr func(string) string, rotulo, valor string) {
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(70, alturaLinha, tr(rotulo+":"), "", 0, "L", false, 0, "")
+	pdf.CellFormat(0, alturaLinha, tr(valor), "", 1, "L", false, 0, "")
+}
+
+func formatarAno(ano int32) string {
+	if ano == models.AnoZeroKm {
+		return "0km"
+	}
+	return fmt.Sprintf("%d", ano)
+}
+
+func formatarPercentual(v float64) string {
+	return fmt.Sprintf("%+.2f%%", v)
+}
+
+func formatarPercentualOpcional(v *float64) string {
+	if v == nil {
+		return "N/A"
+	}
+	return formatarPercentual(*v)
+}
@@ -1,25 +1,67 @@
 package routes
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 
+	"fipe_project/internal/config"
 	projecthandlers "fipe_project/internal/handlers"
 )
 
-func SetupRoutes() http.Handler {
+func SetupRoutes(cfg config.Config) http.Handler {
 	router := mux.NewRouter()
 
 	apiRouter := router.PathPrefix("/api").Subrouter()
+	exigirToken := projecthandlers.ExigirToken(cfg.AdminToken)
+	if cfg.AdminToken == "" {
+		log.Printf("ATENÇÃO: ADMIN_TOKEN não definido; /api/admin e /api/watchlist responderão 503")
+	}
 
 	apiRouter.HandleFunc("/tabelas", projecthandlers.GetTabelasReferencia).Methods("GET", "OPTIONS")
 	apiRouter.HandleFunc("/marcas", projecthandlers.GetMarcas).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/marcas/{marca}", projecthandlers.GetMarca).Methods("GET", "OPTIONS")
 	apiRouter.HandleFunc("/modelos/{marca}", projecthandlers.GetModelos).Methods("GET", "OPTIONS")
 	apiRouter.HandleFunc("/veiculos", projecthandlers.GetVeiculos).Methods("GET", "OPTIONS")
 	apiRouter.HandleFunc("/dashboard", projecthandlers.GetDashboardMarcas).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/dashboard/segmentos", projecthandlers.GetDashboardSegmentos).Methods("GET", "OPTIONS")
 	apiRouter.HandleFunc("/0km", projecthandlers.GetVeiculosNovos).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/comparar", projecthandlers.GetComparar).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/ciclo-vida", projecthandlers.GetCicloVida).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/rankings", projecthandlers.GetRankings).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/previsao", projecthandlers.GetPrevisao).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/qualidade", projecthandlers.GetQualidade).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/indice", projecthandlers.GetIndice).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/valor-residual", projecthandlers.GetValorResidual).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/ipva", projecthandlers.GetIPVA).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/financiamento/simulacao", projecthandlers.PostSimulacaoFinanciamento).Methods("POST", "OPTIONS")
+	apiRouter.HandleFunc("/custo-total", projecthandlers.GetCustoTotal).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/relatorio/veiculo", projecthandlers.GetRelatorioVeiculo).Methods("GET", "OPTIONS")
+	apiRouter.HandleFunc("/avaliacao/lote", projecthandlers.PostAvaliacaoLote).Methods("POST", "OPTIONS")
+	apiRouter.HandleFunc("/avaliacao/lote/{id}", projecthandlers.GetAvaliacaoLote).Methods("GET", "OPTIONS")
+
+	apiRouter.Handle("/watchlist", exigirToken(http.HandlerFunc(projecthandlers.GetWatchlist))).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/watchlist", exigirToken(http.HandlerFunc(projecthandlers.PostWatchlist))).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/watchlist/entregas", exigirToken(http.HandlerFunc(projecthandlers.GetEntregasWatchlist))).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/watchlist/avaliar", exigirToken(http.HandlerFunc(projecthandlers.PostAvaliarWatchlist))).Methods("POST", "OPTIONS")
+	apiRouter.Handle("/watchlist/{id}", exigirToken(http.HandlerFunc(projecthandlers.GetItemWatchlist))).Methods("GET", "OPTIONS")
+	apiRouter.Handle("/watchlist/{id}", exigirToken(http.HandlerFunc(projecthandlers.PutItemWatchlist))).Methods("PUT", "OPTIONS")
+	apiRouter.Handle("/watchlist/{id}", exigirToken(http.HandlerFunc(projecthandlers.DeleteItemWatchlist))).Methods("DELETE", "OPTIONS")
+	apiRouter.Handle("/watchlist/{id}/teste", exigirToken(http.HandlerFunc(projecthandlers.PostTesteWatchlist))).Methods("POST", "OPTIONS")
+
+	apiRouter.HandleFunc("/segmentos", projecthandlers.GetSegmentos).Methods("GET", "OPTIONS")
+
+	apiRouter.HandleFunc("/eventos", projecthandlers.GetEventos).Methods("GET", "OPTIONS")
+
+	adminRouter := apiRouter.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(exigirToken)
+	adminRouter.HandleFunc("/jobs", projecthandlers.GetJobs(cfg.FusoAgendador)).Methods("GET", "OPTIONS")
+	adminRouter.HandleFunc("/jobs/execucoes", projecthandlers.GetExecucoesJobs).Methods("GET", "OPTIONS")
+	adminRouter.HandleFunc("/jobs/{nome}/executar", projecthandlers.PostExecutarJob).Methods("POST", "OPTIONS")
+	adminRouter.HandleFunc("/segmentos/manuais/{modelo}", projecthandlers.PutSegmentoManual).Methods("PUT", "OPTIONS")
+	adminRouter.HandleFunc("/segmentos/manuais/{modelo}", projecthandlers.DeleteSegmentoManual).Methods("DELETE", "OPTIONS")
 
 	staticFileServer := http.FileServer(http.Dir("./frontend/"))
 	router.PathPrefix("/").Handler(staticFileServer)
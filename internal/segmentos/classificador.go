@@ -0,0 +1,179 @@
+// Package segmentos classifica os modelos em segmentos (hatch, sedan, SUV,
+// picape...) a partir de um arquivo de regras editável e de overrides
+// manuais gravados no Mongo.
+package segmentos
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"fipe_project/internal/models"
+	"fipe_project/internal/utils"
+)
+
+// ArquivoRegrasPadrao é usado quando a variável SEGMENTOS_REGRAS não está definida.
+const ArquivoRegrasPadrao = "./config/segmentos.json"
+
+// Intervalo máximo entre recargas dos overrides manuais, para que alterações
+// feitas por outra instância do servidor sejam vistas.
+const validadeCache = time.Minute
+
+type arquivoRegras struct {
+	Regras []models.RegraSegmento `json:"regras"`
+}
+
+type regraCompilada struct {
+	segmento models.Segmento
+	marcas   map[string]bool
+	padroes  []*regexp.Regexp
+}
+
+// Classificador atribui segmentos a modelos. É imutável depois de carregado e
+// pode ser usado por várias goroutines.
+type Classificador struct {
+	regras  []models.RegraSegmento
+	regex   []regraCompilada
+	manuais map[int32]models.Segmento
+}
+
+// NovoClassificador compila as regras informadas. Os padrões são aplicados ao
+// nome do modelo normalizado por utils.NormalizarTexto.
+func NovoClassificador(regras []models.RegraSegmento, manuais []models.SegmentoManual) (*Classificador, error) {
+	c := &Classificador{regras: regras, manuais: make(map[int32]models.Segmento, len(manuais))}
+	for i, regra := range regras {
+		if !regra.Segmento.Valido() {
+			return nil, fmt.Errorf("regra %d: segmento desconhecido '%s'", i+1, regra.Segmento)
+		}
+		compilada := regraCompilada{segmento: regra.Segmento}
+		if len(regra.Marcas) > 0 {
+			compilada.marcas = make(map[string]bool, len(regra.Marcas))
+			for _, m := range regra.Marcas {
+				compilada.marcas[utils.NormalizarTexto(m)] = true
+			}
+		}
+		for _, p := range regra.Padroes {
+			re, err := regexp.Compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("regra %d (%s): padrão inválido '%s': %v", i+1, regra.Segmento, p, err)
+			}
+			compilada.padroes = append(compilada.padroes, re)
+		}
+		c.regex = append(c.regex, compilada)
+	}
+	for _, m := range manuais {
+		c.manuais[m.ModelCode] = m.Segmento
+	}
+	return c, nil
+}
+
+// Classificar retorna o segmento do modelo: o override manual, se houver, ou o
+// da primeira regra que casar. Sem regra, o modelo fica em SegmentoOutros.
+func (c *Classificador) Classificar(brandName, modelName string, modelCode int32) models.Segmento {
+	if s, ok := c.manuais[modelCode]; ok {
+		return s
+	}
+	marca := utils.NormalizarTexto(brandName)
+	nome := utils.NormalizarTexto(modelName)
+	for _, regra := range c.regex {
+		if regra.marcas != nil && !regra.marcas[marca] {
+			continue
+		}
+		for _, re := range regra.padroes {
+			if re.MatchString(nome) {
+				return regra.segmento
+			}
+		}
+	}
+	return models.SegmentoOutros
+}
+
+// Regras retorna as regras carregadas, na ordem de avaliação.
+func (c *Classificador) Regras() []models.RegraSegmento {
+	return c.regras
+}
+
+var (
+	mu             sync.Mutex
+	atual          *Classificador
+	carregadoEm    time.Time
+	modArquivo     time.Time
+	caminhoArquivo = ArquivoRegrasPadrao
+)
+
+func init() {
+	if caminho := os.Getenv("SEGMENTOS_REGRAS"); caminho != "" {
+		caminhoArquivo = caminho
+	}
+}
+
+// Atual retorna o classificador em uso, recarregando o arquivo de regras
+// quando ele muda e os overrides manuais a cada minuto. Se a recarga falhar e
+// já houver um classificador, ele continua em uso e o erro é apenas logado.
+func Atual(ctx context.Context) (*Classificador, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	info, errStat := os.Stat(caminhoArquivo)
+	arquivoMudou := errStat == nil && !info.ModTime().Equal(modArquivo)
+	if atual != nil && !arquivoMudou && time.Since(carregadoEm) < validadeCache {
+		return atual, nil
+	}
+
+	c, err := carregar(ctx)
+	if err != nil {
+		if atual != nil {
+			log.Printf("Erro ao recarregar segmentos, mantendo regras anteriores: %v", err)
+			carregadoEm = time.Now()
+			return atual, nil
+		}
+		return nil, err
+	}
+	atual, carregadoEm = c, time.Now()
+	if errStat == nil {
+		modArquivo = info.ModTime()
+	}
+	return atual, nil
+}
+
+// Invalidar força a recarga na próxima chamada de Atual.
+func Invalidar() {
+	mu.Lock()
+	defer mu.Unlock()
+	carregadoEm = time.Time{}
+}
+
+func carregar(ctx context.Context) (*Classificador, error) {
+	regras, err := lerArquivo(caminhoArquivo)
+	if err != nil {
+		return nil, err
+	}
+	manuais, err := ListarManuais(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NovoClassificador(regras, manuais)
+}
+
+// lerArquivo lê as regras do arquivo. Um arquivo ausente não é erro: todos os
+// modelos sem override ficam em SegmentoOutros.
+func lerArquivo(caminho string) ([]models.RegraSegmento, error) {
+	dados, err := os.ReadFile(caminho)
+	if os.IsNotExist(err) {
+		log.Printf("Arquivo de regras de segmento %s não encontrado; usando apenas overrides manuais", caminho)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler regras de segmento %s: %v", caminho, err)
+	}
+	var arquivo arquivoRegras
+	if err := json.Unmarshal(dados, &arquivo); err != nil {
+		return nil, fmt.Errorf("erro ao interpretar regras de segmento %s: %v", caminho, err)
+	}
+	return arquivo.Regras, nil
+}
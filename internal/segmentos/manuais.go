@@ -0,0 +1,63 @@
+package segmentos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+)
+
+const colecaoManuais = "SegmentosManuais"
+
+var (
+	// ErrSegmentoInvalido indica um segmento fora da lista models.Segmentos.
+	ErrSegmentoInvalido = errors.New("segmento inválido")
+	// ErrManualNaoEncontrado indica que o modelo não tem override manual.
+	ErrManualNaoEncontrado = errors.New("override de segmento não encontrado")
+)
+
+func ListarManuais(ctx context.Context) ([]models.SegmentoManual, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "modelCode", Value: 1}})
+	cursor, err := database.DB.Collection(colecaoManuais).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar segmentos manuais: %v", err)
+	}
+	manuais := []models.SegmentoManual{}
+	if err := cursor.All(ctx, &manuais); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar segmentos manuais: %v", err)
+	}
+	return manuais, nil
+}
+
+// DefinirManual grava (ou substitui) o segmento fixo de um modelo.
+func DefinirManual(ctx context.Context, manual *models.SegmentoManual) error {
+	if !manual.Segmento.Valido() {
+		return fmt.Errorf("%w: '%s'", ErrSegmentoInvalido, manual.Segmento)
+	}
+	manual.AtualizadoEm = time.Now()
+	opts := options.Replace().SetUpsert(true)
+	_, err := database.DB.Collection(colecaoManuais).ReplaceOne(ctx, bson.M{"modelCode": manual.ModelCode}, manual, opts)
+	if err != nil {
+		return fmt.Errorf("erro ao gravar segmento do modelo %d: %v", manual.ModelCode, err)
+	}
+	Invalidar()
+	return nil
+}
+
+func RemoverManual(ctx context.Context, modelCode int32) error {
+	res, err := database.DB.Collection(colecaoManuais).DeleteOne(ctx, bson.M{"modelCode": modelCode})
+	if err != nil {
+		return fmt.Errorf("erro ao remover segmento do modelo %d: %v", modelCode, err)
+	}
+	if res.DeletedCount == 0 {
+		return ErrManualNaoEncontrado
+	}
+	Invalidar()
+	return nil
+}
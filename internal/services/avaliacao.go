@@ -0,0 +1,309 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+	"fipe_project/internal/utils"
+)
+
+const (
+	// LimiteLoteSincrono é o tamanho máximo de lote avaliado na própria requisição;
+	// lotes maiores viram jobs assíncronos.
+	LimiteLoteSincrono = 200
+	// LimiteLote é o tamanho máximo aceito para um lote.
+	LimiteLote = 20000
+
+	confiancaMinimaMarca  = 0.6
+	confiancaMinimaModelo = 0.5
+)
+
+// AvaliarLote resolve cada veículo do lote contra a tabela informada e
+// devolve o preço por linha, a confiança da correspondência e os totais da frota.
+func AvaliarLote(ctx context.Context, tabelaId int, itens []models.ItemAvaliacao) (*models.ResultadoAvaliacaoLote, error) {
+	ref, err := TabelaRef(ctx, tabelaId)
+	if err != nil {
+		return nil, err
+	}
+	marcas, err := CarregarTabela(ctx, tabelaId)
+	if err != nil {
+		return nil, err
+	}
+	if len(marcas) == 0 {
+		return nil, ErrNaoEncontrado
+	}
+	indice := novoIndiceAvaliacao(marcas)
+
+	resultado := &models.ResultadoAvaliacaoLote{
+		TabelaId: tabelaId,
+		Ref:      ref,
+		Periodo:  models.PeriodoDoMes(ref),
+		Itens:    make([]models.ResultadoItemAvaliacao, 0, len(itens)),
+	}
+	for i, item := range itens {
+		res := indice.avaliar(item)
+		res.Linha = i + 1
+		resultado.Itens = append(resultado.Itens, res)
+
+		resultado.Totais.Veiculos++
+		if res.Erro != "" {
+			resultado.Totais.Erros++
+			continue
+		}
+		resultado.Totais.Avaliados++
+		resultado.Totais.ValorTotal = resultado.Totais.ValorTotal.Add(res.Valor)
+	}
+
+	resultado.Totais.ValorTotalFmt = resultado.Totais.ValorTotal.String()
+	if resultado.Totais.Avaliados > 0 {
+		resultado.Totais.ValorMedioFmt = resultado.Totais.ValorTotal.Div(int64(resultado.Totais.Avaliados)).String()
+	} else {
+		resultado.Totais.ValorMedioFmt = "N/A"
+	}
+	return resultado, nil
+}
+
+// CriarJobAvaliacao persiste um lote para processamento assíncrono.
+func CriarJobAvaliacao(ctx context.Context, tabelaId int, itens []models.ItemAvaliacao) (*models.JobAvaliacaoLote, error) {
+	job := &models.JobAvaliacaoLote{
+		Id:       primitive.NewObjectID(),
+		Status:   models.StatusJobPendente,
+		TabelaId: tabelaId,
+		Total:    len(itens),
+		Itens:    itens,
+		CriadoEm: time.Now(),
+	}
+	if _, err := database.DB.Collection("AvaliacaoLote").InsertOne(ctx, job); err != nil {
+		return nil, fmt.Errorf("erro ao criar job de avaliação: %v", err)
+	}
+	return job, nil
+}
+
+// DescartarJobAvaliacao remove um job ainda pendente. Usado quando o job foi
+// gravado mas não pôde ser enfileirado, para não deixar um lote pendente que
+// nenhum worker vai processar.
+func DescartarJobAvaliacao(ctx context.Context, id primitive.ObjectID) error {
+	_, err := database.DB.Collection("AvaliacaoLote").DeleteOne(ctx, bson.M{"_id": id, "status": models.StatusJobPendente})
+	if err != nil {
+		return fmt.Errorf("erro ao descartar job de avaliação %s: %v", id.Hex(), err)
+	}
+	return nil
+}
+
+// BuscarJobAvaliacao retorna o estado (e, se concluído, o resultado) de um job.
+func BuscarJobAvaliacao(ctx context.Context, id primitive.ObjectID) (*models.JobAvaliacaoLote, error) {
+	var job models.JobAvaliacaoLote
+	err := database.DB.Collection("AvaliacaoLote").FindOne(ctx, bson.M{"_id": id}).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNaoEncontrado
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar job de avaliação %s: %v", id.Hex(), err)
+	}
+	return &job, nil
+}
+
+// ProcessarJobAvaliacao avalia o lote de um job e grava o resultado. Roda no
+// worker, que garante uma execução por vez; um job deixado em processamento
+// por um worker interrompido é retomado. Erros da avaliação ficam gravados no
+// job, e apenas falhas ao acessar o banco são devolvidas. Um job já
+// finalizado é ignorado.
+func ProcessarJobAvaliacao(ctx context.Context, id primitive.ObjectID) error {
+	coll := database.DB.Collection("AvaliacaoLote")
+	var job models.JobAvaliacaoLote
+	err := coll.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": bson.M{"$in": bson.A{models.StatusJobPendente, models.StatusJobProcessando}}},
+		bson.M{"$set": bson.M{"status": models.StatusJobProcessando}},
+	).Decode(&job)
+	if err == mongo.ErrNoDocuments {
+		log.Printf("Job de avaliação %s inexistente ou já finalizado", id.Hex())
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao iniciar job de avaliação %s: %v", id.Hex(), err)
+	}
+
+	resultado, errAval := AvaliarLote(ctx, job.TabelaId, job.Itens)
+	agora := time.Now()
+	set := bson.M{"concluidoEm": agora}
+	if errAval != nil {
+		set["status"] = models.StatusJobErro
+		set["erro"] = errAval.Error()
+	} else {
+		set["status"] = models.StatusJobConcluido
+		set["resultado"] = resultado
+	}
+	if _, err := coll.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set}); err != nil {
+		return fmt.Errorf("erro ao gravar resultado do job %s: %v", id.Hex(), err)
+	}
+	log.Printf("Job de avaliação %s finalizado (%d veículos, erro: %v)", id.Hex(), job.Total, errAval)
+	return nil
+}
+
+type refModelo struct {
+	marca  *models.Marca
+	modelo *models.Modelo
+}
+
+// indiceAvaliacao guarda a tabela carregada em memória e um índice por código
+// FIPE, para resolver todas as linhas do lote sem novas consultas.
+type indiceAvaliacao struct {
+	marcas        []models.Marca
+	porCodigoFipe map[string][]refModelo
+}
+
+func novoIndiceAvaliacao(marcas []models.Marca) *indiceAvaliacao {
+	idx := &indiceAvaliacao{marcas: marcas, porCodigoFipe: make(map[string][]refModelo)}
+	for i := range marcas {
+		for j := range marcas[i].Models {
+			modelo := &marcas[i].Models[j]
+			vistos := make(map[string]bool)
+			for _, y := range modelo.Years {
+				codigo := normalizarCodigoFipe(y.CodigoFipe)
+				if codigo == "" || vistos[codigo] {
+					continue
+				}
+				vistos[codigo] = true
+				idx.porCodigoFipe[codigo] = append(idx.porCodigoFipe[codigo], refModelo{&marcas[i], modelo})
+			}
+		}
+	}
+	return idx
+}
+
+func (idx *indiceAvaliacao) avaliar(item models.ItemAvaliacao) models.ResultadoItemAvaliacao {
+	res := models.ResultadoItemAvaliacao{Entrada: item}
+
+	if strings.TrimSpace(item.Ano) == "" {
+		res.Erro = "ano não informado"
+		return res
+	}
+	ano, err := models.ParseAnoConsulta(item.Ano)
+	if err != nil {
+		res.Erro = err.Error()
+		return res
+	}
+	res.Ano = ano.AnoModelo
+
+	var ref refModelo
+	var confianca float64
+	switch {
+	case strings.TrimSpace(item.CodigoFipe) != "":
+		ref, confianca, err = idx.porCodigo(item.CodigoFipe, ano)
+	case strings.TrimSpace(item.Marca) != "" && strings.TrimSpace(item.Modelo) != "":
+		ref, confianca, err = idx.porNome(item.Marca, item.Modelo, ano)
+	default:
+		err = fmt.Errorf("informe 'codigoFipe' ou 'marca' e 'modelo'")
+	}
+	if err != nil {
+		res.Erro = err.Error()
+		return res
+	}
+
+	res.BrandName, res.BrandCode = ref.marca.BrandName, ref.marca.BrandCode
+	res.ModelName, res.ModelCode = ref.modelo.ModelName, ref.modelo.ModelCode
+	res.Confianca = math.Round(confianca*100) / 100
+
+	anoModelo, ok := ref.modelo.AnoCodigo(ano)
+	if !ok {
+		res.Erro = fmt.Sprintf("ano %s não encontrado para o modelo", item.Ano)
+		return res
+	}
+	res.CodigoFipe = anoModelo.CodigoFipe
+	price, err := anoModelo.Valor()
+	if err != nil {
+		res.Erro = fmt.Sprintf("preço indisponível: %v", err)
+		return res
+	}
+	res.Valor = price
+	res.ValorFmt = price.String()
+	return res
+}
+
+func (idx *indiceAvaliacao) porCodigo(codigoFipe string, ano models.CodigoAno) (refModelo, float64, error) {
+	candidatos := idx.porCodigoFipe[normalizarCodigoFipe(codigoFipe)]
+	if len(candidatos) == 0 {
+		return refModelo{}, 0, fmt.Errorf("código FIPE %s não encontrado", codigoFipe)
+	}
+	// Um mesmo código FIPE pode aparecer em mais de um modelo; prefere o que tem o ano (e o combustível) pedido.
+	for _, c := range candidatos {
+		if _, ok := c.modelo.AnoCodigo(ano); ok {
+			return c, 1, nil
+		}
+	}
+	return candidatos[0], 1, nil
+}
+
+func (idx *indiceAvaliacao) porNome(marcaParam, modeloParam string, ano models.CodigoAno) (refModelo, float64, error) {
+	var marca *models.Marca
+	confiancaMarca := 0.0
+	if code, err := strconv.Atoi(strings.TrimSpace(marcaParam)); err == nil {
+		for i := range idx.marcas {
+			if idx.marcas[i].BrandCode == int32(code) {
+				marca, confiancaMarca = &idx.marcas[i], 1
+				break
+			}
+		}
+	} else {
+		for i := range idx.marcas {
+			if s := similaridadeMarca(marcaParam, idx.marcas[i].BrandName); s > confiancaMarca {
+				marca, confiancaMarca = &idx.marcas[i], s
+			}
+		}
+	}
+	if marca == nil || confiancaMarca < confiancaMinimaMarca {
+		return refModelo{}, 0, fmt.Errorf("marca '%s' não encontrada", marcaParam)
+	}
+
+	var modelo *models.Modelo
+	confiancaModelo := 0.0
+	if code, err := strconv.Atoi(strings.TrimSpace(modeloParam)); err == nil {
+		for j := range marca.Models {
+			if marca.Models[j].ModelCode == int32(code) {
+				modelo, confiancaModelo = &marca.Models[j], 1
+				break
+			}
+		}
+	} else {
+		// Só concorrem modelos que têm o ano (e o combustível) pedido, para não casar com versões de outra geração.
+		for j := range marca.Models {
+			if _, ok := marca.Models[j].AnoCodigo(ano); !ok {
+				continue
+			}
+			if s := utils.Similaridade(modeloParam, marca.Models[j].ModelName); s > confiancaModelo {
+				modelo, confiancaModelo = &marca.Models[j], s
+			}
+		}
+	}
+	if modelo == nil || confiancaModelo < confiancaMinimaModelo {
+		return refModelo{}, 0, fmt.Errorf("modelo '%s' não encontrado na marca %s", modeloParam, marca.BrandName)
+	}
+	return refModelo{marca, modelo}, confiancaMarca * confiancaModelo, nil
+}
+
+// similaridadeMarca compara também com cada parte do nome, pois a FIPE usa
+// nomes compostos como "VW - VolksWagen" e "GM - Chevrolet".
+func similaridadeMarca(entrada, brandName string) float64 {
+	melhor := utils.Similaridade(entrada, brandName)
+	for _, parte := range strings.Split(brandName, " - ") {
+		if s := utils.Similaridade(entrada, parte); s > melhor {
+			melhor = s
+		}
+	}
+	return melhor
+}
+
+func normalizarCodigoFipe(codigo string) string {
+	return strings.ReplaceAll(strings.TrimSpace(codigo), "-", "")
+}
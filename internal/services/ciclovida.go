@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"fipe_project/internal/models"
+	"fipe_project/internal/utils"
+)
+
+const (
+	MaxTabelasCicloVida = 13
+	// Similaridade mínima entre os nomes para que um modelo descontinuado e um
+	// lançado com o mesmo código FIPE sejam tratados como renomeação.
+	limiarRenomeacao = 0.5
+)
+
+// ErrCicloVidaInvalido indica parâmetros fora dos limites em CicloVidaModelos.
+var ErrCicloVidaInvalido = errors.New("consulta de ciclo de vida inválida")
+
+// CicloVidaModelos compara o catálogo de modelos entre tabelas consecutivas
+// (ordenadas pelo código, que é cronológico) e lista, por marca, os modelos
+// lançados, descontinuados, renomeados e os anos-modelo novos. Se marca for
+// informada, só ela é comparada.
+func CicloVidaModelos(ctx context.Context, tabelaIds []int, marca *int32) (*models.CicloVida, error) {
+	ids := append([]int(nil), tabelaIds...)
+	sort.Ints(ids)
+	distintos := ids[:0]
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			distintos = append(distintos, id)
+		}
+	}
+	ids = distintos
+	if len(ids) < 2 {
+		return nil, fmt.Errorf("%w: informe ao menos duas tabelas diferentes", ErrCicloVidaInvalido)
+	}
+	if len(ids) > MaxTabelasCicloVida {
+		return nil, fmt.Errorf("%w: no máximo %d tabelas", ErrCicloVidaInvalido, MaxTabelasCicloVida)
+	}
+
+	carregar := func(tabelaId int) (models.TabelaComparada, []models.Marca, error) {
+		ref, err := TabelaRef(ctx, tabelaId)
+		if err != nil {
+			return models.TabelaComparada{}, nil, err
+		}
+		tabela := models.TabelaComparada{TabelaId: tabelaId, Ref: ref, Periodo: models.PeriodoDoMes(ref)}
+		if marca == nil {
+			marcas, err := CarregarTabela(ctx, tabelaId)
+			return tabela, marcas, err
+		}
+		m, err := BuscarMarca(ctx, tabelaId, *marca)
+		if errors.Is(err, ErrNaoEncontrado) {
+			return tabela, nil, nil
+		}
+		if err != nil {
+			return tabela, nil, err
+		}
+		return tabela, []models.Marca{*m}, nil
+	}
+
+	// Só duas tabelas ficam em memória por vez.
+	anterior, marcasAnterior, err := carregar(ids[0])
+	if err != nil {
+		return nil, err
+	}
+	ciclo := &models.CicloVida{}
+	for _, id := range ids[1:] {
+		atual, marcasAtual, err := carregar(id)
+		if err != nil {
+			return nil, err
+		}
+		ciclo.Periodos = append(ciclo.Periodos, compararCatalogos(anterior, atual, marcasAnterior, marcasAtual))
+		anterior, marcasAnterior = atual, marcasAtual
+	}
+	return ciclo, nil
+}
+
+func compararCatalogos(tabelaAnterior, tabelaAtual models.TabelaComparada, anteriores, atuais []models.Marca) models.CicloVidaPeriodo {
+	periodo := models.CicloVidaPeriodo{TabelaAnterior: tabelaAnterior, TabelaAtual: tabelaAtual, Marcas: []models.CicloVidaMarca{}}
+
+	porCodigo := make(map[int32]*models.Marca, len(anteriores))
+	for i := range anteriores {
+		porCodigo[anteriores[i].BrandCode] = &anteriores[i]
+	}
+	vistas := make(map[int32]bool, len(atuais))
+	var pares [][2]*models.Marca
+	for i := range atuais {
+		vistas[atuais[i].BrandCode] = true
+		pares = append(pares, [2]*models.Marca{porCodigo[atuais[i].BrandCode], &atuais[i]})
+	}
+	for i := range anteriores {
+		if !vistas[anteriores[i].BrandCode] {
+			pares = append(pares, [2]*models.Marca{&anteriores[i], nil})
+		}
+	}
+
+	for _, par := range pares {
+		m := compararMarca(par[0], par[1])
+		if len(m.Lancamentos)+len(m.Descontinuados)+len(m.Renomeados)+len(m.NovosAnos) == 0 {
+			continue
+		}
+		periodo.Totais.Lancamentos += len(m.Lancamentos)
+		periodo.Totais.Descontinuados += len(m.Descontinuados)
+		periodo.Totais.Renomeados += len(m.Renomeados)
+		periodo.Totais.NovosAnos += len(m.NovosAnos)
+		periodo.Marcas = append(periodo.Marcas, m)
+	}
+	sort.Slice(periodo.Marcas, func(i, j int) bool { return periodo.Marcas[i].BrandName < periodo.Marcas[j].BrandName })
+	return periodo
+}
+
+// compararMarca compara os modelos de uma marca entre duas tabelas. Qualquer
+// um dos lados pode ser nil (marca nova ou removida).
+func compararMarca(anterior, atual *models.Marca) models.CicloVidaMarca {
+	resultado := models.CicloVidaMarca{
+		Lancamentos:    []models.ModeloCatalogo{},
+		Descontinuados: []models.ModeloCatalogo{},
+		Renomeados:     []models.RenomeacaoModelo{},
+		NovosAnos:      []models.NovosAnosModelo{},
+	}
+	var modelosAnteriores, modelosAtuais []models.Modelo
+	if anterior != nil {
+		resultado.BrandCode, resultado.BrandName = anterior.BrandCode, anterior.BrandName
+		modelosAnteriores = anterior.Models
+	}
+	if atual != nil {
+		resultado.BrandCode, resultado.BrandName = atual.BrandCode, atual.BrandName
+		modelosAtuais = atual.Models
+	}
+
+	porCodigo := make(map[int32]models.Modelo, len(modelosAnteriores))
+	for _, m := range modelosAnteriores {
+		porCodigo[m.ModelCode] = m
+	}
+	presentes := make(map[int32]bool, len(modelosAtuais))
+	var lancados []models.Modelo
+	for _, m := range modelosAtuais {
+		presentes[m.ModelCode] = true
+		antigo, ok := porCodigo[m.ModelCode]
+		if !ok {
+			lancados = append(lancados, m)
+			continue
+		}
+		if utils.NormalizarTexto(antigo.ModelName) != utils.NormalizarTexto(m.ModelName) {
+			resultado.Renomeados = append(resultado.Renomeados, models.RenomeacaoModelo{
+				Anterior:     catalogo(antigo),
+				Atual:        catalogo(m),
+				Similaridade: utils.Similaridade(antigo.ModelName, m.ModelName),
+			})
+		}
+		adicionarNovosAnos(&resultado, antigo, m)
+	}
+	var descontinuados []models.Modelo
+	for _, m := range modelosAnteriores {
+		if !presentes[m.ModelCode] {
+			descontinuados = append(descontinuados, m)
+		}
+	}
+
+	// Um modelo lançado que compartilha código FIPE com um descontinuado e
+	// tem nome parecido é a mesma versão com outro código de modelo. Depende
+	// do fipeCode nas entradas de ano, que a ingestão atual não grava (a
+	// migração codigos_fipe só copia códigos já existentes entre tabelas):
+	// sem ele, a troca de código aparece como lançamento e descontinuação.
+	usados := make(map[int32]bool)
+	for _, novo := range lancados {
+		codigosNovo := codigosFipe(novo)
+		var melhor *models.Modelo
+		var melhorCodigo string
+		melhorSimilaridade := limiarRenomeacao
+		for i, antigo := range descontinuados {
+			if usados[antigo.ModelCode] {
+				continue
+			}
+			codigo := codigoEmComum(codigosNovo, codigosFipe(antigo))
+			if codigo == "" {
+				continue
+			}
+			if s := utils.Similaridade(antigo.ModelName, novo.ModelName); s >= melhorSimilaridade {
+				melhor, melhorCodigo, melhorSimilaridade = &descontinuados[i], codigo, s
+			}
+		}
+		if melhor == nil {
+			resultado.Lancamentos = append(resultado.Lancamentos, catalogo(novo))
+			continue
+		}
+		usados[melhor.ModelCode] = true
+		resultado.Renomeados = append(resultado.Renomeados, models.RenomeacaoModelo{
+			Anterior:     catalogo(*melhor),
+			Atual:        catalogo(novo),
+			CodigoFipe:   melhorCodigo,
+			Similaridade: melhorSimilaridade,
+		})
+		adicionarNovosAnos(&resultado, *melhor, novo)
+	}
+	for _, antigo := range descontinuados {
+		if !usados[antigo.ModelCode] {
+			resultado.Descontinuados = append(resultado.Descontinuados, catalogo(antigo))
+		}
+	}
+
+	porNome := func(lista []models.ModeloCatalogo) func(i, j int) bool {
+		return func(i, j int) bool { return lista[i].ModelName < lista[j].ModelName }
+	}
+	sort.Slice(resultado.Lancamentos, porNome(resultado.Lancamentos))
+	sort.Slice(resultado.Descontinuados, porNome(resultado.Descontinuados))
+	return resultado
+}
+
+func adicionarNovosAnos(resultado *models.CicloVidaMarca, antigo, atual models.Modelo) {
+	var novos []int32
+	for _, y := range atual.Years {
+		if _, ok := antigo.Ano(y.Year); !ok {
+			novos = append(novos, y.Year)
+		}
+	}
+	if len(novos) > 0 {
+		resultado.NovosAnos = append(resultado.NovosAnos, models.NovosAnosModelo{ModelCode: atual.ModelCode, ModelName: atual.ModelName, Anos: novos})
+	}
+}
+
+func catalogo(m models.Modelo) models.ModeloCatalogo {
+	c := models.ModeloCatalogo{ModelCode: m.ModelCode, ModelName: m.ModelName, CodigosFipe: codigosFipe(m)}
+	for _, y := range m.Years {
+		c.Anos = append(c.Anos, y.Year)
+	}
+	return c
+}
+
+// codigosFipe retorna os códigos FIPE distintos dos anos do modelo.
+func codigosFipe(m models.Modelo) []string {
+	var codigos []string
+	vistos := make(map[string]bool)
+	for _, y := range m.Years {
+		if y.CodigoFipe != "" && !vistos[y.CodigoFipe] {
+			vistos[y.CodigoFipe] = true
+			codigos = append(codigos, y.CodigoFipe)
+		}
+	}
+	return codigos
+}
+
+func codigoEmComum(a, b []string) string {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return x
+			}
+		}
+	}
+	return ""
+}
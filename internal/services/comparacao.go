@@ -0,0 +1,207 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"fipe_project/internal/models"
+	"fipe_project/internal/segmentos"
+	"fipe_project/internal/utils"
+)
+
+const (
+	// MaxVeiculosComparacao limita os anos-modelo de uma comparação, já com os
+	// pares sem ano expandidos para todos os anos do modelo.
+	MaxVeiculosComparacao = 20
+	MaxTabelasComparacao  = 12
+)
+
+// ErrComparacaoInvalida indica parâmetros de comparação fora dos limites.
+var ErrComparacaoInvalida = errors.New("comparação inválida")
+
+// CompararVeiculos monta a matriz de comparação dos anos-modelo informados nas
+// tabelas informadas. A primeira tabela é a referência: sobre ela são
+// calculadas as variações das demais, a depreciação em relação ao 0km, a
+// posição de preço dentro da marca e as diferenças entre os veículos. Um par
+// com Ano zero é expandido para todos os anos do modelo na tabela de referência.
+func CompararVeiculos(ctx context.Context, pares []models.ParVeiculo, tabelaIds []int) (*models.Comparacao, error) {
+	if len(pares) == 0 || len(tabelaIds) == 0 {
+		return nil, fmt.Errorf("%w: informe ao menos um veículo e uma tabela", ErrComparacaoInvalida)
+	}
+	if len(tabelaIds) > MaxTabelasComparacao {
+		return nil, fmt.Errorf("%w: no máximo %d tabelas", ErrComparacaoInvalida, MaxTabelasComparacao)
+	}
+
+	comparacao := &models.Comparacao{}
+	for _, id := range tabelaIds {
+		ref, err := TabelaRef(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		comparacao.Tabelas = append(comparacao.Tabelas, models.TabelaComparada{TabelaId: id, Ref: ref, Periodo: models.PeriodoDoMes(ref)})
+	}
+	tabelaRef := tabelaIds[0]
+
+	classificador, err := segmentos.Atual(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Modelos já carregados, por tabela e código, para não repetir consultas
+	// quando o mesmo modelo aparece em mais de um ano. Guarda nil para modelos
+	// ausentes da tabela.
+	type chave struct {
+		tabela int
+		modelo int32
+	}
+	cacheModelos := make(map[chave]*models.Marca)
+	buscar := func(tabelaId int, modelCode int32) (*models.Marca, error) {
+		if marca, ok := cacheModelos[chave{tabelaId, modelCode}]; ok {
+			return marca, nil
+		}
+		marca, _, err := BuscarModelo(ctx, tabelaId, int(modelCode))
+		if errors.Is(err, ErrNaoEncontrado) {
+			marca, err = nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		cacheModelos[chave{tabelaId, modelCode}] = marca
+		return marca, nil
+	}
+	cacheMarcas := make(map[int32]*models.Marca)
+
+	var expandidos []models.ParVeiculo
+	for _, par := range pares {
+		if par.Ano != 0 {
+			expandidos = append(expandidos, par)
+			continue
+		}
+		marca, err := buscar(tabelaRef, par.ModelCode)
+		if err != nil {
+			return nil, err
+		}
+		if marca == nil {
+			return nil, fmt.Errorf("%w: modelo %d não encontrado na tabela %d", ErrComparacaoInvalida, par.ModelCode, tabelaRef)
+		}
+		for _, y := range marca.Models[0].Years {
+			expandidos = append(expandidos, models.ParVeiculo{ModelCode: par.ModelCode, Ano: y.Year})
+		}
+	}
+	if len(expandidos) > MaxVeiculosComparacao {
+		return nil, fmt.Errorf("%w: no máximo %d anos-modelo", ErrComparacaoInvalida, MaxVeiculosComparacao)
+	}
+
+	for _, par := range expandidos {
+		veiculo := models.VeiculoComparado{ModelCode: par.ModelCode, Ano: par.Ano}
+
+		var precoRef models.Money
+		refDisponivel := false
+		for i, tabelaId := range tabelaIds {
+			preco := models.PrecoComparado{TabelaId: tabelaId, ValorFmt: "N/A"}
+
+			marca, err := buscar(tabelaId, par.ModelCode)
+			if err != nil {
+				return nil, err
+			}
+			if marca != nil {
+				modelo := marca.Models[0]
+				if veiculo.BrandName == "" {
+					veiculo.BrandName, veiculo.BrandCode = marca.BrandName, marca.BrandCode
+				}
+				veiculo.ModelName = modelo.ModelName
+				if y, ok := modelo.Ano(par.Ano); ok {
+					if valor, err := y.Valor(); err == nil {
+						preco.Valor, preco.ValorFmt, preco.Disponivel = valor, valor.String(), true
+					}
+				}
+			}
+
+			if i == 0 {
+				precoRef, refDisponivel = preco.Valor, preco.Disponivel
+			} else if preco.Disponivel && refDisponivel {
+				preco.Variacao, _ = utils.CalculatePercentageDiff(preco.Valor.Float64(), precoRef.Float64())
+			}
+			veiculo.Precos = append(veiculo.Precos, preco)
+		}
+
+		if veiculo.ModelName != "" {
+			veiculo.Segmento = classificador.Classificar(veiculo.BrandName, veiculo.ModelName, veiculo.ModelCode)
+		}
+
+		if veiculo.ModelName == "" {
+			veiculo.Erro = "modelo não encontrado nas tabelas informadas"
+		} else if !refDisponivel {
+			veiculo.Erro = fmt.Sprintf("ano-modelo sem preço na tabela %d", tabelaRef)
+		}
+
+		if refDisponivel && par.Ano != models.AnoZeroKm {
+			if marca := cacheModelos[chave{tabelaRef, par.ModelCode}]; marca != nil {
+				if y, ok := marca.Models[0].Ano(models.AnoZeroKm); ok {
+					if preco0km, err := y.Valor(); err == nil {
+						veiculo.Depreciacao, _ = utils.CalculatePercentageDiff(precoRef.Float64(), preco0km.Float64())
+					}
+				}
+			}
+		}
+
+		if refDisponivel && veiculo.BrandCode != 0 {
+			marca := cacheMarcas[veiculo.BrandCode]
+			if marca == nil {
+				var err error
+				marca, err = BuscarMarca(ctx, tabelaRef, veiculo.BrandCode)
+				if err != nil && !errors.Is(err, ErrNaoEncontrado) {
+					return nil, err
+				}
+				cacheMarcas[veiculo.BrandCode] = marca
+			}
+			if marca != nil {
+				veiculo.PosicaoMarca = posicaoPorPreco(marca.Models, par.Ano, precoRef)
+				var doSegmento []models.Modelo
+				for _, m := range marca.Models {
+					if classificador.Classificar(marca.BrandName, m.ModelName, m.ModelCode) == veiculo.Segmento {
+						doSegmento = append(doSegmento, m)
+					}
+				}
+				veiculo.PosicaoSegmento = posicaoPorPreco(doSegmento, par.Ano, precoRef)
+			}
+		}
+
+		comparacao.Veiculos = append(comparacao.Veiculos, veiculo)
+	}
+
+	comparacao.Diferencas = make([][]*float64, len(comparacao.Veiculos))
+	for i, vi := range comparacao.Veiculos {
+		comparacao.Diferencas[i] = make([]*float64, len(comparacao.Veiculos))
+		for j, vj := range comparacao.Veiculos {
+			if i == j || !vi.Precos[0].Disponivel || !vj.Precos[0].Disponivel {
+				continue
+			}
+			comparacao.Diferencas[i][j], _ = utils.CalculatePercentageDiff(vi.Precos[0].Valor.Float64(), vj.Precos[0].Valor.Float64())
+		}
+	}
+	return comparacao, nil
+}
+
+// posicaoPorPreco retorna a colocação de preco entre os modelos do grupo que
+// têm o mesmo ano-modelo, do mais caro para o mais barato.
+func posicaoPorPreco(modelos []models.Modelo, ano int32, preco models.Money) *models.Posicao {
+	var precos []models.Money
+	for _, m := range modelos {
+		y, ok := m.Ano(ano)
+		if !ok {
+			continue
+		}
+		if p, err := y.Valor(); err == nil {
+			precos = append(precos, p)
+		}
+	}
+	if len(precos) == 0 {
+		return nil
+	}
+	sort.Slice(precos, func(i, j int) bool { return precos[i] > precos[j] })
+	posicao := sort.Search(len(precos), func(i int) bool { return precos[i] <= preco }) + 1
+	return &models.Posicao{Posicao: posicao, Total: len(precos)}
+}
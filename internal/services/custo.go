@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	"fipe_project/internal/ipva"
+	"fipe_project/internal/models"
+	"fipe_project/internal/segmentos"
+)
+
+const (
+	MaxAnosCustoTotal     = 10
+	MaxVeiculosCustoTotal = 5
+	MaxKmAnual            = 200000
+
+	// ArquivoCustosPadrao é usado quando a variável CUSTOS_PREMISSAS não está
+	// definida.
+	ArquivoCustosPadrao = "./config/custos.json"
+
+	anosCustoTotalPadrao = 5
+	kmAnualPadrao        = 15000
+)
+
+// ErrCustoTotalInvalido indica parâmetros fora dos limites em CalcularCustoTotal.
+var ErrCustoTotalInvalido = errors.New("consulta de custo total inválida")
+
+// premissasCustosPadrao valem quando o arquivo de premissas não existe.
+var premissasCustosPadrao = models.ConfigCustos{
+	SeguroPercentual: 4.5,
+	Combustiveis: map[models.Combustivel]models.CustoCombustivel{
+		models.CombustivelGasolina: {Preco: 6.2, Unidade: "L", Consumo: 11.5},
+	},
+	Manutencao: models.PremissasManutencao{Base: 1500, CrescimentoAnual: 8},
+}
+
+// ConsultaCustoTotal descreve os veículos (com ano obrigatório) e o uso.
+// SeguroPercentual zero usa o percentual das premissas.
+type ConsultaCustoTotal struct {
+	TabelaId         int
+	Veiculos         []models.ParVeiculo
+	UF               string
+	Anos             int
+	KmAnual          int
+	SeguroPercentual float64
+}
+
+// CalcularCustoTotal estima, ano a ano, o custo de manter cada veículo:
+// depreciação pela curva do modelo na tabela, IPVA da UF sobre o valor no
+// início de cada ano, seguro como percentual desse valor, combustível pela
+// quilometragem e manutenção crescente com a idade.
+func CalcularCustoTotal(ctx context.Context, c ConsultaCustoTotal) (*models.CustoTotal, error) {
+	if c.Anos == 0 {
+		c.Anos = anosCustoTotalPadrao
+	}
+	if c.KmAnual == 0 {
+		c.KmAnual = kmAnualPadrao
+	}
+	if c.Anos < 1 || c.Anos > MaxAnosCustoTotal {
+		return nil, fmt.Errorf("%w: o período deve estar entre 1 e %d anos", ErrCustoTotalInvalido, MaxAnosCustoTotal)
+	}
+	if c.KmAnual < 0 || c.KmAnual > MaxKmAnual {
+		return nil, fmt.Errorf("%w: a quilometragem anual deve estar entre 0 e %d", ErrCustoTotalInvalido, MaxKmAnual)
+	}
+	if len(c.Veiculos) == 0 || len(c.Veiculos) > MaxVeiculosCustoTotal {
+		return nil, fmt.Errorf("%w: informe de 1 a %d veículos", ErrCustoTotalInvalido, MaxVeiculosCustoTotal)
+	}
+	if c.SeguroPercentual < 0 || c.SeguroPercentual > 100 {
+		return nil, fmt.Errorf("%w: o percentual do seguro deve estar entre 0 e 100", ErrCustoTotalInvalido)
+	}
+	for _, v := range c.Veiculos {
+		if v.Ano == 0 {
+			return nil, fmt.Errorf("%w: informe o ano do modelo %d", ErrCustoTotalInvalido, v.ModelCode)
+		}
+	}
+
+	premissas, err := carregarPremissasCustos()
+	if err != nil {
+		return nil, err
+	}
+	regras, err := ipva.Atual()
+	if err != nil {
+		return nil, err
+	}
+	ref, err := TabelaRef(ctx, c.TabelaId)
+	if err != nil {
+		return nil, err
+	}
+	anoTabela := anoReferencia(ref)
+	regraAtual, versao, err := regras.Regra(c.UF, anoTabela)
+	if err != nil {
+		return nil, err
+	}
+	classificador, err := segmentos.Atual(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resultado := &models.CustoTotal{
+		TabelaId:   c.TabelaId,
+		Ref:        ref,
+		Periodo:    models.PeriodoDoMes(ref),
+		UF:         regraAtual.UF,
+		VersaoIPVA: versao.Versao,
+		Anos:       c.Anos,
+		KmAnual:    c.KmAnual,
+		Veiculos:   []models.CustoTotalVeiculo{},
+	}
+
+	for _, par := range c.Veiculos {
+		marca, modelo, err := BuscarModelo(ctx, c.TabelaId, int(par.ModelCode))
+		if errors.Is(err, ErrNaoEncontrado) {
+			return nil, fmt.Errorf("%w: modelo %d", ErrNaoEncontrado, par.ModelCode)
+		}
+		if err != nil {
+			return nil, err
+		}
+		anoModelo, ok := modelo.Ano(par.Ano)
+		if !ok {
+			return nil, fmt.Errorf("%w: modelo %d, ano %d", ErrNaoEncontrado, par.ModelCode, par.Ano)
+		}
+		preco, err := anoModelo.Valor()
+		if err != nil {
+			return nil, err
+		}
+
+		detalhes := anoModelo.Detalhes()
+		segmento := classificador.Classificar(marca.BrandName, modelo.ModelName, modelo.ModelCode)
+		idade := idadeVeiculo(detalhes, anoTabela)
+		veiculo := models.CustoTotalVeiculo{
+			BrandName:     marca.BrandName,
+			ModelCode:     modelo.ModelCode,
+			ModelName:     modelo.ModelName,
+			Ano:           detalhes,
+			Segmento:      segmento,
+			Idade:         idade,
+			PrecoAtual:    preco,
+			PrecoAtualFmt: preco.String(),
+			Anos:          make([]models.CustoAnual, 0, c.Anos),
+		}
+
+		veiculo.SeguroPercentual = c.SeguroPercentual
+		if veiculo.SeguroPercentual == 0 {
+			veiculo.SeguroPercentual = premissas.SeguroPercentual
+			if p, ok := premissas.SeguroSegmento[segmento]; ok {
+				veiculo.SeguroPercentual = p
+			}
+		}
+		// Sem premissa para o combustível do ano-modelo, vale a da gasolina.
+		veiculo.Combustivel = detalhes.Combustivel
+		combustivel, ok := premissas.Combustiveis[detalhes.Combustivel]
+		if !ok {
+			veiculo.Combustivel = models.CombustivelGasolina
+			combustivel = premissas.Combustiveis[models.CombustivelGasolina]
+		}
+		veiculo.PrecoCombustivel = combustivel.Preco
+		veiculo.UnidadeCombustivel = combustivel.Unidade
+		veiculo.Consumo = combustivel.Consumo
+		custoCombustivel := models.MoneyFromFloat(float64(c.KmAnual) / combustivel.Consumo * combustivel.Preco)
+
+		curva := novaCurvaDepreciacao(*modelo, anoTabela)
+		for i := 0; i < c.Anos; i++ {
+			fatorInicial, ext1 := curva.fator(float64(idade), float64(i))
+			fatorFinal, ext2 := curva.fator(float64(idade), float64(i+1))
+			veiculo.Extrapolado = veiculo.Extrapolado || ext1 || ext2
+			inicial, final := preco.Mul(fatorInicial), preco.Mul(fatorFinal)
+
+			exercicio := anoTabela + i
+			regra, _, err := regras.Regra(c.UF, exercicio)
+			if err != nil {
+				return nil, err
+			}
+			imposto := models.CalculoIPVA{ValorBase: inicial, Idade: idade + i, Combustivel: detalhes.Combustivel}
+			ipva.Aplicar(regra, &imposto)
+
+			ano := models.CustoAnual{
+				Ano:             i + 1,
+				Exercicio:       exercicio,
+				Idade:           idade + i,
+				ValorInicial:    inicial,
+				ValorInicialFmt: inicial.String(),
+				ValorFinal:      final,
+				ValorFinalFmt:   final.String(),
+				IPVAIsento:      imposto.Isento,
+				CustosPosse: models.CustosPosse{
+					Depreciacao: inicial.Sub(final),
+					IPVA:        imposto.Valor,
+					Seguro:      inicial.Mul(veiculo.SeguroPercentual / 100),
+					Combustivel: custoCombustivel,
+					Manutencao:  models.MoneyFromFloat(premissas.Manutencao.Base * math.Pow(1+premissas.Manutencao.CrescimentoAnual/100, float64(idade+i))),
+				},
+			}
+			totalizarCustos(&ano.CustosPosse)
+			veiculo.Anos = append(veiculo.Anos, ano)
+			somarCustos(&veiculo.Total, ano.CustosPosse)
+		}
+		totalizarCustos(&veiculo.Total)
+
+		veiculo.CustoMensal = veiculo.Total.Total.Div(int64(c.Anos * 12))
+		veiculo.CustoMensalFmt = veiculo.CustoMensal.String()
+		if c.KmAnual > 0 {
+			veiculo.CustoKm = veiculo.Total.Total.Div(int64(c.Anos * c.KmAnual))
+			veiculo.CustoKmFmt = veiculo.CustoKm.String()
+		}
+		resultado.Veiculos = append(resultado.Veiculos, veiculo)
+	}
+	return resultado, nil
+}
+
+func somarCustos(total *models.CustosPosse, c models.CustosPosse) {
+	total.Depreciacao = total.Depreciacao.Add(c.Depreciacao)
+	total.IPVA = total.IPVA.Add(c.IPVA)
+	total.Seguro = total.Seguro.Add(c.Seguro)
+	total.Combustivel = total.Combustivel.Add(c.Combustivel)
+	total.Manutencao = total.Manutencao.Add(c.Manutencao)
+}
+
+// totalizarCustos soma os componentes em Total e preenche os textos.
+func totalizarCustos(c *models.CustosPosse) {
+	c.Total = c.Depreciacao.Add(c.IPVA).Add(c.Seguro).Add(c.Combustivel).Add(c.Manutencao)
+	c.DepreciacaoFmt = c.Depreciacao.String()
+	c.IPVAFmt = c.IPVA.String()
+	c.SeguroFmt = c.Seguro.String()
+	c.CombustivelFmt = c.Combustivel.String()
+	c.ManutencaoFmt = c.Manutencao.String()
+	c.TotalFmt = c.Total.String()
+}
+
+// carregarPremissasCustos lê o arquivo de premissas (CUSTOS_PREMISSAS ou
+// ArquivoCustosPadrao). Sem arquivo, valem premissasCustosPadrao.
+func carregarPremissasCustos() (models.ConfigCustos, error) {
+	caminho := ArquivoCustosPadrao
+	if c := os.Getenv("CUSTOS_PREMISSAS"); c != "" {
+		caminho = c
+	}
+	dados, err := os.ReadFile(caminho)
+	if errors.Is(err, os.ErrNotExist) {
+		return premissasCustosPadrao, nil
+	}
+	if err != nil {
+		return models.ConfigCustos{}, fmt.Errorf("erro ao ler premissas de custo em %s: %v", caminho, err)
+	}
+	var config models.ConfigCustos
+	if err := json.Unmarshal(dados, &config); err != nil {
+		return models.ConfigCustos{}, fmt.Errorf("erro ao interpretar premissas de custo em %s: %v", caminho, err)
+	}
+	if config.SeguroPercentual < 0 || config.SeguroPercentual > 100 {
+		return models.ConfigCustos{}, fmt.Errorf("percentual de seguro inválido em %s", caminho)
+	}
+	for s, p := range config.SeguroSegmento {
+		if !s.Valido() || p < 0 || p > 100 {
+			return models.ConfigCustos{}, fmt.Errorf("seguro inválido em %s para o segmento '%s'", caminho, s)
+		}
+	}
+	if _, ok := config.Combustiveis[models.CombustivelGasolina]; !ok {
+		return models.ConfigCustos{}, fmt.Errorf("premissas de custo em %s sem o combustível '%s'", caminho, models.CombustivelGasolina)
+	}
+	for comb, custo := range config.Combustiveis {
+		if _, err := models.ParseCombustivel(string(comb)); err != nil || comb == models.CombustivelDesconhecido {
+			return models.ConfigCustos{}, fmt.Errorf("combustível desconhecido '%s' em %s", comb, caminho)
+		}
+		if custo.Preco < 0 || custo.Consumo <= 0 {
+			return models.ConfigCustos{}, fmt.Errorf("preço ou consumo inválido em %s para '%s'", caminho, comb)
+		}
+	}
+	if config.Manutencao.Base < 0 || config.Manutencao.CrescimentoAnual < 0 {
+		return models.ConfigCustos{}, fmt.Errorf("premissas de manutenção inválidas em %s", caminho)
+	}
+	return config, nil
+}
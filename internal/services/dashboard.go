@@ -0,0 +1,284 @@
+package services
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"fipe_project/internal/models"
+	"fipe_project/internal/utils"
+)
+
+// FiltroEstatisticas restringe os veículos considerados em ItensTabela e nas
+// estatísticas do dashboard. Campos vazios não filtram.
+type FiltroEstatisticas struct {
+	Marca       *int32
+	Combustivel models.Combustivel
+	Segmento    models.Segmento
+	// ExcluirSinalizados descarta das estatísticas os anos-modelo com
+	// ocorrências de qualidade (ver AnalisarQualidade).
+	ExcluirSinalizados bool
+}
+
+// aceitaAno informa se a entrada de ano passa no filtro de combustível.
+func (f FiltroEstatisticas) aceitaAno(detalhes models.CodigoAno) bool {
+	return f.Combustivel == models.CombustivelDesconhecido || detalhes.Combustivel == f.Combustivel
+}
+
+// EstatisticasMarcas calcula, por marca, o menor e o maior preço 0km, o
+// valor médio 0km e o total de modelos disponíveis na tabela. Retorna também
+// o nome de cada marca encontrada, mesmo as sem veículos que passem no filtro.
+// Os agregados são calculados pelo banco, só sobre as entradas 0km (ver
+// estatisticasZeroKm).
+func EstatisticasMarcas(ctx context.Context, tabelaId int, tabelaRef string, filtro FiltroEstatisticas) (map[int32]*models.BrandPeriodStats, map[int32]string, error) {
+	if filtro.Marca != nil {
+		log.Printf("Tabela %d: Aplicando filtro para brandCode: %d", tabelaId, *filtro.Marca)
+	} else {
+		log.Printf("Tabela %d: Buscando todas as marcas.", tabelaId)
+	}
+
+	brandNames, err := NomesMarcas(ctx, tabelaId, filtro.Marca)
+	if err != nil {
+		return nil, nil, err
+	}
+	agregadas, err := estatisticasZeroKm(ctx, tabelaId, tabelaRef, filtro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	targetStats := make(map[int32]*models.BrandPeriodStats, len(brandNames))
+	for brandCode := range brandNames {
+		// As duas consultas não são atômicas: uma marca gravada entre elas
+		// fica para a próxima.
+		stats, ok := agregadas[brandCode]
+		if !ok {
+			stats = novasEstatisticas(tabelaRef, tabelaId)
+		}
+		finalizarEstatisticas(stats)
+		targetStats[brandCode] = stats
+	}
+
+	log.Printf("Tabela %d: Processou %d marcas (documentos).", tabelaId, len(brandNames))
+	return targetStats, brandNames, nil
+}
+
+// EstatisticasSegmentos calcula as mesmas estatísticas de EstatisticasMarcas,
+// agrupando os modelos de todas as marcas por segmento.
+func EstatisticasSegmentos(ctx context.Context, tabelaId int, tabelaRef string, filtro FiltroEstatisticas) (map[models.Segmento]*models.BrandPeriodStats, error) {
+	itens, _, err := ItensTabela(ctx, tabelaId, filtro)
+	if err != nil {
+		return nil, err
+	}
+	if itens, err = excluirSinalizados(ctx, tabelaId, filtro, itens); err != nil {
+		return nil, err
+	}
+	resultado := make(map[models.Segmento]*models.BrandPeriodStats)
+	for _, item := range itens {
+		stats, ok := resultado[item.Segmento]
+		if !ok {
+			stats = novasEstatisticas(tabelaRef, tabelaId)
+			resultado[item.Segmento] = stats
+		}
+		acumularItem(stats, item)
+	}
+	for _, stats := range resultado {
+		finalizarEstatisticas(stats)
+	}
+	return resultado, nil
+}
+
+// excluirSinalizados retira os anos-modelo sinalizados, quando o filtro pede.
+func excluirSinalizados(ctx context.Context, tabelaId int, filtro FiltroEstatisticas, itens []ItemTabela) ([]ItemTabela, error) {
+	if !filtro.ExcluirSinalizados {
+		return itens, nil
+	}
+	sinalizadas, err := EntradasSinalizadas(ctx, tabelaId, filtro.Marca)
+	if err != nil {
+		return nil, err
+	}
+	validos := itens[:0]
+	for _, item := range itens {
+		if !sinalizadas[item.Chave()] {
+			validos = append(validos, item)
+		}
+	}
+	return validos, nil
+}
+
+func novasEstatisticas(tabelaRef string, tabelaId int) *models.BrandPeriodStats {
+	return &models.BrandPeriodStats{Ref: tabelaRef, Periodo: models.PeriodoDoMes(tabelaRef), TabelaId: tabelaId, ModelosEncontrados: make(map[int32]struct{})}
+}
+
+// acumularItem soma um ano-modelo às estatísticas: só entradas 0km contam
+// como modelos disponíveis, e só as com preço válido entram nos preços.
+// ModelosEncontrados registra os modelos dos itens recebidos, que em
+// EstatisticasMarcas são só os 0km.
+func acumularItem(stats *models.BrandPeriodStats, item ItemTabela) {
+	stats.ModelosEncontrados[item.ModelCode] = struct{}{}
+	if !item.Ano.ZeroKm {
+		return
+	}
+	stats.TotalModelos++
+	if item.PrecoValido {
+		acumularPreco0km(stats, item.ModelName, item.Preco)
+	}
+}
+
+func acumularPreco0km(stats *models.BrandPeriodStats, modelName string, price models.Money) {
+	stats.TotalVeiculos0km++
+	stats.SomaValores0km = stats.SomaValores0km.Add(price)
+	if !stats.Inicializado || price < stats.MenorPreco0km.Valor {
+		stats.MenorPreco0km = models.PriceInfo{Modelo: modelName, Valor: price, ValorFmt: price.String()}
+	}
+	if !stats.Inicializado || price > stats.MaiorPreco0km.Valor {
+		stats.MaiorPreco0km = models.PriceInfo{Modelo: modelName, Valor: price, ValorFmt: price.String()}
+	}
+	stats.Inicializado = true
+}
+
+// finalizarEstatisticas calcula a média e preenche "N/A" quando não houve
+// nenhum preço 0km.
+func finalizarEstatisticas(stats *models.BrandPeriodStats) {
+	if stats.TotalVeiculos0km > 0 {
+		stats.ValorMedio0km = stats.SomaValores0km.Div(int64(stats.TotalVeiculos0km))
+		stats.ValorMedio0kmFmt = stats.ValorMedio0km.String()
+	} else {
+		stats.ValorMedio0km = 0
+		stats.ValorMedio0kmFmt = "N/A"
+	}
+	if !stats.Inicializado {
+		stats.MenorPreco0km = models.PriceInfo{Modelo: "N/A", ValorFmt: "N/A"}
+		stats.MaiorPreco0km = models.PriceInfo{Modelo: "N/A", ValorFmt: "N/A"}
+	}
+}
+
+// EstatisticasIndisponiveis retorna as estatísticas de uma marca que não possui
+// dados na tabela informada.
+func EstatisticasIndisponiveis(tabelaRef string, tabelaId int) *models.BrandPeriodStats {
+	return &models.BrandPeriodStats{
+		Ref:              tabelaRef,
+		Periodo:          models.PeriodoDoMes(tabelaRef),
+		TabelaId:         tabelaId,
+		ValorMedio0kmFmt: "N/A",
+		MenorPreco0km:    models.PriceInfo{Modelo: "N/A", ValorFmt: "N/A"},
+		MaiorPreco0km:    models.PriceInfo{Modelo: "N/A", ValorFmt: "N/A"},
+	}
+}
+
+// CompararEstatisticas calcula as diferenças percentuais entre dois períodos de
+// uma marca. A diferença do valor médio 0km só é calculada quando os dois
+// períodos têm preços 0km.
+func CompararEstatisticas(stats1, stats2 *models.BrandPeriodStats) models.PercentageDiffs {
+	diffs := models.PercentageDiffs{}
+	if stats1.TotalVeiculos0km > 0 && stats2.TotalVeiculos0km > 0 {
+		if diffAvg, ok := utils.CalculatePercentageDiff(stats1.ValorMedio0km.Float64(), stats2.ValorMedio0km.Float64()); ok {
+			diffs.ValorMedio0km = diffAvg
+		}
+	}
+	if diffModels, ok := utils.CalculatePercentageDiff(float64(stats1.TotalModelos), float64(stats2.TotalModelos)); ok {
+		diffs.TotalModelos = diffModels
+	}
+	return diffs
+}
+
+// DetalhesAno interpreta uma entrada de ano lida como bson.M (campos "year",
+// "yearCode" e "fuel"). Ver models.AnoModelo.Detalhes.
+func DetalhesAno(yearData bson.M) models.CodigoAno {
+	var ano models.AnoModelo
+	ano.Year, _ = yearData["year"].(int32)
+	ano.CodigoAno, _ = yearData["yearCode"].(string)
+	if fuel, ok := yearData["fuel"].(string); ok {
+		ano.Combustivel = models.Combustivel(fuel)
+	}
+	return ano.Detalhes()
+}
+
+// DashboardMarcas compara as estatísticas de cada marca entre duas tabelas.
+// Uma marca ausente de uma das tabelas aparece com estatísticas
+// indisponíveis no período; um erro ao ler qualquer das tabelas é devolvido,
+// para não responder um dashboard parcial. Com filtro de marca, devolve
+// ErrNaoEncontrado se a marca não existir em nenhuma das tabelas.
+func DashboardMarcas(ctx context.Context, tabela1Id, tabela2Id int, filtro FiltroEstatisticas) ([]models.DashboardBrandEntry, error) {
+	var tabela1Ref, tabela2Ref string
+	var refErr1, refErr2 error
+	var wgRefs sync.WaitGroup
+	wgRefs.Add(2)
+	go func() { defer wgRefs.Done(); tabela1Ref, refErr1 = TabelaRef(ctx, tabela1Id) }()
+	go func() { defer wgRefs.Done(); tabela2Ref, refErr2 = TabelaRef(ctx, tabela2Id) }()
+	wgRefs.Wait()
+	if refErr1 != nil {
+		return nil, refErr1
+	}
+	if refErr2 != nil {
+		return nil, refErr2
+	}
+
+	var statsTabela1, statsTabela2 map[int32]*models.BrandPeriodStats
+	var nomesTabela1, nomesTabela2 map[int32]string
+	var processErr1, processErr2 error
+	var wgProcess sync.WaitGroup
+	wgProcess.Add(2)
+	go func() {
+		defer wgProcess.Done()
+		statsTabela1, nomesTabela1, processErr1 = EstatisticasMarcas(ctx, tabela1Id, tabela1Ref, filtro)
+	}()
+	go func() {
+		defer wgProcess.Done()
+		statsTabela2, nomesTabela2, processErr2 = EstatisticasMarcas(ctx, tabela2Id, tabela2Ref, filtro)
+	}()
+	wgProcess.Wait()
+
+	if processErr1 != nil {
+		return nil, processErr1
+	}
+	if processErr2 != nil {
+		return nil, processErr2
+	}
+
+	BrandInfo := make(map[int32]string)
+	for code, name := range nomesTabela1 {
+		BrandInfo[code] = name
+	}
+	for code, name := range nomesTabela2 {
+		if _, exists := BrandInfo[code]; !exists {
+			BrandInfo[code] = name
+		}
+	}
+
+	if filtro.Marca != nil {
+		if _, ok := BrandInfo[*filtro.Marca]; !ok {
+			return nil, ErrNaoEncontrado
+		}
+	}
+
+	var dashboardResult []models.DashboardBrandEntry
+	for brandCode, brandName := range BrandInfo {
+		stats1, ok1 := statsTabela1[brandCode]
+		stats2, ok2 := statsTabela2[brandCode]
+
+		if !ok1 {
+			stats1 = EstatisticasIndisponiveis(tabela1Ref, tabela1Id)
+		}
+		if !ok2 {
+			stats2 = EstatisticasIndisponiveis(tabela2Ref, tabela2Id)
+		}
+		// Com filtro de combustível ou segmento, marcas sem nenhum modelo que
+		// passe no filtro nos dois períodos não entram no dashboard.
+		filtrado := filtro.Combustivel != models.CombustivelDesconhecido || filtro.Segmento != ""
+		if filtrado && stats1.TotalModelos == 0 && stats2.TotalModelos == 0 {
+			continue
+		}
+
+		entry := models.DashboardBrandEntry{
+			BrandName:             brandName,
+			BrandCode:             brandCode,
+			Periodo1:              *stats1,
+			Periodo2:              *stats2,
+			DiferencasPercentuais: CompararEstatisticas(stats1, stats2),
+		}
+		dashboardResult = append(dashboardResult, entry)
+	}
+	return dashboardResult, nil
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"fipe_project/internal/models"
+)
+
+// Depreciação anual usada quando o modelo não tem dois anos-modelo com preço
+// na tabela.
+const taxaDepreciacaoPadrao = 0.10
+
+// taxaDepreciacaoModelo estima quanto o modelo perde por ano de idade: a
+// média geométrica da razão de preço entre anos-modelo vizinhos (sem o 0km)
+// na mesma tabela. Sem dois anos com preço, devolve taxaDepreciacaoPadrao.
+func taxaDepreciacaoModelo(modelo models.Modelo) (float64, bool) {
+	precos := make(map[int32]float64)
+	for _, y := range modelo.Years {
+		detalhes := y.Detalhes()
+		if detalhes.ZeroKm {
+			continue
+		}
+		preco, err := y.Valor()
+		if err != nil || preco <= 0 {
+			continue
+		}
+		if _, ok := precos[detalhes.AnoModelo]; !ok {
+			precos[detalhes.AnoModelo] = preco.Float64()
+		}
+	}
+	return taxaDepreciacaoPrecos(precos)
+}
+
+// taxaDepreciacaoPrecos calcula a taxa a partir dos preços por ano-modelo.
+func taxaDepreciacaoPrecos(precos map[int32]float64) (float64, bool) {
+	anos := make([]int32, 0, len(precos))
+	for ano := range precos {
+		anos = append(anos, ano)
+	}
+	sort.Slice(anos, func(i, j int) bool { return anos[i] < anos[j] })
+
+	var somaLog float64
+	var totalAnos int32
+	for i := 1; i < len(anos); i++ {
+		somaLog += math.Log(precos[anos[i-1]] / precos[anos[i]])
+		totalAnos += anos[i] - anos[i-1]
+	}
+	if totalAnos == 0 {
+		return taxaDepreciacaoPadrao, true
+	}
+	return 1 - math.Exp(somaLog/float64(totalAnos)), false
+}
+
+// taxaDepreciacaoSegmento é a mediana das taxas dos modelos do segmento na
+// tabela que têm ao menos dois anos-modelo usados com preço. Retorna também
+// quantos modelos entraram na mediana; zero significa sem dados.
+func taxaDepreciacaoSegmento(ctx context.Context, tabelaId int, segmento models.Segmento) (float64, int, error) {
+	itens, _, err := ItensTabela(ctx, tabelaId, FiltroEstatisticas{Segmento: segmento})
+	if err != nil {
+		return 0, 0, err
+	}
+	porModelo := make(map[int32]map[int32]float64)
+	for _, item := range itens {
+		if !item.PrecoValido || item.Ano.ZeroKm {
+			continue
+		}
+		precos, ok := porModelo[item.ModelCode]
+		if !ok {
+			precos = make(map[int32]float64)
+			porModelo[item.ModelCode] = precos
+		}
+		if _, ok := precos[item.Ano.AnoModelo]; !ok {
+			precos[item.Ano.AnoModelo] = item.Preco.Float64()
+		}
+	}
+	var taxas []float64
+	for _, precos := range porModelo {
+		if taxa, padrao := taxaDepreciacaoPrecos(precos); !padrao {
+			taxas = append(taxas, taxa)
+		}
+	}
+	if len(taxas) == 0 {
+		return 0, 0, nil
+	}
+	sort.Float64s(taxas)
+	meio := len(taxas) / 2
+	if len(taxas)%2 == 1 {
+		return taxas[meio], len(taxas), nil
+	}
+	return (taxas[meio-1] + taxas[meio]) / 2, len(taxas), nil
+}
+
+// curvaDepreciacao é o preço de um modelo em função da idade, montada com os
+// anos-modelo de uma única tabela: um carro de idade a daqui a t anos deve
+// valer, em relação ao preço atual, o que o ano-modelo de idade a+t vale hoje
+// em relação ao de idade a.
+type curvaDepreciacao struct {
+	idades    []float64
+	logPrecos []float64
+	// Taxa anual usada fora do intervalo de idades conhecido.
+	taxa float64
+}
+
+// novaCurvaDepreciacao monta a curva do modelo. Anos com a mesma idade (0km e
+// o ano corrente, ou combustíveis diferentes) entram pela média geométrica.
+func novaCurvaDepreciacao(modelo models.Modelo, anoTabela int) curvaDepreciacao {
+	soma := make(map[int]float64)
+	contagem := make(map[int]int)
+	for _, y := range modelo.Years {
+		preco, err := y.Valor()
+		if err != nil || preco <= 0 {
+			continue
+		}
+		idade := idadeVeiculo(y.Detalhes(), anoTabela)
+		soma[idade] += math.Log(preco.Float64())
+		contagem[idade]++
+	}
+	curva := curvaDepreciacao{}
+	curva.taxa, _ = taxaDepreciacaoModelo(modelo)
+	idades := make([]int, 0, len(soma))
+	for idade := range soma {
+		idades = append(idades, idade)
+	}
+	sort.Ints(idades)
+	for _, idade := range idades {
+		curva.idades = append(curva.idades, float64(idade))
+		curva.logPrecos = append(curva.logPrecos, soma[idade]/float64(contagem[idade]))
+	}
+	return curva
+}
+
+// logValor devolve o logaritmo do preço na idade informada, interpolando
+// entre as idades conhecidas e extrapolando com a taxa anual fora delas.
+func (c curvaDepreciacao) logValor(idade float64) (float64, bool) {
+	n := len(c.idades)
+	taxaLog := math.Log(1 - c.taxa)
+	switch {
+	case n == 0:
+		return taxaLog * idade, true
+	case idade < c.idades[0]:
+		return c.logPrecos[0] - taxaLog*(c.idades[0]-idade), true
+	case idade > c.idades[n-1]:
+		return c.logPrecos[n-1] + taxaLog*(idade-c.idades[n-1]), true
+	}
+	i := sort.SearchFloat64s(c.idades, idade)
+	if c.idades[i] == idade {
+		return c.logPrecos[i], false
+	}
+	frac := (idade - c.idades[i-1]) / (c.idades[i] - c.idades[i-1])
+	return c.logPrecos[i-1] + frac*(c.logPrecos[i]-c.logPrecos[i-1]), false
+}
+
+// fator devolve quanto um veículo de idade a retém do valor após t anos e se
+// foi preciso extrapolar a curva.
+func (c curvaDepreciacao) fator(idade, anos float64) (float64, bool) {
+	inicio, ext1 := c.logValor(idade)
+	fim, ext2 := c.logValor(idade + anos)
+	return math.Exp(fim - inicio), ext1 || ext2
+}
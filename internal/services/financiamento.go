@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+
+	"fipe_project/internal/financiamento"
+	"fipe_project/internal/models"
+)
+
+// PedidoFinanciamento identifica o veículo e as condições da simulação.
+// Entrada em reais prevalece sobre EntradaPercentual quando informada.
+type PedidoFinanciamento struct {
+	TabelaId          int
+	ModeloId          int
+	Ano               int32
+	EntradaPercentual float64
+	Entrada           models.Money
+	Prazo             int
+	TaxaMensal        float64
+	Sistema           string
+	FinanciarCustos   bool
+	Tarifas           models.Money
+}
+
+// SimularFinanciamento busca o valor FIPE do ano-modelo na tabela e monta a
+// simulação com financiamento.Simular.
+func SimularFinanciamento(ctx context.Context, p PedidoFinanciamento) (*models.SimulacaoFinanciamento, error) {
+	marca, modelo, err := BuscarModelo(ctx, p.TabelaId, p.ModeloId)
+	if err != nil {
+		return nil, err
+	}
+	anoModelo, ok := modelo.Ano(p.Ano)
+	if !ok {
+		return nil, ErrNaoEncontrado
+	}
+	preco, err := anoModelo.Valor()
+	if err != nil {
+		return nil, err
+	}
+	ref, err := TabelaRef(ctx, p.TabelaId)
+	if err != nil {
+		return nil, err
+	}
+
+	entrada := p.Entrada
+	if entrada == 0 {
+		entrada = preco.Mul(p.EntradaPercentual / 100)
+	}
+	simulacao := &models.SimulacaoFinanciamento{
+		TabelaId:  p.TabelaId,
+		Ref:       ref,
+		Periodo:   models.PeriodoDoMes(ref),
+		BrandName: marca.BrandName,
+		ModelCode: modelo.ModelCode,
+		ModelName: modelo.ModelName,
+		Ano:       anoModelo.Detalhes(),
+	}
+	err = financiamento.Simular(financiamento.Condicoes{
+		ValorVeiculo:    preco,
+		Entrada:         entrada,
+		Tarifas:         p.Tarifas,
+		Prazo:           p.Prazo,
+		TaxaMensal:      p.TaxaMensal,
+		Sistema:         p.Sistema,
+		FinanciarCustos: p.FinanciarCustos,
+	}, simulacao)
+	if err != nil {
+		return nil, err
+	}
+	return simulacao, nil
+}
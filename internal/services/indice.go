@@ -0,0 +1,304 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+
+	"fipe_project/internal/models"
+)
+
+const (
+	MaxTabelasIndice = 120
+	// ArquivoPesosIndicePadrao é usado quando a variável INDICE_PESOS não está
+	// definida.
+	ArquivoPesosIndicePadrao = "./config/indice.json"
+	valorBaseIndice          = 100
+)
+
+// ErrIndiceInvalido indica parâmetros fora dos limites em CalcularIndice.
+var ErrIndiceInvalido = errors.New("consulta de índice inválida")
+
+// ConsultaIndice descreve o cálculo do índice entre as tabelas De e Ate
+// (inclusive). Pesos é "igual" (padrão) ou "segmento"; Subindices lista os
+// grupos de subíndices ("marca", "segmento", "idade"), todos se vazio. Filtro
+// restringe a cesta como no dashboard.
+type ConsultaIndice struct {
+	De         int
+	Ate        int
+	Pesos      string
+	Subindices []string
+	Filtro     FiltroEstatisticas
+}
+
+type itemCesta struct {
+	peso     float64
+	segmento models.Segmento
+	series   []string
+}
+
+type serieCalculada struct {
+	serie models.SerieIndice
+	valor float64
+}
+
+// CalcularIndice calcula um índice de preços encadeado mês a mês. A cesta é
+// fixa: os anos-modelo com preço na primeira tabela. Cada elo é a média
+// geométrica ponderada das variações de preço dos itens da cesta presentes
+// nas duas tabelas consecutivas, e o índice é o produto dos elos, com base
+// 100 na primeira tabela. Os subíndices usam a mesma cesta e os mesmos pesos,
+// restritos aos itens de cada marca, segmento ou faixa de idade (idade na
+// tabela base).
+func CalcularIndice(ctx context.Context, c ConsultaIndice) (*models.IndicePrecos, error) {
+	if c.Pesos == "" {
+		c.Pesos = models.PesoIgual
+	}
+	if c.Pesos != models.PesoIgual && c.Pesos != models.PesoSegmento {
+		return nil, fmt.Errorf("%w: pesos deve ser '%s' ou '%s'", ErrIndiceInvalido, models.PesoIgual, models.PesoSegmento)
+	}
+	if c.Ate <= c.De {
+		return nil, fmt.Errorf("%w: a tabela final deve ser posterior à inicial", ErrIndiceInvalido)
+	}
+	if c.Ate-c.De+1 > MaxTabelasIndice {
+		return nil, fmt.Errorf("%w: no máximo %d tabelas", ErrIndiceInvalido, MaxTabelasIndice)
+	}
+	grupos := map[string]bool{}
+	if len(c.Subindices) == 0 {
+		c.Subindices = []string{models.GrupoMarca, models.GrupoSegmento, models.GrupoIdade}
+	}
+	for _, g := range c.Subindices {
+		if g != models.GrupoMarca && g != models.GrupoSegmento && g != models.GrupoIdade {
+			return nil, fmt.Errorf("%w: subíndice desconhecido '%s'", ErrIndiceInvalido, g)
+		}
+		grupos[g] = true
+	}
+
+	todas, err := TabelasAte(ctx, c.Ate, c.Ate-c.De+1)
+	if err != nil {
+		return nil, err
+	}
+	var tabelas []models.TabelaReferencia
+	for _, t := range todas {
+		if t.Codigo >= c.De {
+			tabelas = append(tabelas, t)
+		}
+	}
+	if len(tabelas) < 2 {
+		return nil, fmt.Errorf("%w: são necessárias ao menos duas tabelas no intervalo", ErrIndiceInvalido)
+	}
+
+	base := tabelas[0]
+	itensBase, _, err := ItensTabela(ctx, base.Codigo, c.Filtro)
+	if err != nil {
+		return nil, err
+	}
+	var pesosSegmento map[models.Segmento]float64
+	if c.Pesos == models.PesoSegmento {
+		if pesosSegmento, err = carregarPesosSegmento(); err != nil {
+			return nil, err
+		}
+	}
+
+	anoBase := anoReferencia(base.Mes)
+	series := map[string]*serieCalculada{}
+	novaSerie := func(grupo, chave, nome string) string {
+		id := grupo + ":" + chave
+		if _, ok := series[id]; !ok {
+			series[id] = &serieCalculada{
+				serie: models.SerieIndice{Grupo: grupo, Chave: chave, Nome: nome},
+				valor: valorBaseIndice,
+			}
+		}
+		return id
+	}
+	geral := novaSerie(models.GrupoGeral, models.GrupoGeral, "Geral")
+
+	// Cesta e pesos. No peso por segmento, o peso do segmento é dividido
+	// igualmente entre os seus itens.
+	cesta := map[models.ParVeiculo]*itemCesta{}
+	anteriores := map[models.ParVeiculo]models.Money{}
+	porSegmento := map[models.Segmento]int{}
+	for _, item := range itensBase {
+		if !item.PrecoValido {
+			continue
+		}
+		if _, ok := cesta[item.Chave()]; ok {
+			continue
+		}
+		ids := []string{geral}
+		if grupos[models.GrupoMarca] {
+			ids = append(ids, novaSerie(models.GrupoMarca, strconv.Itoa(int(item.BrandCode)), item.BrandName))
+		}
+		if grupos[models.GrupoSegmento] {
+			ids = append(ids, novaSerie(models.GrupoSegmento, string(item.Segmento), string(item.Segmento)))
+		}
+		if grupos[models.GrupoIdade] {
+			faixa := faixaIdade(idadeVeiculo(item.Ano, anoBase))
+			ids = append(ids, novaSerie(models.GrupoIdade, faixa, faixa+" anos"))
+		}
+		cesta[item.Chave()] = &itemCesta{peso: 1, segmento: item.Segmento, series: ids}
+		anteriores[item.Chave()] = item.Preco
+		porSegmento[item.Segmento]++
+	}
+	if pesosSegmento != nil {
+		for chave, ic := range cesta {
+			ic.peso = pesosSegmento[ic.segmento] / float64(porSegmento[ic.segmento])
+			if ic.peso <= 0 {
+				delete(cesta, chave)
+				delete(anteriores, chave)
+			}
+		}
+	}
+	if len(cesta) == 0 {
+		return nil, fmt.Errorf("%w: nenhum ano-modelo com preço na tabela base", ErrIndiceInvalido)
+	}
+	for _, ic := range cesta {
+		for _, id := range ic.series {
+			series[id].serie.ItensCesta++
+		}
+	}
+	for id, s := range series {
+		if s.serie.ItensCesta == 0 {
+			delete(series, id)
+			continue
+		}
+		s.serie.Pontos = []models.PontoIndice{{TabelaId: base.Codigo, Ref: base.Mes, Periodo: models.PeriodoDoMes(base.Mes), Valor: valorBaseIndice, Itens: s.serie.ItensCesta}}
+	}
+
+	type elo struct {
+		somaPesos, somaLog float64
+		itens              int
+	}
+	for _, t := range tabelas[1:] {
+		itens, _, err := ItensTabela(ctx, t.Codigo, c.Filtro)
+		if err != nil {
+			return nil, err
+		}
+		atuais := precosPorChave(itens)
+		elos := make(map[string]*elo, len(series))
+		for chave, ic := range cesta {
+			p0, ok0 := anteriores[chave]
+			p1, ok1 := atuais[chave]
+			if !ok0 || !ok1 || p0 <= 0 || p1 <= 0 {
+				continue
+			}
+			relativo := math.Log(p1.Float64() / p0.Float64())
+			for _, id := range ic.series {
+				e, ok := elos[id]
+				if !ok {
+					e = &elo{}
+					elos[id] = e
+				}
+				e.somaPesos += ic.peso
+				e.somaLog += ic.peso * relativo
+				e.itens++
+			}
+		}
+		for id, s := range series {
+			ponto := models.PontoIndice{TabelaId: t.Codigo, Ref: t.Mes, Periodo: models.PeriodoDoMes(t.Mes)}
+			if e, ok := elos[id]; ok && e.somaPesos > 0 {
+				fator := math.Exp(e.somaLog / e.somaPesos)
+				s.valor *= fator
+				variacao := arredondar((fator - 1) * 100)
+				ponto.Variacao = &variacao
+				ponto.Itens = e.itens
+			}
+			ponto.Valor = math.Round(s.valor*10000) / 10000
+			s.serie.Pontos = append(s.serie.Pontos, ponto)
+		}
+
+		// Cada elo compara só tabelas consecutivas: itens ausentes nesta
+		// tabela ficam fora do próximo elo.
+		anteriores = make(map[models.ParVeiculo]models.Money, len(cesta))
+		for chave := range cesta {
+			if p, ok := atuais[chave]; ok {
+				anteriores[chave] = p
+			}
+		}
+	}
+
+	indice := &models.IndicePrecos{
+		Base:       models.TabelaComparada{TabelaId: base.Codigo, Ref: base.Mes, Periodo: models.PeriodoDoMes(base.Mes)},
+		Pesos:      c.Pesos,
+		ItensCesta: len(cesta),
+		Geral:      series[geral].serie,
+		Subindices: []models.SerieIndice{},
+	}
+	ordemGrupo := map[string]int{models.GrupoMarca: 0, models.GrupoSegmento: 1, models.GrupoIdade: 2}
+	for id, s := range series {
+		if id != geral {
+			indice.Subindices = append(indice.Subindices, s.serie)
+		}
+	}
+	sort.Slice(indice.Subindices, func(i, j int) bool {
+		a, b := indice.Subindices[i], indice.Subindices[j]
+		if a.Grupo != b.Grupo {
+			return ordemGrupo[a.Grupo] < ordemGrupo[b.Grupo]
+		}
+		if a.Grupo == models.GrupoIdade {
+			return ordemFaixa(a.Chave) < ordemFaixa(b.Chave)
+		}
+		return a.Nome < b.Nome
+	})
+	return indice, nil
+}
+
+// carregarPesosSegmento lê os pesos por segmento do arquivo de configuração
+// (INDICE_PESOS ou ArquivoPesosIndicePadrao). Sem arquivo, todos os segmentos
+// pesam igual; com arquivo, segmentos não listados ficam fora da cesta.
+func carregarPesosSegmento() (map[models.Segmento]float64, error) {
+	caminho := ArquivoPesosIndicePadrao
+	if c := os.Getenv("INDICE_PESOS"); c != "" {
+		caminho = c
+	}
+	dados, err := os.ReadFile(caminho)
+	if errors.Is(err, os.ErrNotExist) {
+		pesos := make(map[models.Segmento]float64, len(models.Segmentos))
+		for _, s := range models.Segmentos {
+			pesos[s] = 1
+		}
+		return pesos, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao ler pesos do índice em %s: %v", caminho, err)
+	}
+	var config models.ConfigIndice
+	if err := json.Unmarshal(dados, &config); err != nil {
+		return nil, fmt.Errorf("erro ao interpretar pesos do índice em %s: %v", caminho, err)
+	}
+	for s, peso := range config.PesosSegmento {
+		if !s.Valido() || peso < 0 {
+			return nil, fmt.Errorf("peso inválido no índice para o segmento '%s'", s)
+		}
+	}
+	return config.PesosSegmento, nil
+}
+
+var faixasIdade = []struct {
+	ate   int
+	faixa string
+}{{0, "0"}, {3, "1-3"}, {7, "4-7"}, {12, "8-12"}, {math.MaxInt, "13+"}}
+
+// faixaIdade agrupa a idade do veículo em anos para os subíndices.
+func faixaIdade(idade int) string {
+	for _, f := range faixasIdade {
+		if idade <= f.ate {
+			return f.faixa
+		}
+	}
+	return faixasIdade[len(faixasIdade)-1].faixa
+}
+
+func ordemFaixa(faixa string) int {
+	for i, f := range faixasIdade {
+		if f.faixa == faixa {
+			return i
+		}
+	}
+	return len(faixasIdade)
+}
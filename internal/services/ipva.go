@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+
+	"fipe_project/internal/ipva"
+	"fipe_project/internal/models"
+)
+
+// CalcularIPVA calcula o IPVA do ano-modelo na UF com o valor FIPE da tabela.
+// O exercício é o informado ou, se zero, o ano da tabela; a idade do veículo
+// é contada no exercício.
+func CalcularIPVA(ctx context.Context, uf string, tabelaId int, modeloId int, ano int32, exercicio int) (*models.CalculoIPVA, error) {
+	regras, err := ipva.Atual()
+	if err != nil {
+		return nil, err
+	}
+	marca, modelo, err := BuscarModelo(ctx, tabelaId, modeloId)
+	if err != nil {
+		return nil, err
+	}
+	anoModelo, ok := modelo.Ano(ano)
+	if !ok {
+		return nil, ErrNaoEncontrado
+	}
+	preco, err := anoModelo.Valor()
+	if err != nil {
+		return nil, err
+	}
+	ref, err := TabelaRef(ctx, tabelaId)
+	if err != nil {
+		return nil, err
+	}
+	if exercicio == 0 {
+		exercicio = anoReferencia(ref)
+	}
+	regra, versao, err := regras.Regra(uf, exercicio)
+	if err != nil {
+		return nil, err
+	}
+
+	detalhes := anoModelo.Detalhes()
+	calculo := &models.CalculoIPVA{
+		Versao:       versao.Versao,
+		Exercicio:    exercicio,
+		TabelaId:     tabelaId,
+		Ref:          ref,
+		Periodo:      models.PeriodoDoMes(ref),
+		BrandName:    marca.BrandName,
+		ModelCode:    modelo.ModelCode,
+		ModelName:    modelo.ModelName,
+		Ano:          detalhes,
+		Combustivel:  detalhes.Combustivel,
+		Idade:        idadeVeiculo(detalhes, exercicio),
+		ValorBase:    preco,
+		ValorBaseFmt: preco.String(),
+	}
+	ipva.Aplicar(regra, calculo)
+	return calculo, nil
+}
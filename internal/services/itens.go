@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+	"fipe_project/internal/segmentos"
+)
+
+// ItemTabela é um ano-modelo de uma tabela com segmento, código do ano e
+// preço já interpretados. É a unidade de agregação do dashboard e dos rankings.
+type ItemTabela struct {
+	BrandCode   int32
+	BrandName   string
+	ModelCode   int32
+	ModelName   string
+	Segmento    models.Segmento
+	Ano         models.CodigoAno
+	Preco       models.Money
+	PrecoValido bool
+	// PrecoTexto é o preço como gravado no documento.
+	PrecoTexto string
+}
+
+// Chave identifica o ano-modelo entre tabelas diferentes.
+func (i ItemTabela) Chave() models.ParVeiculo {
+	return models.ParVeiculo{ModelCode: i.ModelCode, Ano: i.Ano.AnoModelo}
+}
+
+// ItensTabela percorre os documentos de uma tabela e retorna os anos-modelo
+// que passam no filtro, além do nome de todas as marcas da tabela (ou da
+// marca filtrada).
+func ItensTabela(ctx context.Context, tabelaId int, filtro FiltroEstatisticas) ([]ItemTabela, map[int32]string, error) {
+	classificador, err := segmentos.Atual(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filter := bson.M{"monthYearId": tabelaId}
+	if filtro.Marca != nil {
+		filter["brandCode"] = *filtro.Marca
+	}
+	cursor, err := database.DB.Collection("Veiculos").Find(ctx, filter)
+	if err != nil {
+		return nil, nil, fmt.Errorf("erro ao buscar dados da tabela %d com filtro %v: %v", tabelaId, filter, err)
+	}
+	defer cursor.Close(ctx)
+
+	var itens []ItemTabela
+	brandNames := make(map[int32]string)
+	for cursor.Next(ctx) {
+		var marca models.Marca
+		if err := cursor.Decode(&marca); err != nil {
+			log.Printf("Erro ao decodificar documento da tabela %d: %v", tabelaId, err)
+			continue
+		}
+		if _, exists := brandNames[marca.BrandCode]; !exists {
+			brandNames[marca.BrandCode] = marca.BrandName
+		}
+		for _, modelo := range marca.Models {
+			segmento := classificador.Classificar(marca.BrandName, modelo.ModelName, modelo.ModelCode)
+			if filtro.Segmento != "" && segmento != filtro.Segmento {
+				continue
+			}
+			for _, y := range modelo.Years {
+				detalhes := y.Detalhes()
+				if !filtro.aceitaAno(detalhes) {
+					continue
+				}
+				item := ItemTabela{
+					BrandCode:  marca.BrandCode,
+					BrandName:  marca.BrandName,
+					ModelCode:  modelo.ModelCode,
+					ModelName:  modelo.ModelName,
+					Segmento:   segmento,
+					Ano:        detalhes,
+					PrecoTexto: y.Price,
+				}
+				if preco, err := y.Valor(); err == nil {
+					item.Preco, item.PrecoValido = preco, true
+				}
+				itens = append(itens, item)
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, nil, fmt.Errorf("erro no cursor da tabela %d: %v", tabelaId, err)
+	}
+	return itens, brandNames, nil
+}
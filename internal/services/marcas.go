@@ -0,0 +1,477 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/events"
+	"fipe_project/internal/models"
+)
+
+const (
+	colecaoCatalogoMarcas = "CatalogoMarcas"
+	colecaoMarcasGlobal   = "CatalogoMarcasGlobal"
+)
+
+// ordemMarcas ordena os nomes de marca em português, sem diferenciar
+// maiúsculas e acentos.
+var ordemMarcas = &options.Collation{Locale: "pt", Strength: 1}
+
+// CalcularCatalogoTabela resume as marcas de uma tabela a partir de Veiculos,
+// sem gravar. Documentos repetidos da mesma marca são somados, sem contar o
+// mesmo modelo duas vezes. O resultado vem ordenado por nome.
+func CalcularCatalogoTabela(ctx context.Context, tabelaId int) ([]models.MarcaCatalogo, error) {
+	marcas, err := CarregarTabela(ctx, tabelaId)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := TabelaRef(ctx, tabelaId)
+	if err != nil {
+		return nil, err
+	}
+
+	porCodigo := make(map[int32]*models.MarcaCatalogo)
+	modelosVistos := make(map[int32]map[int32]bool)
+	agora := time.Now()
+	for _, marca := range marcas {
+		entrada, ok := porCodigo[marca.BrandCode]
+		if !ok {
+			entrada = &models.MarcaCatalogo{
+				Id:           fmt.Sprintf("%d:%d", tabelaId, marca.BrandCode),
+				TabelaId:     tabelaId,
+				Ref:          ref,
+				Periodo:      models.PeriodoDoMes(ref),
+				BrandCode:    marca.BrandCode,
+				BrandName:    marca.BrandName,
+				AtualizadoEm: agora,
+			}
+			porCodigo[marca.BrandCode] = entrada
+			modelosVistos[marca.BrandCode] = make(map[int32]bool)
+		}
+		for _, modelo := range marca.Models {
+			if modelosVistos[marca.BrandCode][modelo.ModelCode] {
+				continue
+			}
+			modelosVistos[marca.BrandCode][modelo.ModelCode] = true
+			entrada.Modelos++
+			tem0km := false
+			for _, y := range modelo.Years {
+				entrada.AnosModelo++
+				zeroKm := y.Detalhes().ZeroKm
+				tem0km = tem0km || zeroKm
+				preco, err := y.Valor()
+				if err != nil {
+					continue
+				}
+				entrada.Precos = entrada.Precos.Incluir(preco)
+				if zeroKm {
+					entrada.Precos0km = entrada.Precos0km.Incluir(preco)
+				}
+			}
+			if tem0km {
+				entrada.Modelos0km++
+			}
+		}
+	}
+
+	catalogo := make([]models.MarcaCatalogo, 0, len(porCodigo))
+	for _, entrada := range porCodigo {
+		catalogo = append(catalogo, *entrada)
+	}
+	sort.Slice(catalogo, func(i, j int) bool {
+		a, b := strings.ToLower(catalogo[i].BrandName), strings.ToLower(catalogo[j].BrandName)
+		if a != b {
+			return a < b
+		}
+		return catalogo[i].BrandCode < catalogo[j].BrandCode
+	})
+	return catalogo, nil
+}
+
+// AtualizarCatalogoMarcas grava o catálogo de marcas da tabela, substituindo
+// o anterior, e recalcula o catálogo global das marcas afetadas. Roda na
+// publicação de cada tabela (ver alerts.PublicarTabela) e publica o evento
+// EstatisticasReconstruidas. Retorna quantas marcas a tabela tem.
+func AtualizarCatalogoMarcas(ctx context.Context, tabelaId int) (int, error) {
+	catalogo, err := CalcularCatalogoTabela(ctx, tabelaId)
+	if err != nil {
+		return 0, err
+	}
+	coll := database.DB.Collection(colecaoCatalogoMarcas)
+
+	anteriores, err := coll.Distinct(ctx, "brandCode", bson.M{"tabelaId": tabelaId})
+	if err != nil {
+		return 0, fmt.Errorf("erro ao buscar catálogo da tabela %d: %v", tabelaId, err)
+	}
+	afetadas := make(map[int32]bool, len(catalogo))
+	for _, codigo := range anteriores {
+		if c, ok := codigo.(int32); ok {
+			afetadas[c] = true
+		}
+	}
+
+	ids := make(bson.A, 0, len(catalogo))
+	for _, entrada := range catalogo {
+		afetadas[entrada.BrandCode] = true
+		ids = append(ids, entrada.Id)
+		_, err := coll.ReplaceOne(ctx, bson.M{"_id": entrada.Id}, entrada, options.Replace().SetUpsert(true))
+		if err != nil {
+			return 0, fmt.Errorf("erro ao gravar catálogo da marca %d na tabela %d: %v", entrada.BrandCode, tabelaId, err)
+		}
+	}
+	if _, err := coll.DeleteMany(ctx, bson.M{"tabelaId": tabelaId, "_id": bson.M{"$nin": ids}}); err != nil {
+		return 0, fmt.Errorf("erro ao limpar catálogo da tabela %d: %v", tabelaId, err)
+	}
+
+	codigos := make([]int32, 0, len(afetadas))
+	for codigo := range afetadas {
+		codigos = append(codigos, codigo)
+	}
+	if err := atualizarMarcasGlobal(ctx, codigos); err != nil {
+		return 0, err
+	}
+	log.Printf("Catálogo de marcas da tabela %d: %d marcas", tabelaId, len(catalogo))
+	if _, err := events.Publicar(ctx, events.EstatisticasReconstruidas, bson.M{"tabelaId": tabelaId, "marcas": len(catalogo)}); err != nil {
+		log.Printf("Catálogo de marcas: %v", err)
+	}
+	return len(catalogo), nil
+}
+
+// atualizarMarcasGlobal recalcula, a partir de CatalogoMarcas, o catálogo
+// global das marcas informadas. Marcas sem nenhuma tabela são removidas.
+func atualizarMarcasGlobal(ctx context.Context, codigos []int32) error {
+	if len(codigos) == 0 {
+		return nil
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"brandCode": bson.M{"$in": codigos}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "tabelaId", Value: -1}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":            "$brandCode",
+			"brandName":      bson.M{"$first": "$brandName"},
+			"primeiraTabela": bson.M{"$min": "$tabelaId"},
+			"ultimaTabela":   bson.M{"$max": "$tabelaId"},
+			"tabelas":        bson.M{"$sum": 1},
+			"atual":          bson.M{"$first": "$$ROOT"},
+		}}},
+	}
+	cursor, err := database.DB.Collection(colecaoCatalogoMarcas).Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("erro ao agregar catálogo global de marcas: %v", err)
+	}
+	var globais []models.MarcaGlobal
+	if err := cursor.All(ctx, &globais); err != nil {
+		return fmt.Errorf("erro ao decodificar catálogo global de marcas: %v", err)
+	}
+
+	coll := database.DB.Collection(colecaoMarcasGlobal)
+	encontradas := make(map[int32]bool, len(globais))
+	for _, g := range globais {
+		encontradas[g.BrandCode] = true
+		if _, err := coll.ReplaceOne(ctx, bson.M{"_id": g.BrandCode}, g, options.Replace().SetUpsert(true)); err != nil {
+			return fmt.Errorf("erro ao gravar marca %d no catálogo global: %v", g.BrandCode, err)
+		}
+	}
+	var removidas bson.A
+	for _, codigo := range codigos {
+		if !encontradas[codigo] {
+			removidas = append(removidas, codigo)
+		}
+	}
+	if len(removidas) > 0 {
+		if _, err := coll.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": removidas}}); err != nil {
+			return fmt.Errorf("erro ao remover marcas do catálogo global: %v", err)
+		}
+	}
+	return nil
+}
+
+// ListarMarcas retorna o catálogo de marcas da tabela, ordenado por nome. Uma
+// tabela ainda não catalogada (em ingestão, ou antes da migração do catálogo)
+// é resumida direto de Veiculos por resumirMarcas.
+func ListarMarcas(ctx context.Context, tabelaId int) ([]models.MarcaCatalogo, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "brandName", Value: 1}, {Key: "brandCode", Value: 1}}).SetCollation(ordemMarcas)
+	cursor, err := database.DB.Collection(colecaoCatalogoMarcas).Find(ctx, bson.M{"tabelaId": tabelaId}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar catálogo da tabela %d: %v", tabelaId, err)
+	}
+	catalogo := []models.MarcaCatalogo{}
+	if err := cursor.All(ctx, &catalogo); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar catálogo da tabela %d: %v", tabelaId, err)
+	}
+	if len(catalogo) > 0 {
+		return catalogo, nil
+	}
+	if catalogo, err = resumirMarcas(ctx, bson.M{"monthYearId": tabelaId}); err != nil {
+		return nil, err
+	}
+	sort.Slice(catalogo, func(i, j int) bool {
+		a, b := strings.ToLower(catalogo[i].BrandName), strings.ToLower(catalogo[j].BrandName)
+		if a != b {
+			return a < b
+		}
+		return catalogo[i].BrandCode < catalogo[j].BrandCode
+	})
+	return catalogo, nil
+}
+
+// resumirMarcas resume, sem gravar, as marcas dos documentos de Veiculos que
+// passam no filtro, uma entrada por tabela e marca, em ordem de tabela. É a
+// versão de consulta de CalcularCatalogoTabela, feita no banco com dois
+// $group (por modelo e por marca) para não carregar os documentos: os 0km
+// são reconhecidos pelo ano ou pelo código do ano, e as faixas de preço vêm
+// do priceCents gravado pela migração precos_centavos (ver centavosGravados).
+func resumirMarcas(ctx context.Context, filtro bson.M) ([]models.MarcaCatalogo, error) {
+	anos := bson.M{"$ifNull": bson.A{"$models.years", bson.A{}}}
+	zeroKm := bson.M{"$or": bson.A{
+		bson.M{"$eq": bson.A{"$$ano.year", models.AnoZeroKm}},
+		bson.M{"$eq": bson.A{bson.M{"$substrCP": bson.A{bson.M{"$ifNull": bson.A{"$$ano.yearCode", ""}}, 0, 6}}, strconv.Itoa(models.AnoZeroKm) + "-"}},
+		bson.M{"$eq": bson.A{"$$ano.yearCode", strconv.Itoa(models.AnoZeroKm)}},
+	}}
+	precos := func(so0km bool) bson.M {
+		preco := interface{}(centavosGravados("$ano."))
+		if so0km {
+			preco = bson.M{"$cond": bson.A{zeroKm, preco, nil}}
+		}
+		return bson.M{"$map": bson.M{"input": anos, "as": "ano", "in": preco}}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filtro}},
+		{{Key: "$project", Value: bson.M{"monthYearId": 1, "brandCode": 1, "brandName": 1, "models.modelCode": 1,
+			"models.years.year": 1, "models.years.yearCode": 1, "models.years.priceCents": 1}}},
+		{{Key: "$unwind", Value: "$models"}},
+		// Um modelo repetido em outro documento da marca conta uma vez só.
+		{{Key: "$group", Value: bson.M{
+			"_id":       bson.M{"tabela": "$monthYearId", "marca": "$brandCode", "modelo": "$models.modelCode"},
+			"brandName": bson.M{"$first": "$brandName"},
+			"anos":      bson.M{"$first": bson.M{"$size": anos}},
+			"zeroKm":    bson.M{"$first": bson.M{"$anyElementTrue": bson.A{bson.M{"$map": bson.M{"input": anos, "as": "ano", "in": zeroKm}}}}},
+			"minimo":    bson.M{"$first": bson.M{"$min": precos(false)}},
+			"maximo":    bson.M{"$first": bson.M{"$max": precos(false)}},
+			"minimo0km": bson.M{"$first": bson.M{"$min": precos(true)}},
+			"maximo0km": bson.M{"$first": bson.M{"$max": precos(true)}},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":        bson.M{"tabela": "$_id.tabela", "marca": "$_id.marca"},
+			"brandName":  bson.M{"$first": "$brandName"},
+			"modelos":    bson.M{"$sum": 1},
+			"anosModelo": bson.M{"$sum": "$anos"},
+			"modelos0km": bson.M{"$sum": bson.M{"$cond": bson.A{"$zeroKm", 1, 0}}},
+			"minimo":     bson.M{"$min": "$minimo"},
+			"maximo":     bson.M{"$max": "$maximo"},
+			"minimo0km":  bson.M{"$min": "$minimo0km"},
+			"maximo0km":  bson.M{"$max": "$maximo0km"},
+		}}},
+		{{Key: "$lookup", Value: bson.M{"from": "TabelaReferencia", "localField": "_id.tabela", "foreignField": "codigo", "as": "tabela"}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id.tabela", Value: 1}}}},
+	}
+	cursor, err := database.DB.Collection("Veiculos").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao resumir marcas de Veiculos: %v", err)
+	}
+	var grupos []struct {
+		Id struct {
+			Tabela int   `bson:"tabela"`
+			Marca  int32 `bson:"marca"`
+		} `bson:"_id"`
+		BrandName  string        `bson:"brandName"`
+		Modelos    int           `bson:"modelos"`
+		AnosModelo int           `bson:"anosModelo"`
+		Modelos0km int           `bson:"modelos0km"`
+		Minimo     *models.Money `bson:"minimo"`
+		Maximo     *models.Money `bson:"maximo"`
+		Minimo0km  *models.Money `bson:"minimo0km"`
+		Maximo0km  *models.Money `bson:"maximo0km"`
+		Tabela     []struct {
+			Mes string `bson:"mes"`
+		} `bson:"tabela"`
+	}
+	if err := cursor.All(ctx, &grupos); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar resumo das marcas: %v", err)
+	}
+
+	faixa := func(minimo, maximo *models.Money) *models.FaixaPreco {
+		if minimo == nil || maximo == nil {
+			return nil
+		}
+		return &models.FaixaPreco{Minimo: *minimo, MinimoFmt: minimo.String(), Maximo: *maximo, MaximoFmt: maximo.String()}
+	}
+	resumos := make([]models.MarcaCatalogo, 0, len(grupos))
+	for _, g := range grupos {
+		ref := fmt.Sprintf("Tabela %d", g.Id.Tabela)
+		if len(g.Tabela) > 0 {
+			ref = g.Tabela[0].Mes
+		}
+		resumos = append(resumos, models.MarcaCatalogo{
+			Id:         fmt.Sprintf("%d:%d", g.Id.Tabela, g.Id.Marca),
+			TabelaId:   g.Id.Tabela,
+			Ref:        ref,
+			Periodo:    models.PeriodoDoMes(ref),
+			BrandCode:  g.Id.Marca,
+			BrandName:  g.BrandName,
+			Modelos:    g.Modelos,
+			AnosModelo: g.AnosModelo,
+			Modelos0km: g.Modelos0km,
+			Precos:     faixa(g.Minimo, g.Maximo),
+			Precos0km:  faixa(g.Minimo0km, g.Maximo0km),
+		})
+	}
+	return resumos, nil
+}
+
+// ListarMarcasGlobal retorna todas as marcas catalogadas, ordenadas por nome.
+func ListarMarcasGlobal(ctx context.Context) ([]models.MarcaGlobal, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "brandName", Value: 1}, {Key: "_id", Value: 1}}).SetCollation(ordemMarcas)
+	cursor, err := database.DB.Collection(colecaoMarcasGlobal).Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar catálogo global de marcas: %v", err)
+	}
+	marcas := []models.MarcaGlobal{}
+	if err := cursor.All(ctx, &marcas); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar catálogo global de marcas: %v", err)
+	}
+	return marcas, nil
+}
+
+// DetalharMarca retorna a marca no catálogo global, seu resumo na tabela
+// informada (ou na mais recente, com tabelaId 0) e o histórico por tabela.
+// Uma marca que ainda não está no catálogo, ou uma tabela ainda não
+// catalogada, é resumida direto de Veiculos (ver resumirMarcas). Devolve
+// ErrNaoEncontrado se a marca não existir em Veiculos ou na tabela.
+func DetalharMarca(ctx context.Context, brandCode int32, tabelaId int) (*models.DetalheMarca, error) {
+	var detalhe models.DetalheMarca
+	err := database.DB.Collection(colecaoMarcasGlobal).FindOne(ctx, bson.M{"_id": brandCode}).Decode(&detalhe.Marca)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("erro ao buscar marca %d: %v", brandCode, err)
+	}
+	catalogada := err == nil
+
+	if catalogada {
+		opts := options.Find().SetSort(bson.D{{Key: "tabelaId", Value: 1}})
+		cursor, err := database.DB.Collection(colecaoCatalogoMarcas).Find(ctx, bson.M{"brandCode": brandCode}, opts)
+		if err != nil {
+			return nil, fmt.Errorf("erro ao buscar histórico da marca %d: %v", brandCode, err)
+		}
+		if err := cursor.All(ctx, &detalhe.Historico); err != nil {
+			return nil, fmt.Errorf("erro ao decodificar histórico da marca %d: %v", brandCode, err)
+		}
+	} else {
+		if detalhe.Historico, err = resumirMarcas(ctx, bson.M{"brandCode": brandCode}); err != nil {
+			return nil, err
+		}
+		if len(detalhe.Historico) == 0 {
+			return nil, fmt.Errorf("%w: marca %d", ErrNaoEncontrado, brandCode)
+		}
+		atual := detalhe.Historico[len(detalhe.Historico)-1]
+		detalhe.Marca = models.MarcaGlobal{
+			BrandCode:      brandCode,
+			BrandName:      atual.BrandName,
+			PrimeiraTabela: detalhe.Historico[0].TabelaId,
+			UltimaTabela:   atual.TabelaId,
+			Tabelas:        len(detalhe.Historico),
+			Atual:          atual,
+		}
+	}
+
+	if tabelaId == 0 {
+		detalhe.Tabela = detalhe.Marca.Atual
+		return &detalhe, nil
+	}
+	for _, entrada := range detalhe.Historico {
+		if entrada.TabelaId == tabelaId {
+			detalhe.Tabela = entrada
+			return &detalhe, nil
+		}
+	}
+	if catalogada {
+		resumo, err := resumirMarcas(ctx, bson.M{"monthYearId": tabelaId, "brandCode": brandCode})
+		if err != nil {
+			return nil, err
+		}
+		if len(resumo) > 0 {
+			detalhe.Tabela = resumo[0]
+			return &detalhe, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: marca %d na tabela %d", ErrNaoEncontrado, brandCode, tabelaId)
+}
+
+// ApagarCatalogoMarcas remove os dois catálogos e retorna quantos documentos
+// havia. Usada na reversão da migração do catálogo.
+func ApagarCatalogoMarcas(ctx context.Context, simular bool) (int64, error) {
+	var total int64
+	for _, nome := range []string{colecaoCatalogoMarcas, colecaoMarcasGlobal} {
+		coll := database.DB.Collection(nome)
+		n, err := coll.CountDocuments(ctx, bson.M{})
+		if err != nil {
+			return total, fmt.Errorf("erro ao contar %s: %v", nome, err)
+		}
+		total += n
+		if simular {
+			continue
+		}
+		if _, err := coll.DeleteMany(ctx, bson.M{}); err != nil {
+			return total, fmt.Errorf("erro ao apagar %s: %v", nome, err)
+		}
+	}
+	return total, nil
+}
+
+// TabelasSemCatalogo retorna, em ordem crescente, as tabelas presentes em
+// Veiculos que ainda não têm catálogo de marcas.
+func TabelasSemCatalogo(ctx context.Context) ([]int, error) {
+	tabelas, err := TabelasComVeiculos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	valores, err := database.DB.Collection(colecaoCatalogoMarcas).Distinct(ctx, "tabelaId", bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar tabelas do catálogo de marcas: %v", err)
+	}
+	catalogadas := make(map[int]bool, len(valores))
+	for _, v := range valores {
+		switch codigo := v.(type) {
+		case int32:
+			catalogadas[int(codigo)] = true
+		case int64:
+			catalogadas[int(codigo)] = true
+		}
+	}
+	pendentes := []int{}
+	for _, tabelaId := range tabelas {
+		if !catalogadas[tabelaId] {
+			pendentes = append(pendentes, tabelaId)
+		}
+	}
+	return pendentes, nil
+}
+
+// TabelasComVeiculos retorna os códigos das tabelas presentes em Veiculos,
+// em ordem crescente.
+func TabelasComVeiculos(ctx context.Context) ([]int, error) {
+	valores, err := database.DB.Collection("Veiculos").Distinct(ctx, "monthYearId", bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao listar tabelas de Veiculos: %v", err)
+	}
+	tabelas := make([]int, 0, len(valores))
+	for _, v := range valores {
+		switch codigo := v.(type) {
+		case int32:
+			tabelas = append(tabelas, int(codigo))
+		case int64:
+			tabelas = append(tabelas, int(codigo))
+		}
+	}
+	sort.Ints(tabelas)
+	return tabelas, nil
+}
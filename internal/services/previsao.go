@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"fipe_project/internal/models"
+	"fipe_project/internal/previsao"
+)
+
+const (
+	MaxMesesPrevisao        = 12
+	MaxHistoricoPrevisao    = 60
+	mesesPrevisaoPadrao     = 3
+	historicoPrevisaoPadrao = 36
+	confiancaPadrao         = 0.95
+)
+
+// ErrPrevisaoInvalida indica parâmetros fora dos limites em PreverPreco.
+var ErrPrevisaoInvalida = errors.New("consulta de previsão inválida")
+
+// ConsultaPrevisao descreve uma previsão: o ano-modelo, a tabela de origem,
+// quantos meses projetar (1 a 12), quantas tabelas de histórico usar e o
+// nível de confiança do intervalo (0.8, 0.9, 0.95 ou 0.99). Zeros assumem os
+// padrões.
+type ConsultaPrevisao struct {
+	TabelaId  int
+	ModeloId  int
+	Ano       int32
+	Meses     int
+	Historico int
+	Confianca float64
+}
+
+// PreverPreco projeta o preço do ano-modelo para as próximas tabelas com
+// cada método de previsao e avalia cada um por backtesting no histórico.
+func PreverPreco(ctx context.Context, c ConsultaPrevisao) (*models.PrevisaoPreco, error) {
+	if c.Meses == 0 {
+		c.Meses = mesesPrevisaoPadrao
+	}
+	if c.Historico == 0 {
+		c.Historico = historicoPrevisaoPadrao
+	}
+	if c.Confianca == 0 {
+		c.Confianca = confiancaPadrao
+	}
+	if c.Meses < 1 || c.Meses > MaxMesesPrevisao {
+		return nil, fmt.Errorf("%w: 'meses' deve estar entre 1 e %d", ErrPrevisaoInvalida, MaxMesesPrevisao)
+	}
+	if c.Historico < 2 || c.Historico > MaxHistoricoPrevisao {
+		return nil, fmt.Errorf("%w: 'historico' deve estar entre 2 e %d", ErrPrevisaoInvalida, MaxHistoricoPrevisao)
+	}
+	z, ok := previsao.Quantis[c.Confianca]
+	if !ok {
+		return nil, fmt.Errorf("%w: 'confianca' deve ser 0.8, 0.9, 0.95 ou 0.99", ErrPrevisaoInvalida)
+	}
+
+	marca, modelo, err := BuscarModelo(ctx, c.TabelaId, c.ModeloId)
+	if err != nil {
+		return nil, err
+	}
+	anoModelo, ok := modelo.Ano(c.Ano)
+	if !ok {
+		return nil, ErrNaoEncontrado
+	}
+	preco, err := anoModelo.Valor()
+	if err != nil {
+		return nil, err
+	}
+	ref, err := TabelaRef(ctx, c.TabelaId)
+	if err != nil {
+		return nil, err
+	}
+	historico, modelos, err := historicoModelo(ctx, c.ModeloId, c.Ano, c.TabelaId, c.Historico)
+	if err != nil {
+		return nil, err
+	}
+
+	taxa, padrao := taxaDepreciacaoModelo(*modelo)
+	resultado := &models.PrevisaoPreco{
+		TabelaId:         c.TabelaId,
+		Ref:              ref,
+		Periodo:          models.PeriodoDoMes(ref),
+		BrandCode:        marca.BrandCode,
+		BrandName:        marca.BrandName,
+		ModelCode:        modelo.ModelCode,
+		ModelName:        modelo.ModelName,
+		Ano:              anoModelo.Detalhes(),
+		PrecoAtual:       preco,
+		PrecoAtualFmt:    preco.String(),
+		Horizonte:        c.Meses,
+		Confianca:        c.Confianca,
+		DepreciacaoAnual: arredondar(taxa * 100),
+		TaxaPadrao:       padrao,
+		Historico:        historico,
+	}
+
+	// T é o código da tabela: os códigos são mensais e sequenciais, então
+	// tabelas ausentes viram lacunas e T mod 12 identifica o mês do ano.
+	// A curva de idade de cada tabela vem só dos preços daquela tabela: no
+	// backtesting, a origem não usa a curva da tabela pedida, posterior a ela.
+	var obs []previsao.Observacao
+	taxas := make(map[int]float64, len(historico))
+	for _, p := range historico {
+		if p.Disponivel {
+			obs = append(obs, previsao.Observacao{T: p.TabelaId, Valor: p.Valor.Float64()})
+			taxas[p.TabelaId], _ = taxaDepreciacaoModelo(modelos[p.TabelaId])
+		}
+	}
+	taxas[c.TabelaId] = taxa
+
+	metodos := []previsao.Metodo{
+		previsao.TendenciaLinear{},
+		previsao.TendenciaExponencial{},
+		previsao.Sazonal{},
+		previsao.DepreciacaoIdade{TaxaAnual: taxa, Taxas: taxas},
+	}
+	melhorMAPE := math.Inf(1)
+	for _, m := range metodos {
+		item := models.MetodoPrevisao{Metodo: m.Nome(), Descricao: m.Descricao(), Pontos: []models.PontoPrevisto{}}
+		pontos, err := m.Prever(obs, c.Meses, z)
+		if err != nil {
+			item.Motivo = err.Error()
+			if errors.Is(err, previsao.ErrDadosInsuficientes) {
+				item.Motivo = fmt.Sprintf("%v: são necessárias %d tabelas com preço, há %d", err, m.MinObservacoes(), len(obs))
+			}
+			resultado.Metodos = append(resultado.Metodos, item)
+			continue
+		}
+		item.Disponivel = true
+		for _, p := range pontos {
+			item.Pontos = append(item.Pontos, pontoPrevisto(c.TabelaId, p))
+		}
+		if met := previsao.Backtest(m, obs, c.Meses, z); met.Previsoes > 0 {
+			item.Backtest = &models.MetricasBacktest{
+				Previsoes: met.Previsoes,
+				MAE:       arredondar(met.MAE),
+				MAPE:      arredondar(met.MAPE),
+				RMSE:      arredondar(met.RMSE),
+				Cobertura: arredondar(met.Cobertura),
+			}
+			if met.MAPE < melhorMAPE {
+				melhorMAPE = met.MAPE
+				resultado.Recomendado = m.Nome()
+			}
+		}
+		// Sem backtesting em nenhum método, recomenda o primeiro disponível.
+		if resultado.Recomendado == "" {
+			resultado.Recomendado = m.Nome()
+		}
+		resultado.Metodos = append(resultado.Metodos, item)
+	}
+	return resultado, nil
+}
+
+func pontoPrevisto(tabelaId int, p previsao.Ponto) models.PontoPrevisto {
+	valor := models.MoneyFromFloat(p.Valor)
+	minimo := models.MoneyFromFloat(p.Minimo)
+	maximo := models.MoneyFromFloat(p.Maximo)
+	return models.PontoPrevisto{
+		Meses:     p.H,
+		TabelaId:  tabelaId + p.H,
+		Valor:     valor,
+		ValorFmt:  valor.String(),
+		Minimo:    minimo,
+		MinimoFmt: minimo.String(),
+		Maximo:    maximo,
+		MaximoFmt: maximo.String(),
+	}
+}
+
+func arredondar(v float64) float64 {
+	return math.Round(v*100) / 100
+}
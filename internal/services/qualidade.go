@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"fipe_project/internal/models"
+	"fipe_project/internal/utils"
+)
+
+// LimitesQualidadePadrao são os limiares usados quando a consulta não informa
+// outros e na exclusão de entradas sinalizadas do dashboard.
+var LimitesQualidadePadrao = models.LimitesQualidade{
+	FatorFamilia: 3,
+	MinFamilia:   4,
+	FatorAnos:    3,
+	SaltoMensal:  30,
+}
+
+// ErrQualidadeInvalida indica limiares fora do aceitável em AnalisarQualidade.
+var ErrQualidadeInvalida = errors.New("consulta de qualidade inválida")
+
+// AnalisarQualidade verifica os preços de uma tabela (ou de uma marca, se
+// informada) e lista as ocorrências: preços ilegíveis, outliers na família
+// do modelo e entre anos-modelo vizinhos, usados acima do 0km, saltos em
+// relação à tabela anterior e marcas que sumiram.
+func AnalisarQualidade(ctx context.Context, tabelaId int, marca *int32, limites models.LimitesQualidade) (*models.RelatorioQualidade, error) {
+	relatorio, _, err := analisarQualidade(ctx, tabelaId, marca, limites)
+	return relatorio, err
+}
+
+// EntradasSinalizadas retorna os anos-modelo da tabela com ao menos uma
+// ocorrência nos limites padrão.
+func EntradasSinalizadas(ctx context.Context, tabelaId int, marca *int32) (map[models.ParVeiculo]bool, error) {
+	_, sinalizadas, err := analisarQualidade(ctx, tabelaId, marca, LimitesQualidadePadrao)
+	return sinalizadas, err
+}
+
+func analisarQualidade(ctx context.Context, tabelaId int, marca *int32, limites models.LimitesQualidade) (*models.RelatorioQualidade, map[models.ParVeiculo]bool, error) {
+	if limites.FatorFamilia <= 1 || limites.FatorAnos <= 1 {
+		return nil, nil, fmt.Errorf("%w: os fatores devem ser maiores que 1", ErrQualidadeInvalida)
+	}
+	if limites.MinFamilia < 3 {
+		return nil, nil, fmt.Errorf("%w: a família mínima deve ter ao menos 3 entradas", ErrQualidadeInvalida)
+	}
+	if limites.SaltoMensal <= 0 {
+		return nil, nil, fmt.Errorf("%w: o salto mensal deve ser positivo", ErrQualidadeInvalida)
+	}
+
+	ref, err := TabelaRef(ctx, tabelaId)
+	if err != nil {
+		return nil, nil, err
+	}
+	filtro := FiltroEstatisticas{Marca: marca}
+	itens, brandNames, err := ItensTabela(ctx, tabelaId, filtro)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	relatorio := &models.RelatorioQualidade{
+		TabelaId:      tabelaId,
+		Ref:           ref,
+		Periodo:       models.PeriodoDoMes(ref),
+		Limites:       limites,
+		TotalEntradas: len(itens),
+		Totais:        make(map[models.TipoOcorrencia]int),
+		Ocorrencias:   []models.OcorrenciaQualidade{},
+	}
+	sinalizadas := make(map[models.ParVeiculo]bool)
+	registrar := func(o models.OcorrenciaQualidade, item *ItemTabela) {
+		if item != nil {
+			ano := item.Ano
+			o.BrandCode, o.BrandName = item.BrandCode, item.BrandName
+			o.ModelCode, o.ModelName = item.ModelCode, item.ModelName
+			o.Ano = &ano
+			o.PrecoTexto = item.PrecoTexto
+			if item.PrecoValido {
+				preco := item.Preco
+				o.Preco = &preco
+			}
+			sinalizadas[item.Chave()] = true
+		}
+		if o.Referencia != nil {
+			o.ReferenciaFmt = o.Referencia.String()
+		}
+		o.Razao = math.Round(o.Razao*100) / 100
+		relatorio.Totais[o.Tipo]++
+		relatorio.Ocorrencias = append(relatorio.Ocorrencias, o)
+	}
+
+	for i := range itens {
+		if _, err := models.ParseMoney(itens[i].PrecoTexto); errors.Is(err, models.ErrPrecoInvalido) {
+			registrar(models.OcorrenciaQualidade{
+				Tipo:    models.OcorrenciaPrecoInvalido,
+				Detalhe: fmt.Sprintf("preço ilegível: '%s'", itens[i].PrecoTexto),
+			}, &itens[i])
+		}
+	}
+	verificarFamilias(itens, limites, registrar)
+	verificarAnos(itens, limites, registrar)
+
+	tabelas, err := TabelasAte(ctx, tabelaId-1, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tabelas) == 1 {
+		anterior := tabelas[0]
+		relatorio.TabelaAnterior = &models.TabelaComparada{TabelaId: anterior.Codigo, Ref: anterior.Mes, Periodo: models.PeriodoDoMes(anterior.Mes)}
+		itensAnteriores, marcasAnteriores, err := ItensTabela(ctx, anterior.Codigo, filtro)
+		if err != nil {
+			return nil, nil, err
+		}
+		precosAnteriores := precosPorChave(itensAnteriores)
+		for i := range itens {
+			base, ok := precosAnteriores[itens[i].Chave()]
+			if !ok || !itens[i].PrecoValido || base <= 0 {
+				continue
+			}
+			razao := itens[i].Preco.Float64() / base.Float64()
+			if math.Abs(razao-1)*100 > limites.SaltoMensal {
+				registrar(models.OcorrenciaQualidade{
+					Tipo:       models.OcorrenciaSaltoMensal,
+					Referencia: &base,
+					Razao:      razao,
+					Detalhe:    fmt.Sprintf("variação de %.1f%% em relação a %s", (razao-1)*100, anterior.Mes),
+				}, &itens[i])
+			}
+		}
+		for brandCode, brandName := range marcasAnteriores {
+			if _, ok := brandNames[brandCode]; !ok {
+				registrar(models.OcorrenciaQualidade{
+					Tipo:      models.OcorrenciaMarcaAusente,
+					BrandCode: brandCode,
+					BrandName: brandName,
+					Detalhe:   fmt.Sprintf("marca presente em %s e ausente nesta tabela", anterior.Mes),
+				}, nil)
+			}
+		}
+	}
+
+	relatorio.Sinalizadas = len(sinalizadas)
+	sort.SliceStable(relatorio.Ocorrencias, func(i, j int) bool {
+		a, b := relatorio.Ocorrencias[i], relatorio.Ocorrencias[j]
+		if a.BrandName != b.BrandName {
+			return a.BrandName < b.BrandName
+		}
+		if a.ModelName != b.ModelName {
+			return a.ModelName < b.ModelName
+		}
+		return a.Tipo < b.Tipo
+	})
+	return relatorio, sinalizadas, nil
+}
+
+// verificarFamilias compara cada preço com a mediana da sua família: mesma
+// marca, mesmo primeiro nome de modelo ("gol", "onix") e mesmo ano-modelo.
+// Famílias menores que MinFamilia não têm mediana confiável e são ignoradas.
+func verificarFamilias(itens []ItemTabela, limites models.LimitesQualidade, registrar func(models.OcorrenciaQualidade, *ItemTabela)) {
+	type chaveFamilia struct {
+		brandCode int32
+		nome      string
+		ano       int32
+	}
+	familias := make(map[chaveFamilia][]int)
+	for i, item := range itens {
+		if !item.PrecoValido {
+			continue
+		}
+		nome, _, _ := strings.Cut(utils.NormalizarTexto(item.ModelName), " ")
+		chave := chaveFamilia{item.BrandCode, nome, item.Ano.AnoModelo}
+		familias[chave] = append(familias[chave], i)
+	}
+	for chave, indices := range familias {
+		if len(indices) < limites.MinFamilia {
+			continue
+		}
+		precos := make([]models.Money, len(indices))
+		for k, i := range indices {
+			precos[k] = itens[i].Preco
+		}
+		mediana := medianaPrecos(precos)
+		for _, i := range indices {
+			razao := itens[i].Preco.Float64() / mediana.Float64()
+			if razao > limites.FatorFamilia || razao < 1/limites.FatorFamilia {
+				registrar(models.OcorrenciaQualidade{
+					Tipo:       models.OcorrenciaOutlierFamilia,
+					Referencia: &mediana,
+					Razao:      razao,
+					Detalhe:    fmt.Sprintf("%.1fx a mediana de %d versões '%s'", razao, len(indices), chave.nome),
+				}, &itens[i])
+			}
+		}
+	}
+}
+
+// verificarAnos compara os anos-modelo de cada modelo entre si: um preço que
+// destoa dos dois anos vizinhos na mesma direção e usados mais caros que o
+// 0km do próprio modelo.
+func verificarAnos(itens []ItemTabela, limites models.LimitesQualidade, registrar func(models.OcorrenciaQualidade, *ItemTabela)) {
+	porModelo := make(map[int32][]int)
+	for i, item := range itens {
+		if item.PrecoValido {
+			porModelo[item.ModelCode] = append(porModelo[item.ModelCode], i)
+		}
+	}
+	for _, indices := range porModelo {
+		// O 0km (ano 32000) fica por último, como o ano-modelo mais novo.
+		sort.Slice(indices, func(a, b int) bool { return itens[indices[a]].Ano.AnoModelo < itens[indices[b]].Ano.AnoModelo })
+
+		for k := 1; k+1 < len(indices); k++ {
+			anterior, atual, seguinte := itens[indices[k-1]], itens[indices[k]], itens[indices[k+1]]
+			r1 := atual.Preco.Float64() / anterior.Preco.Float64()
+			r2 := atual.Preco.Float64() / seguinte.Preco.Float64()
+			acima := r1 > limites.FatorAnos && r2 > limites.FatorAnos
+			abaixo := r1 < 1/limites.FatorAnos && r2 < 1/limites.FatorAnos
+			if acima || abaixo {
+				referencia := models.Money((int64(anterior.Preco) + int64(seguinte.Preco)) / 2)
+				registrar(models.OcorrenciaQualidade{
+					Tipo:       models.OcorrenciaOutlierAnos,
+					Referencia: &referencia,
+					Razao:      atual.Preco.Float64() / referencia.Float64(),
+					Detalhe:    fmt.Sprintf("destoa dos anos-modelo vizinhos (%.1fx e %.1fx)", r1, r2),
+				}, &itens[indices[k]])
+			}
+		}
+
+		ultimo := itens[indices[len(indices)-1]]
+		if !ultimo.Ano.ZeroKm {
+			continue
+		}
+		preco0km := ultimo.Preco
+		for _, i := range indices[:len(indices)-1] {
+			if itens[i].Ano.ZeroKm || itens[i].Preco <= preco0km {
+				continue
+			}
+			registrar(models.OcorrenciaQualidade{
+				Tipo:       models.OcorrenciaAcima0km,
+				Referencia: &preco0km,
+				Razao:      itens[i].Preco.Float64() / preco0km.Float64(),
+				Detalhe:    "ano-modelo usado mais caro que o 0km",
+			}, &itens[i])
+		}
+	}
+}
+
+func medianaPrecos(precos []models.Money) models.Money {
+	ordenados := append([]models.Money(nil), precos...)
+	sort.Slice(ordenados, func(i, j int) bool { return ordenados[i] < ordenados[j] })
+	meio := len(ordenados) / 2
+	if len(ordenados)%2 == 1 {
+		return ordenados[meio]
+	}
+	return (ordenados[meio-1] + ordenados[meio]).Div(2)
+}
@@ -0,0 +1,379 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"fipe_project/internal/models"
+	"fipe_project/internal/utils"
+)
+
+const (
+	MaxItensRanking      = 100
+	itensRankingPadrao   = 10
+	MaxMesesVolatilidade = 36
+	mesesVolatilidade    = 12
+	// Mínimo de variações mensais para calcular a volatilidade de um ano-modelo.
+	minVariacoesVolatilidade = 2
+)
+
+// ErrRankingInvalido indica parâmetros inválidos em Rankings.
+var ErrRankingInvalido = errors.New("ranking inválido")
+
+// ConsultaRanking descreve um ranking. TabelaBaseId (variação) é, por padrão,
+// a tabela anterior a TabelaId; Meses (volatilidade) é a janela de tabelas.
+// IdadeMin e IdadeMax filtram pela idade do veículo em anos, sendo 0 o 0km.
+type ConsultaRanking struct {
+	TabelaId     int
+	TabelaBaseId int
+	Criterio     string
+	Ordem        string
+	Nivel        string
+	N            int
+	Meses        int
+	Filtro       FiltroEstatisticas
+	IdadeMin     *int
+	IdadeMax     *int
+}
+
+type candidatoRanking struct {
+	item      ItemTabela
+	idade     int
+	valor     float64
+	precoBase *models.Money
+	amostras  int
+}
+
+// Rankings ordena os anos-modelo da tabela (ou as marcas, pela média dos seus
+// anos-modelo) pelo critério pedido e devolve os N primeiros. Usa a mesma
+// agregação por ano-modelo do dashboard (ItensTabela).
+func Rankings(ctx context.Context, c ConsultaRanking) (*models.Ranking, error) {
+	if err := normalizarConsulta(&c); err != nil {
+		return nil, err
+	}
+
+	ref, err := TabelaRef(ctx, c.TabelaId)
+	if err != nil {
+		return nil, err
+	}
+	ranking := &models.Ranking{
+		Criterio: c.Criterio,
+		Ordem:    c.Ordem,
+		Nivel:    c.Nivel,
+		Tabela:   models.TabelaComparada{TabelaId: c.TabelaId, Ref: ref, Periodo: models.PeriodoDoMes(ref)},
+		Itens:    []models.ItemRanking{},
+	}
+
+	itens, _, err := ItensTabela(ctx, c.TabelaId, c.Filtro)
+	if err != nil {
+		return nil, err
+	}
+	anoTabela := anoReferencia(ref)
+
+	var candidatos []candidatoRanking
+	switch c.Criterio {
+	case models.CriterioPreco:
+		for _, item := range itens {
+			if item.PrecoValido {
+				candidatos = append(candidatos, candidatoRanking{item: item, valor: item.Preco.Float64()})
+			}
+		}
+
+	case models.CriterioVariacao, models.CriterioVariacaoAbsoluta:
+		base, err := tabelaBaseRanking(ctx, c)
+		if err != nil {
+			return nil, err
+		}
+		ranking.TabelaBase = base
+		if base == nil {
+			break
+		}
+		itensBase, _, err := ItensTabela(ctx, base.TabelaId, c.Filtro)
+		if err != nil {
+			return nil, err
+		}
+		precosBase := precosPorChave(itensBase)
+		for _, item := range itens {
+			precoBase, ok := precosBase[item.Chave()]
+			if !item.PrecoValido || !ok {
+				continue
+			}
+			candidato := candidatoRanking{item: item, precoBase: &precoBase}
+			if c.Criterio == models.CriterioVariacaoAbsoluta {
+				candidato.valor = item.Preco.Sub(precoBase).Float64()
+			} else if v, ok := utils.CalculatePercentageDiff(item.Preco.Float64(), precoBase.Float64()); ok {
+				candidato.valor = *v
+			} else {
+				continue
+			}
+			candidatos = append(candidatos, candidato)
+		}
+
+	case models.CriterioDepreciacao:
+		precos0km := make(map[int32]models.Money)
+		for _, item := range itens {
+			if item.Ano.ZeroKm && item.PrecoValido {
+				precos0km[item.ModelCode] = item.Preco
+			}
+		}
+		for _, item := range itens {
+			preco0km, ok := precos0km[item.ModelCode]
+			idade := idadeVeiculo(item.Ano, anoTabela)
+			if !item.PrecoValido || !ok || idade < 1 {
+				continue
+			}
+			taxa := (1 - math.Pow(item.Preco.Float64()/preco0km.Float64(), 1/float64(idade))) * 100
+			candidatos = append(candidatos, candidatoRanking{item: item, valor: taxa, precoBase: &preco0km})
+		}
+
+	case models.CriterioVolatilidade:
+		tabelas, err := TabelasAte(ctx, c.TabelaId, c.Meses)
+		if err != nil {
+			return nil, err
+		}
+		ranking.Tabelas = len(tabelas)
+		series := make([]map[models.ParVeiculo]models.Money, 0, len(tabelas))
+		for _, t := range tabelas {
+			if t.Codigo == c.TabelaId {
+				series = append(series, precosPorChave(itens))
+				continue
+			}
+			itensTabela, _, err := ItensTabela(ctx, t.Codigo, c.Filtro)
+			if err != nil {
+				return nil, err
+			}
+			series = append(series, precosPorChave(itensTabela))
+		}
+		for _, item := range itens {
+			if !item.PrecoValido {
+				continue
+			}
+			if desvio, n, ok := volatilidade(series, item.Chave()); ok {
+				candidatos = append(candidatos, candidatoRanking{item: item, valor: desvio, amostras: n})
+			}
+		}
+	}
+
+	filtrados := candidatos[:0]
+	for _, cand := range candidatos {
+		cand.idade = idadeVeiculo(cand.item.Ano, anoTabela)
+		if (c.IdadeMin != nil && cand.idade < *c.IdadeMin) || (c.IdadeMax != nil && cand.idade > *c.IdadeMax) {
+			continue
+		}
+		filtrados = append(filtrados, cand)
+	}
+
+	var posicoes []models.ItemRanking
+	if c.Nivel == models.NivelMarca {
+		posicoes = agruparPorMarca(filtrados, c.Criterio)
+	} else {
+		for _, cand := range filtrados {
+			idade := cand.idade
+			item := models.ItemRanking{
+				BrandCode:   cand.item.BrandCode,
+				BrandName:   cand.item.BrandName,
+				ModelCode:   cand.item.ModelCode,
+				ModelName:   cand.item.ModelName,
+				Ano:         cand.item.Ano.AnoModelo,
+				Combustivel: cand.item.Ano.Combustivel,
+				Segmento:    cand.item.Segmento,
+				Idade:       &idade,
+				Preco:       cand.item.Preco,
+				Valor:       cand.valor,
+				Amostras:    cand.amostras,
+			}
+			if cand.precoBase != nil {
+				item.PrecoBase = cand.precoBase
+				item.PrecoBaseFmt = cand.precoBase.String()
+			}
+			posicoes = append(posicoes, item)
+		}
+	}
+
+	sort.SliceStable(posicoes, func(i, j int) bool {
+		if posicoes[i].Valor != posicoes[j].Valor {
+			if c.Ordem == models.OrdemMenores {
+				return posicoes[i].Valor < posicoes[j].Valor
+			}
+			return posicoes[i].Valor > posicoes[j].Valor
+		}
+		if posicoes[i].BrandName != posicoes[j].BrandName {
+			return posicoes[i].BrandName < posicoes[j].BrandName
+		}
+		return posicoes[i].ModelName < posicoes[j].ModelName
+	})
+	ranking.Elegiveis = len(posicoes)
+	if len(posicoes) > c.N {
+		posicoes = posicoes[:c.N]
+	}
+	for i := range posicoes {
+		posicoes[i].Posicao = i + 1
+		posicoes[i].PrecoFmt = posicoes[i].Preco.String()
+		posicoes[i].ValorFmt = formatarValorRanking(c.Criterio, posicoes[i].Valor)
+	}
+	ranking.Itens = append(ranking.Itens, posicoes...)
+	return ranking, nil
+}
+
+func normalizarConsulta(c *ConsultaRanking) error {
+	switch c.Criterio {
+	case "":
+		c.Criterio = models.CriterioPreco
+	case models.CriterioPreco, models.CriterioVariacao, models.CriterioVariacaoAbsoluta,
+		models.CriterioDepreciacao, models.CriterioVolatilidade:
+	default:
+		return fmt.Errorf("%w: critério '%s' desconhecido", ErrRankingInvalido, c.Criterio)
+	}
+	switch c.Ordem {
+	case "":
+		c.Ordem = models.OrdemMaiores
+	case models.OrdemMaiores, models.OrdemMenores:
+	default:
+		return fmt.Errorf("%w: ordem deve ser '%s' ou '%s'", ErrRankingInvalido, models.OrdemMaiores, models.OrdemMenores)
+	}
+	switch c.Nivel {
+	case "":
+		c.Nivel = models.NivelModelo
+	case models.NivelModelo, models.NivelMarca:
+	default:
+		return fmt.Errorf("%w: nível deve ser '%s' ou '%s'", ErrRankingInvalido, models.NivelModelo, models.NivelMarca)
+	}
+	if c.N == 0 {
+		c.N = itensRankingPadrao
+	}
+	if c.N < 1 || c.N > MaxItensRanking {
+		return fmt.Errorf("%w: 'n' deve estar entre 1 e %d", ErrRankingInvalido, MaxItensRanking)
+	}
+	if c.Meses == 0 {
+		c.Meses = mesesVolatilidade
+	}
+	if c.Meses < minVariacoesVolatilidade+1 || c.Meses > MaxMesesVolatilidade {
+		return fmt.Errorf("%w: 'meses' deve estar entre %d e %d", ErrRankingInvalido, minVariacoesVolatilidade+1, MaxMesesVolatilidade)
+	}
+	if c.TabelaBaseId == c.TabelaId && c.TabelaBaseId != 0 {
+		return fmt.Errorf("%w: a tabela base deve ser diferente da tabela", ErrRankingInvalido)
+	}
+	return nil
+}
+
+// tabelaBaseRanking resolve a tabela de comparação dos critérios de variação:
+// a informada ou a imediatamente anterior. Devolve nil se não houver anterior.
+func tabelaBaseRanking(ctx context.Context, c ConsultaRanking) (*models.TabelaComparada, error) {
+	if c.TabelaBaseId != 0 {
+		ref, err := TabelaRef(ctx, c.TabelaBaseId)
+		if err != nil {
+			return nil, err
+		}
+		return &models.TabelaComparada{TabelaId: c.TabelaBaseId, Ref: ref, Periodo: models.PeriodoDoMes(ref)}, nil
+	}
+	tabelas, err := TabelasAte(ctx, c.TabelaId-1, 1)
+	if err != nil || len(tabelas) == 0 {
+		return nil, err
+	}
+	return &models.TabelaComparada{TabelaId: tabelas[0].Codigo, Ref: tabelas[0].Mes, Periodo: models.PeriodoDoMes(tabelas[0].Mes)}, nil
+}
+
+func precosPorChave(itens []ItemTabela) map[models.ParVeiculo]models.Money {
+	precos := make(map[models.ParVeiculo]models.Money, len(itens))
+	for _, item := range itens {
+		if item.PrecoValido {
+			precos[item.Chave()] = item.Preco
+		}
+	}
+	return precos
+}
+
+// volatilidade retorna o desvio-padrão das variações percentuais entre
+// tabelas consecutivas em que o ano-modelo tem preço, e quantas variações
+// foram usadas.
+func volatilidade(series []map[models.ParVeiculo]models.Money, chave models.ParVeiculo) (float64, int, bool) {
+	var variacoes []float64
+	for i := 1; i < len(series); i++ {
+		anterior, ok1 := series[i-1][chave]
+		atual, ok2 := series[i][chave]
+		if !ok1 || !ok2 {
+			continue
+		}
+		if v, ok := utils.CalculatePercentageDiff(atual.Float64(), anterior.Float64()); ok {
+			variacoes = append(variacoes, *v)
+		}
+	}
+	if len(variacoes) < minVariacoesVolatilidade {
+		return 0, 0, false
+	}
+	var soma float64
+	for _, v := range variacoes {
+		soma += v
+	}
+	media := soma / float64(len(variacoes))
+	var quadrados float64
+	for _, v := range variacoes {
+		quadrados += (v - media) * (v - media)
+	}
+	return math.Sqrt(quadrados / float64(len(variacoes))), len(variacoes), true
+}
+
+// agruparPorMarca resume os candidatos por marca: a métrica e o preço são as
+// médias dos anos-modelo da marca.
+func agruparPorMarca(candidatos []candidatoRanking, criterio string) []models.ItemRanking {
+	type acumulado struct {
+		item   models.ItemRanking
+		soma   float64
+		precos []models.Money
+	}
+	porMarca := make(map[int32]*acumulado)
+	var ordem []int32
+	for _, cand := range candidatos {
+		a, ok := porMarca[cand.item.BrandCode]
+		if !ok {
+			a = &acumulado{item: models.ItemRanking{BrandCode: cand.item.BrandCode, BrandName: cand.item.BrandName}}
+			porMarca[cand.item.BrandCode] = a
+			ordem = append(ordem, cand.item.BrandCode)
+		}
+		a.soma += cand.valor
+		a.precos = append(a.precos, cand.item.Preco)
+	}
+	itens := make([]models.ItemRanking, 0, len(ordem))
+	for _, codigo := range ordem {
+		a := porMarca[codigo]
+		a.item.Amostras = len(a.precos)
+		a.item.Valor = a.soma / float64(len(a.precos))
+		a.item.Preco, _ = models.Media(a.precos)
+		itens = append(itens, a.item)
+	}
+	return itens
+}
+
+func formatarValorRanking(criterio string, valor float64) string {
+	switch criterio {
+	case models.CriterioPreco, models.CriterioVariacaoAbsoluta:
+		return models.MoneyFromFloat(valor).String()
+	case models.CriterioDepreciacao:
+		return fmt.Sprintf("%.2f%% a.a.", valor)
+	case models.CriterioVolatilidade:
+		return fmt.Sprintf("%.2f%%", valor)
+	}
+	return fmt.Sprintf("%+.2f%%", valor)
+}
+
+// idadeVeiculo retorna a idade em anos do ano-modelo na data da tabela; 0km e
+// anos-modelo posteriores ao ano da tabela têm idade zero.
+func idadeVeiculo(ano models.CodigoAno, anoTabela int) int {
+	if ano.ZeroKm || int(ano.AnoModelo) >= anoTabela {
+		return 0
+	}
+	return anoTabela - int(ano.AnoModelo)
+}
+
+// anoReferencia extrai o ano do mês de referência ("março/2024 "). Sem mês
+// reconhecível, usa o ano corrente.
+func anoReferencia(ref string) int {
+	if data, ok := models.MesReferencia(ref); ok {
+		return data.Year()
+	}
+	return time.Now().Year()
+}
@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"fipe_project/internal/models"
+	"fipe_project/internal/utils"
+)
+
+const (
+	mesesHistoricoLaudo = 12
+	maxSimilaresLaudo   = 5
+	// Faixa de preço, em relação ao veículo avaliado, para considerar um modelo similar.
+	faixaSimilaridadeLaudo = 0.20
+)
+
+// LaudoVeiculo monta os dados do laudo de avaliação de um ano-modelo na tabela
+// informada: preço atual, histórico de 12 meses, depreciação comparada à média
+// da marca e modelos similares da mesma marca.
+func LaudoVeiculo(ctx context.Context, tabelaId int, modeloId int, ano int32) (*models.LaudoVeiculo, error) {
+	marca, modelo, err := BuscarModelo(ctx, tabelaId, modeloId)
+	if err != nil {
+		return nil, err
+	}
+	anoModelo, ok := modelo.Ano(ano)
+	if !ok {
+		return nil, ErrNaoEncontrado
+	}
+	preco, err := anoModelo.Valor()
+	if err != nil {
+		return nil, err
+	}
+
+	ref, err := TabelaRef(ctx, tabelaId)
+	if err != nil {
+		return nil, err
+	}
+
+	laudo := &models.LaudoVeiculo{
+		TabelaId:  tabelaId,
+		Ref:       ref,
+		EmitidoEm: time.Now(),
+		BrandName: marca.BrandName,
+		BrandCode: marca.BrandCode,
+		ModelName: modelo.ModelName,
+		ModelCode: modelo.ModelCode,
+		Ano:       ano,
+		Preco:     models.PriceInfo{Modelo: modelo.ModelName, Valor: preco, ValorFmt: preco.String()},
+	}
+
+	if ano != models.AnoZeroKm {
+		if zeroKm, ok := modelo.Ano(models.AnoZeroKm); ok {
+			if preco0km, err := zeroKm.Valor(); err == nil {
+				laudo.DepreciacaoVs0km, _ = utils.CalculatePercentageDiff(preco.Float64(), preco0km.Float64())
+			}
+		}
+	}
+
+	laudo.Historico, err = HistoricoPrecos(ctx, modeloId, ano, tabelaId, mesesHistoricoLaudo)
+	if err != nil {
+		return nil, err
+	}
+
+	statsAtual, _, err := EstatisticasMarcas(ctx, tabelaId, ref, FiltroEstatisticas{Marca: &marca.BrandCode})
+	if err != nil {
+		return nil, err
+	}
+	if stats, ok := statsAtual[marca.BrandCode]; ok {
+		laudo.EstatisticasMarca = *stats
+	} else {
+		laudo.EstatisticasMarca = *EstatisticasIndisponiveis(ref, tabelaId)
+	}
+
+	if len(laudo.Historico) > 1 {
+		inicio := laudo.Historico[0]
+		if inicio.Disponivel {
+			laudo.VariacaoModelo, _ = utils.CalculatePercentageDiff(preco.Float64(), inicio.Valor.Float64())
+		}
+		statsInicio, _, err := EstatisticasMarcas(ctx, inicio.TabelaId, inicio.Ref, FiltroEstatisticas{Marca: &marca.BrandCode})
+		if err != nil {
+			return nil, err
+		}
+		if stats, ok := statsInicio[marca.BrandCode]; ok && stats.TotalVeiculos0km > 0 && laudo.EstatisticasMarca.TotalVeiculos0km > 0 {
+			laudo.VariacaoMarca, _ = utils.CalculatePercentageDiff(laudo.EstatisticasMarca.ValorMedio0km.Float64(), stats.ValorMedio0km.Float64())
+		}
+	}
+
+	marcaCompleta, err := BuscarMarca(ctx, tabelaId, marca.BrandCode)
+	if err != nil {
+		return nil, err
+	}
+	laudo.Similares = modelosSimilares(marcaCompleta, modelo.ModelCode, ano, preco)
+
+	return laudo, nil
+}
+
+// modelosSimilares retorna os modelos da marca, no mesmo ano-modelo, com preço
+// próximo ao do veículo avaliado, ordenados pela proximidade de preço.
+func modelosSimilares(marca *models.Marca, modeloCode int32, ano int32, preco models.Money) []models.PriceInfo {
+	faixa := preco.Mul(faixaSimilaridadeLaudo)
+	var similares []models.PriceInfo
+	for _, m := range marca.Models {
+		if m.ModelCode == modeloCode {
+			continue
+		}
+		y, ok := m.Ano(ano)
+		if !ok {
+			continue
+		}
+		p, err := y.Valor()
+		if err != nil || distancia(p, preco) > faixa {
+			continue
+		}
+		similares = append(similares, models.PriceInfo{Modelo: m.ModelName, Valor: p, ValorFmt: p.String()})
+	}
+	sort.Slice(similares, func(i, j int) bool {
+		return distancia(similares[i].Valor, preco) < distancia(similares[j].Valor, preco)
+	})
+	if len(similares) > maxSimilaresLaudo {
+		similares = similares[:maxSimilaresLaudo]
+	}
+	return similares
+}
+
+func distancia(a, b models.Money) models.Money {
+	if a > b {
+		return a.Sub(b)
+	}
+	return b.Sub(a)
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"fipe_project/internal/models"
+	"fipe_project/internal/segmentos"
+)
+
+const MaxMesesResidual = 120
+
+// ErrResidualInvalido indica parâmetros fora dos limites em CalcularResidual.
+var ErrResidualInvalido = errors.New("consulta de valor residual inválida")
+
+// ConsultaResidual descreve o veículo e o horizonte: Meses a partir da tabela
+// ou DataAlvo no formato "2027-06".
+type ConsultaResidual struct {
+	TabelaId int
+	ModeloId int
+	Ano      int32
+	Meses    int
+	DataAlvo string
+}
+
+// CalcularResidual estima o valor do ano-modelo na data alvo pela curva de
+// depreciação do próprio modelo (os anos-modelo da tabela) e pela taxa do
+// segmento, e compara manter o veículo com trocá-lo agora pelo mesmo modelo
+// 0km. Quando a curva do modelo precisa ser extrapolada e há taxa de
+// segmento, o residual é a média das duas estimativas.
+func CalcularResidual(ctx context.Context, c ConsultaResidual) (*models.ValorResidual, error) {
+	marca, modelo, err := BuscarModelo(ctx, c.TabelaId, c.ModeloId)
+	if err != nil {
+		return nil, err
+	}
+	anoModelo, ok := modelo.Ano(c.Ano)
+	if !ok {
+		return nil, ErrNaoEncontrado
+	}
+	preco, err := anoModelo.Valor()
+	if err != nil {
+		return nil, err
+	}
+	ref, err := TabelaRef(ctx, c.TabelaId)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.DataAlvo != "" {
+		alvo, err := models.ParsePeriodo(c.DataAlvo)
+		if err != nil {
+			return nil, fmt.Errorf("%w: data alvo deve estar no formato AAAA-MM", ErrResidualInvalido)
+		}
+		inicio, ok := models.MesReferencia(ref)
+		if !ok {
+			return nil, fmt.Errorf("%w: mês de referência da tabela %d desconhecido, informe 'meses'", ErrResidualInvalido, c.TabelaId)
+		}
+		c.Meses = (alvo.Year()-inicio.Year())*12 + int(alvo.Month()-inicio.Month())
+	}
+	if c.Meses < 1 || c.Meses > MaxMesesResidual {
+		return nil, fmt.Errorf("%w: o horizonte deve estar entre 1 e %d meses após a tabela", ErrResidualInvalido, MaxMesesResidual)
+	}
+
+	classificador, err := segmentos.Atual(ctx)
+	if err != nil {
+		return nil, err
+	}
+	segmento := classificador.Classificar(marca.BrandName, modelo.ModelName, modelo.ModelCode)
+	taxaSegmento, modelosSegmento, err := taxaDepreciacaoSegmento(ctx, c.TabelaId, segmento)
+	if err != nil {
+		return nil, err
+	}
+
+	detalhes := anoModelo.Detalhes()
+	anoTabela := anoReferencia(ref)
+	idade := idadeVeiculo(detalhes, anoTabela)
+	anos := float64(c.Meses) / 12
+	curva := novaCurvaDepreciacao(*modelo, anoTabela)
+
+	resultado := &models.ValorResidual{
+		TabelaId:      c.TabelaId,
+		Ref:           ref,
+		Periodo:       models.PeriodoDoMes(ref),
+		BrandCode:     marca.BrandCode,
+		BrandName:     marca.BrandName,
+		ModelCode:     modelo.ModelCode,
+		ModelName:     modelo.ModelName,
+		Ano:           detalhes,
+		Segmento:      segmento,
+		Idade:         idade,
+		Meses:         c.Meses,
+		DataAlvo:      c.DataAlvo,
+		PrecoAtual:    preco,
+		PrecoAtualFmt: preco.String(),
+	}
+
+	// projetar aplica as duas estimativas a um preço de partida e escolhe o
+	// residual.
+	projetar := func(inicial models.Money, idade int) ([]models.EstimativaResidual, models.Money) {
+		fatorModelo, extrapolado := curva.fator(float64(idade), anos)
+		estimativas := []models.EstimativaResidual{estimativaResidual("modelo", inicial, fatorModelo, anos, extrapolado)}
+		residual := estimativas[0].Valor
+		if modelosSegmento > 0 {
+			seg := estimativaResidual("segmento", inicial, math.Pow(1-taxaSegmento, anos), anos, false)
+			estimativas = append(estimativas, seg)
+			if extrapolado {
+				residual = residual.Add(seg.Valor).Div(2)
+			}
+		}
+		return estimativas, residual
+	}
+
+	resultado.Estimativas, resultado.Residual = projetar(preco, idade)
+	resultado.ResidualFmt = resultado.Residual.String()
+	resultado.Manter = opcaoPosse(preco, resultado.Residual, c.Meses)
+
+	if zeroKm, ok := modelo.Ano(models.AnoZeroKm); ok {
+		if preco0km, err := zeroKm.Valor(); err == nil {
+			_, residualNovo := projetar(preco0km, 0)
+			novo := opcaoPosse(preco0km, residualNovo, c.Meses)
+			desembolso := preco0km.Sub(preco)
+			extra := novo.Depreciacao.Sub(resultado.Manter.Depreciacao)
+			resultado.Troca = &models.ComparacaoTroca{
+				Preco0km:            preco0km,
+				Preco0kmFmt:         preco0km.String(),
+				Desembolso:          desembolso,
+				DesembolsoFmt:       desembolso.String(),
+				Novo:                novo,
+				DepreciacaoExtra:    extra,
+				DepreciacaoExtraFmt: extra.String(),
+			}
+		}
+	}
+	return resultado, nil
+}
+
+func estimativaResidual(metodo string, inicial models.Money, fator, anos float64, extrapolado bool) models.EstimativaResidual {
+	valor := inicial.Mul(fator)
+	return models.EstimativaResidual{
+		Metodo:      metodo,
+		Valor:       valor,
+		ValorFmt:    valor.String(),
+		TaxaAnual:   arredondar((1 - math.Pow(fator, 1/anos)) * 100),
+		Extrapolado: extrapolado,
+	}
+}
+
+func opcaoPosse(inicial, residual models.Money, meses int) models.OpcaoPosse {
+	depreciacao := inicial.Sub(residual)
+	mensal := depreciacao.Div(int64(meses))
+	return models.OpcaoPosse{
+		ValorInicial:     inicial,
+		ValorInicialFmt:  inicial.String(),
+		ValorResidual:    residual,
+		ValorResidualFmt: residual.String(),
+		Depreciacao:      depreciacao,
+		DepreciacaoFmt:   depreciacao.String(),
+		CustoMensal:      mensal,
+		CustoMensalFmt:   mensal.String(),
+	}
+}
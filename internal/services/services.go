@@ -0,0 +1,8 @@
+// Package services concentra as consultas e regras de negócio sobre as
+// coleções da FIPE, compartilhadas entre os handlers HTTP e demais binários.
+package services
+
+import "errors"
+
+// ErrNaoEncontrado indica que o registro solicitado não existe na tabela informada.
+var ErrNaoEncontrado = errors.New("registro não encontrado")
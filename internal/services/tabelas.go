@@ -0,0 +1,325 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+)
+
+// TabelaRef retorna o mês de referência ("mes") de uma tabela. Se a tabela não
+// existir, devolve um rótulo genérico em vez de erro.
+func TabelaRef(ctx context.Context, tabelaId int) (string, error) {
+	coll := database.DB.Collection("TabelaReferencia")
+	var result struct {
+		Mes string `bson:"mes"`
+	}
+
+	filter := bson.M{"codigo": tabelaId}
+	err := coll.FindOne(ctx, filter).Decode(&result)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return fmt.Sprintf("Tabela %d", tabelaId), nil
+		}
+		return "", fmt.Errorf("erro ao buscar ref da tabela %d: %v", tabelaId, err)
+	}
+	return result.Mes, nil
+}
+
+// TabelasAte retorna até n tabelas de referência com código menor ou igual a
+// tabelaId, da mais antiga para a mais recente. Os códigos da FIPE são
+// sequenciais, então a ordem por código equivale à ordem cronológica.
+func TabelasAte(ctx context.Context, tabelaId int, n int) ([]models.TabelaReferencia, error) {
+	coll := database.DB.Collection("TabelaReferencia")
+	opts := options.Find().SetSort(bson.D{{Key: "codigo", Value: -1}}).SetLimit(int64(n))
+	cursor, err := coll.Find(ctx, bson.M{"codigo": bson.M{"$lte": tabelaId}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar tabelas até %d: %v", tabelaId, err)
+	}
+	var tabelas []models.TabelaReferencia
+	if err := cursor.All(ctx, &tabelas); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar tabelas até %d: %v", tabelaId, err)
+	}
+	for i, j := 0, len(tabelas)-1; i < j; i, j = i+1, j-1 {
+		tabelas[i], tabelas[j] = tabelas[j], tabelas[i]
+	}
+	return tabelas, nil
+}
+
+// UltimaTabelaComVeiculos retorna o código da tabela mais recente com
+// documentos em Veiculos, ou ErrNaoEncontrado se a coleção estiver vazia.
+// A tabela pode ainda estar em ingestão.
+func UltimaTabelaComVeiculos(ctx context.Context) (int, error) {
+	var maisRecente struct {
+		MonthYearId int `bson:"monthYearId"`
+	}
+	opts := options.FindOne().SetSort(bson.D{{Key: "monthYearId", Value: -1}}).SetProjection(bson.M{"monthYearId": 1})
+	err := database.DB.Collection("Veiculos").FindOne(ctx, bson.M{}, opts).Decode(&maisRecente)
+	if err == mongo.ErrNoDocuments {
+		return 0, ErrNaoEncontrado
+	}
+	if err != nil {
+		return 0, fmt.Errorf("erro ao buscar tabela mais recente: %v", err)
+	}
+	return maisRecente.MonthYearId, nil
+}
+
+// FiltroTabelas restringe a listagem de tabelas. Zero ou vazio não filtra.
+type FiltroTabelas struct {
+	AnoInicial int
+	AnoFinal   int
+	Status     string
+}
+
+// ListarTabelas retorna as tabelas de referência que têm veículos, da mais
+// recente para a mais antiga, com as contagens de Veiculos e a situação da
+// ingestão. As contagens vêm de TabelaReferencia (ver AtualizarContagemTabela);
+// só as tabelas ainda não contadas, em geral a que está em ingestão, são
+// contadas em Veiculos. Status e ano filtram a busca; tabelas sem o período
+// gravado têm o ano conferido aqui.
+func ListarTabelas(ctx context.Context, filtro FiltroTabelas) ([]models.TabelaResumo, error) {
+	consulta := bson.M{}
+	switch filtro.Status {
+	case models.StatusTabelaPublicada:
+		consulta["publicadaEm"] = bson.M{"$exists": true}
+	case models.StatusTabelaPendente:
+		consulta["publicadaEm"] = bson.M{"$exists": false}
+	}
+	if filtro.AnoInicial != 0 || filtro.AnoFinal != 0 {
+		faixa := bson.M{}
+		if filtro.AnoInicial != 0 {
+			faixa["$gte"] = filtro.AnoInicial
+		}
+		if filtro.AnoFinal != 0 {
+			faixa["$lte"] = filtro.AnoFinal
+		}
+		consulta["$or"] = bson.A{bson.M{"ano": faixa}, bson.M{"ano": bson.M{"$exists": false}}}
+	}
+	cursor, err := database.DB.Collection("TabelaReferencia").Find(ctx, consulta)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar tabelas de referência: %v", err)
+	}
+	var referencias []models.TabelaReferencia
+	if err := cursor.All(ctx, &referencias); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar tabelas de referência: %v", err)
+	}
+
+	var semContagem []int
+	for _, t := range referencias {
+		if t.Contagem == nil {
+			semContagem = append(semContagem, t.Codigo)
+		}
+	}
+	contagens := map[int]models.ContagemTabela{}
+	if len(semContagem) > 0 {
+		if contagens, err = contarVeiculos(ctx, semContagem); err != nil {
+			return nil, err
+		}
+	}
+
+	tabelas := []models.TabelaResumo{}
+	datas := make(map[int]time.Time, len(referencias))
+	for _, r := range referencias {
+		contagem := contagens[r.Codigo]
+		if r.Contagem != nil {
+			contagem = *r.Contagem
+		}
+		if contagem.Marcas == 0 {
+			continue
+		}
+		t := models.TabelaResumo{
+			Codigo: r.Codigo, Mes: r.Mes, Ano: r.Ano, NumeroMes: r.NumeroMes, Periodo: r.Periodo,
+			Marcas: contagem.Marcas, Modelos: contagem.Modelos, AnosModelo: contagem.AnosModelo,
+			PublicadaEm: r.PublicadaEm,
+		}
+		// Tabelas ainda não publicadas não têm o período gravado.
+		if t.Periodo == "" {
+			if data, ok := models.MesReferencia(t.Mes); ok {
+				t.Ano, t.NumeroMes, t.Periodo = data.Year(), int(data.Month()), data.Format(models.FormatoPeriodo)
+			}
+		}
+		if data, err := models.ParsePeriodo(t.Periodo); err == nil {
+			datas[t.Codigo] = data
+		}
+		t.Status = models.StatusTabelaPendente
+		if t.PublicadaEm != nil {
+			t.Status = models.StatusTabelaPublicada
+		}
+		if (filtro.AnoInicial != 0 && t.Ano < filtro.AnoInicial) ||
+			(filtro.AnoFinal != 0 && (t.Ano == 0 || t.Ano > filtro.AnoFinal)) {
+			continue
+		}
+		tabelas = append(tabelas, t)
+	}
+	// Tabelas com mês ilegível vão para o fim; entre datas iguais, vale o código.
+	sort.Slice(tabelas, func(i, j int) bool {
+		di, dj := datas[tabelas[i].Codigo], datas[tabelas[j].Codigo]
+		if !di.Equal(dj) {
+			return di.After(dj)
+		}
+		return tabelas[i].Codigo > tabelas[j].Codigo
+	})
+	return tabelas, nil
+}
+
+// contarVeiculos conta, em Veiculos, os documentos de marca, os modelos e as
+// entradas de ano de cada tabela informada. Tabelas sem veículos ficam fora
+// do mapa.
+func contarVeiculos(ctx context.Context, tabelaIds []int) (map[int]models.ContagemTabela, error) {
+	modelos := bson.M{"$ifNull": bson.A{"$models", bson.A{}}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"monthYearId": bson.M{"$in": tabelaIds}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":     "$monthYearId",
+			"marcas":  bson.M{"$sum": 1},
+			"modelos": bson.M{"$sum": bson.M{"$size": modelos}},
+			"anosModelo": bson.M{"$sum": bson.M{"$sum": bson.M{"$map": bson.M{
+				"input": modelos,
+				"as":    "m",
+				"in":    bson.M{"$size": bson.M{"$ifNull": bson.A{"$$m.years", bson.A{}}}},
+			}}}},
+		}}},
+	}
+	cursor, err := database.DB.Collection("Veiculos").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao contar veículos das tabelas: %v", err)
+	}
+	var grupos []struct {
+		Codigo                int `bson:"_id"`
+		models.ContagemTabela `bson:",inline"`
+	}
+	if err := cursor.All(ctx, &grupos); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar contagem das tabelas: %v", err)
+	}
+	contagens := make(map[int]models.ContagemTabela, len(grupos))
+	for _, g := range grupos {
+		contagens[g.Codigo] = g.ContagemTabela
+	}
+	return contagens, nil
+}
+
+// AtualizarContagemTabela grava em TabelaReferencia as contagens de Veiculos
+// da tabela, lidas por ListarTabelas.
+func AtualizarContagemTabela(ctx context.Context, tabelaId int) error {
+	contagens, err := contarVeiculos(ctx, []int{tabelaId})
+	if err != nil {
+		return err
+	}
+	_, err = database.DB.Collection("TabelaReferencia").UpdateOne(ctx,
+		bson.M{"codigo": tabelaId},
+		bson.M{"$set": bson.M{"contagem": contagens[tabelaId]}},
+	)
+	if err != nil {
+		return fmt.Errorf("erro ao gravar contagem da tabela %d: %v", tabelaId, err)
+	}
+	return nil
+}
+
+// MarcarTabelaPublicada registra em TabelaReferencia que a etapa pós-ingestão
+// da tabela rodou. A primeira publicação é mantida.
+func MarcarTabelaPublicada(ctx context.Context, tabelaId int) error {
+	_, err := database.DB.Collection("TabelaReferencia").UpdateOne(ctx,
+		bson.M{"codigo": tabelaId, "publicadaEm": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"publicadaEm": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("erro ao marcar tabela %d como publicada: %v", tabelaId, err)
+	}
+	return nil
+}
+
+// AtualizarPeriodoTabela grava em TabelaReferencia o mês interpretado da
+// tabela (ano, numeroMes e periodo). Um mês ilegível é só registrado no log.
+func AtualizarPeriodoTabela(ctx context.Context, tabelaId int) error {
+	coll := database.DB.Collection("TabelaReferencia")
+	var tabela models.TabelaReferencia
+	err := coll.FindOne(ctx, bson.M{"codigo": tabelaId}).Decode(&tabela)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("erro ao buscar tabela %d: %v", tabelaId, err)
+	}
+	data, ok := models.MesReferencia(tabela.Mes)
+	if !ok {
+		log.Printf("Mês de referência da tabela %d ilegível: '%s'", tabelaId, tabela.Mes)
+		return nil
+	}
+	_, err = coll.UpdateOne(ctx, bson.M{"codigo": tabelaId}, bson.M{"$set": bson.M{
+		"ano":       data.Year(),
+		"numeroMes": int(data.Month()),
+		"periodo":   data.Format(models.FormatoPeriodo),
+	}})
+	if err != nil {
+		return fmt.Errorf("erro ao gravar período da tabela %d: %v", tabelaId, err)
+	}
+	return nil
+}
+
+// TabelaMaisRecente é o valor do parâmetro 'tabela' que escolhe a tabela mais
+// recente com veículos.
+const TabelaMaisRecente = "latest"
+
+// ErrTabelaInvalida indica um parâmetro de tabela que não é código, período
+// "2024-03" nem TabelaMaisRecente.
+var ErrTabelaInvalida = errors.New("tabela inválida")
+
+// ResolverTabela converte o parâmetro 'tabela' das consultas em código: aceita
+// o código numérico, o período ("2024-03") ou TabelaMaisRecente. O código é
+// devolvido sem consultar o banco; os outros formatos devolvem
+// ErrNaoEncontrado se não houver tabela correspondente.
+func ResolverTabela(ctx context.Context, valor string) (int, error) {
+	valor = strings.TrimSpace(valor)
+	if codigo, err := strconv.Atoi(valor); err == nil {
+		return codigo, nil
+	}
+	if strings.EqualFold(valor, TabelaMaisRecente) {
+		return UltimaTabelaComVeiculos(ctx)
+	}
+	if _, err := models.ParsePeriodo(valor); err != nil {
+		return 0, fmt.Errorf("%w: '%s'", ErrTabelaInvalida, valor)
+	}
+	return tabelaDoPeriodo(ctx, valor)
+}
+
+// tabelaDoPeriodo busca a tabela do período pelo campo gravado e, se não
+// achar, pelo mês das tabelas que ainda não o têm. Havendo mais de uma tabela
+// no mês, vale a de maior código.
+func tabelaDoPeriodo(ctx context.Context, periodo string) (int, error) {
+	coll := database.DB.Collection("TabelaReferencia")
+	var tabela models.TabelaReferencia
+	opts := options.FindOne().SetSort(bson.D{{Key: "codigo", Value: -1}})
+	err := coll.FindOne(ctx, bson.M{"periodo": periodo}, opts).Decode(&tabela)
+	if err == nil {
+		return tabela.Codigo, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return 0, fmt.Errorf("erro ao buscar tabela do período %s: %v", periodo, err)
+	}
+
+	cursor, err := coll.Find(ctx, bson.M{"periodo": bson.M{"$exists": false}}, options.Find().SetSort(bson.D{{Key: "codigo", Value: -1}}))
+	if err != nil {
+		return 0, fmt.Errorf("erro ao buscar tabela do período %s: %v", periodo, err)
+	}
+	var semPeriodo []models.TabelaReferencia
+	if err := cursor.All(ctx, &semPeriodo); err != nil {
+		return 0, fmt.Errorf("erro ao decodificar tabelas: %v", err)
+	}
+	for _, t := range semPeriodo {
+		if models.PeriodoDoMes(t.Mes) == periodo {
+			return t.Codigo, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: tabela do período %s", ErrNaoEncontrado, periodo)
+}
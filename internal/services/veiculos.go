@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+)
+
+// BuscarMarca retorna o documento completo de uma marca em uma tabela.
+func BuscarMarca(ctx context.Context, tabelaId int, brandCode int32) (*models.Marca, error) {
+	collection := database.DB.Collection("Veiculos")
+	var marca models.Marca
+	err := collection.FindOne(ctx, bson.M{"monthYearId": tabelaId, "brandCode": brandCode}).Decode(&marca)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrNaoEncontrado
+	}
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar marca %d na tabela %d: %v", brandCode, tabelaId, err)
+	}
+	return &marca, nil
+}
+
+// BuscarModelo localiza um modelo em uma tabela. A marca retornada contém
+// apenas o modelo encontrado em Models.
+func BuscarModelo(ctx context.Context, tabelaId int, modeloId int) (*models.Marca, *models.Modelo, error) {
+	collection := database.DB.Collection("Veiculos")
+	filter := bson.M{"monthYearId": tabelaId, "models.modelCode": modeloId}
+	projection := options.FindOne().SetProjection(bson.M{
+		"monthYearId": 1, "brandCode": 1, "brandName": 1, "models.$": 1,
+	})
+
+	var marca models.Marca
+	err := collection.FindOne(ctx, filter, projection).Decode(&marca)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil, ErrNaoEncontrado
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("erro ao buscar modelo %d na tabela %d: %v", modeloId, tabelaId, err)
+	}
+	if len(marca.Models) == 0 {
+		return nil, nil, ErrNaoEncontrado
+	}
+	return &marca, &marca.Models[0], nil
+}
+
+// HistoricoPrecos retorna o preço de um ano-modelo nas últimas n tabelas até
+// tabelaId, da mais antiga para a mais recente. Tabelas em que o veículo não
+// aparece (ou tem preço inválido) são devolvidas com Disponivel=false.
+func HistoricoPrecos(ctx context.Context, modeloId int, ano int32, tabelaId int, n int) ([]models.PontoHistorico, error) {
+	historico, _, err := historicoModelo(ctx, modeloId, ano, tabelaId, n)
+	return historico, err
+}
+
+// historicoModelo é HistoricoPrecos devolvendo também o modelo completo em
+// cada tabela em que aparece, indexado pelo código da tabela.
+func historicoModelo(ctx context.Context, modeloId int, ano int32, tabelaId int, n int) ([]models.PontoHistorico, map[int]models.Modelo, error) {
+	tabelas, err := TabelasAte(ctx, tabelaId, n)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tabelas) == 0 {
+		return nil, nil, ErrNaoEncontrado
+	}
+
+	codigos := make([]int, len(tabelas))
+	for i, t := range tabelas {
+		codigos[i] = t.Codigo
+	}
+
+	collection := database.DB.Collection("Veiculos")
+	filter := bson.M{"monthYearId": bson.M{"$in": codigos}, "models.modelCode": modeloId}
+	projection := options.Find().SetProjection(bson.M{"monthYearId": 1, "models.$": 1})
+	cursor, err := collection.Find(ctx, filter, projection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("erro ao buscar histórico do modelo %d: %v", modeloId, err)
+	}
+	var docs []models.Marca
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, nil, fmt.Errorf("erro ao decodificar histórico do modelo %d: %v", modeloId, err)
+	}
+
+	precos := make(map[int]models.AnoModelo, len(docs))
+	modelos := make(map[int]models.Modelo, len(docs))
+	for _, doc := range docs {
+		if len(doc.Models) == 0 {
+			continue
+		}
+		modelos[doc.MonthYearId] = doc.Models[0]
+		if y, ok := doc.Models[0].Ano(ano); ok {
+			precos[doc.MonthYearId] = y
+		}
+	}
+
+	historico := make([]models.PontoHistorico, 0, len(tabelas))
+	for _, t := range tabelas {
+		ponto := models.PontoHistorico{TabelaId: t.Codigo, Ref: t.Mes, Periodo: models.PeriodoDoMes(t.Mes)}
+		if y, ok := precos[t.Codigo]; ok {
+			if price, err := y.Valor(); err == nil {
+				ponto.Valor = price
+				ponto.ValorFmt = price.String()
+				ponto.Disponivel = true
+			}
+		}
+		historico = append(historico, ponto)
+	}
+	return historico, modelos, nil
+}
+
+// CarregarTabela retorna todos os documentos de marcas de uma tabela.
+func CarregarTabela(ctx context.Context, tabelaId int) ([]models.Marca, error) {
+	collection := database.DB.Collection("Veiculos")
+	cursor, err := collection.Find(ctx, bson.M{"monthYearId": tabelaId})
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar marcas da tabela %d: %v", tabelaId, err)
+	}
+	var marcas []models.Marca
+	if err := cursor.All(ctx, &marcas); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar marcas da tabela %d: %v", tabelaId, err)
+	}
+	return marcas, nil
+}
+
+// ContarMarcas retorna quantos documentos de marca a tabela tem em Veiculos.
+func ContarMarcas(ctx context.Context, tabelaId int) (int64, error) {
+	contagem, err := database.DB.Collection("Veiculos").CountDocuments(ctx, bson.M{"monthYearId": tabelaId})
+	if err != nil {
+		return 0, fmt.Errorf("erro ao contar marcas da tabela %d: %v", tabelaId, err)
+	}
+	return contagem, nil
+}
+
+// GravarCombustivelTabela grava em fuel o combustível do código do ano nas
+// entradas da tabela em que ele falta ou diverge, como a migração
+// combustivel_codigo_ano, mas só para a tabela informada. Roda na etapa
+// pós-ingestão, para que o filtro de combustível resolvido no banco valha
+// para a tabela nova sem esperar a migração. Devolve quantos documentos
+// foram alterados. O filtro de cada update confere o modelo e o ano nas
+// posições alteradas, para não gravar num documento que mudou desde a
+// leitura.
+func GravarCombustivelTabela(ctx context.Context, tabelaId int) (int64, error) {
+	coll := database.DB.Collection("Veiculos")
+	cursor, err := coll.Find(ctx, bson.M{"monthYearId": tabelaId, "models.years.yearCode": bson.M{"$exists": true}})
+	if err != nil {
+		return 0, fmt.Errorf("erro ao buscar veículos da tabela %d: %v", tabelaId, err)
+	}
+	defer cursor.Close(ctx)
+
+	var operacoes []mongo.WriteModel
+	for cursor.Next(ctx) {
+		var doc struct {
+			Id           interface{} `bson:"_id"`
+			models.Marca `bson:",inline"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return 0, fmt.Errorf("erro ao decodificar veículos da tabela %d: %v", tabelaId, err)
+		}
+		filtro := bson.M{"_id": doc.Id}
+		set := bson.M{}
+		for i, modelo := range doc.Models {
+			for j, ano := range modelo.Years {
+				combustivel, ok := ano.CombustivelDoCodigo()
+				if !ok {
+					continue
+				}
+				caminho := fmt.Sprintf("models.%d.years.%d.", i, j)
+				filtro[fmt.Sprintf("models.%d.modelCode", i)] = modelo.ModelCode
+				filtro[caminho+"year"] = ano.Year
+				set[caminho+"fuel"] = combustivel
+			}
+		}
+		if len(set) > 0 {
+			operacoes = append(operacoes, mongo.NewUpdateOneModel().SetFilter(filtro).SetUpdate(bson.M{"$set": set}))
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, fmt.Errorf("erro no cursor de veículos da tabela %d: %v", tabelaId, err)
+	}
+	if len(operacoes) == 0 {
+		return 0, nil
+	}
+	res, err := coll.BulkWrite(ctx, operacoes, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		return 0, fmt.Errorf("erro ao gravar combustível da tabela %d: %v", tabelaId, err)
+	}
+	return res.ModifiedCount, nil
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"fipe_project/internal/database"
+	"fipe_project/internal/models"
+	"fipe_project/internal/segmentos"
+)
+
+// filtroZeroKm seleciona as entradas de ano 0km pelo ano ou pelo código do
+// ano. É um pré-filtro: a decisão final é de models.AnoModelo.Detalhes, que
+// também trata documentos com "year" e "yearCode" divergentes.
+func filtroZeroKm(prefixo string) bson.M {
+	return bson.M{"$or": bson.A{
+		bson.M{prefixo + "year": models.AnoZeroKm},
+		bson.M{prefixo + "yearCode": bson.M{"$regex": "^" + strconv.Itoa(models.AnoZeroKm) + "(-|$)"}},
+	}}
+}
+
+// pipelineZeroKm são os estágios comuns às consultas de 0km: seleciona os
+// documentos da tabela (e da marca) com alguma entrada 0km, desdobra modelos
+// e anos e mantém só as entradas 0km. Marca, combustível e segmento que não
+// podem ser decididos no banco ficam para quem consome o resultado.
+func pipelineZeroKm(tabelaId int, marca *int32) mongo.Pipeline {
+	filtro := filtroZeroKm("models.years.")
+	filtro["monthYearId"] = tabelaId
+	if marca != nil {
+		filtro["brandCode"] = *marca
+	}
+	return mongo.Pipeline{
+		{{Key: "$match", Value: filtro}},
+		{{Key: "$project", Value: bson.M{"brandCode": 1, "brandName": 1, "models.modelCode": 1, "models.modelName": 1, "models.years": 1}}},
+		{{Key: "$unwind", Value: "$models"}},
+		{{Key: "$unwind", Value: "$models.years"}},
+		{{Key: "$match", Value: filtroZeroKm("models.years.")}},
+	}
+}
+
+// centavosGravados é o preço em centavos gravado pela migração
+// precos_centavos (priceCents) quando positivo; null quando ausente, e a
+// entrada fica para models.AnoModelo.Valor. O banco não interpreta o texto
+// do preço: o único parser é models.ParseMoney, usado pela migração e pelo
+// Go. A migração também corrige priceCents que divergem do texto.
+func centavosGravados(prefixo string) bson.M {
+	return bson.M{"$cond": bson.A{
+		bson.M{"$and": bson.A{
+			bson.M{"$in": bson.A{bson.M{"$type": "$" + prefixo + "priceCents"}, bson.A{"long", "int"}}},
+			bson.M{"$gt": bson.A{"$" + prefixo + "priceCents", 0}},
+		}},
+		"$" + prefixo + "priceCents",
+		nil,
+	}}
+}
+
+// combustivelGravado é o combustível da entrada 0km gravado pela migração
+// combustivel_codigo_ano (fuel), quando é um identificador da API. Uma
+// entrada sem fuel e sem código de ano tem combustível desconhecido (""),
+// como em models.AnoModelo.Detalhes. Nos demais casos é null e a entrada
+// fica para o Go. Só vale para entradas que o banco reconhece como 0km sem
+// interpretar o código: "32000-<n>" ou, sem código, o ano 32000.
+func combustivelGravado(prefixo string) bson.M {
+	ids := bson.A{}
+	for _, c := range models.Combustiveis() {
+		ids = append(ids, string(c))
+	}
+	semCodigo := bson.M{"$eq": bson.A{bson.M{"$type": "$" + prefixo + "yearCode"}, "missing"}}
+	zeroKm := bson.M{"$or": bson.A{
+		bson.M{"$and": bson.A{semCodigo, bson.M{"$eq": bson.A{"$" + prefixo + "year", models.AnoZeroKm}}}},
+		bson.M{"$eq": bson.A{bson.M{"$substrCP": bson.A{bson.M{"$ifNull": bson.A{"$" + prefixo + "yearCode", ""}}, 0, 6}}, strconv.Itoa(models.AnoZeroKm) + "-"}},
+	}}
+	return bson.M{"$cond": bson.A{
+		zeroKm,
+		bson.M{"$switch": bson.M{
+			"branches": bson.A{
+				bson.M{"case": bson.M{"$in": bson.A{"$" + prefixo + "fuel", ids}}, "then": "$" + prefixo + "fuel"},
+				bson.M{"case": bson.M{"$and": bson.A{
+					semCodigo,
+					bson.M{"$eq": bson.A{bson.M{"$type": "$" + prefixo + "fuel"}, "missing"}},
+				}}, "then": ""},
+			},
+			"default": nil,
+		}},
+		nil,
+	}}
+}
+
+// precoModelo é o menor ou o maior preço 0km de uma marca, com o modelo.
+type precoModelo struct {
+	Valor  models.Money `bson:"valor"`
+	Modelo string       `bson:"modelo"`
+}
+
+// estatisticasZeroKm calcula as estatísticas 0km de cada marca da tabela com
+// um $group: total de anos-modelo ($sum), soma ($sum), menor e maior preço
+// ($min e $max). A média sai da soma exata, com o arredondamento de
+// Money.Div. Só entram no $group as entradas com combustível e preço gravados
+// pelas migrações (ver combustivelGravado e centavosGravados), todas com
+// preço válido; as demais, e todas quando o filtro tem segmento ou exclui
+// sinalizados, vêm em "pendentes" e são acumuladas em Go. Em empate de preço
+// no banco, o menor fica com o modelo de menor nome e o maior, com o de maior
+// nome.
+func estatisticasZeroKm(ctx context.Context, tabelaId int, tabelaRef string, filtro FiltroEstatisticas) (map[int32]*models.BrandPeriodStats, error) {
+	classificador, err := segmentos.Atual(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var noBanco interface{} = false
+	if filtro.Segmento == "" && !filtro.ExcluirSinalizados {
+		noBanco = bson.M{"$and": bson.A{
+			bson.M{"$gt": bson.A{"$combustivel", nil}},
+			bson.M{"$gt": bson.A{"$centavos", nil}},
+		}}
+	}
+	somente := func(valor interface{}) bson.M {
+		return bson.M{"$cond": bson.A{"$noBanco", valor, "$$REMOVE"}}
+	}
+	pipeline := append(pipelineZeroKm(tabelaId, filtro.Marca),
+		bson.D{{Key: "$addFields", Value: bson.M{
+			"combustivel": combustivelGravado("models.years."),
+			"centavos":    centavosGravados("models.years."),
+		}}},
+	)
+	if filtro.Combustivel != models.CombustivelDesconhecido {
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: bson.M{"combustivel": bson.M{"$in": bson.A{nil, string(filtro.Combustivel)}}}}})
+	}
+	pipeline = append(pipeline,
+		bson.D{{Key: "$addFields", Value: bson.M{"noBanco": noBanco}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":          "$brandCode",
+			"brandName":    bson.M{"$first": "$brandName"},
+			"totalModelos": bson.M{"$sum": bson.M{"$cond": bson.A{"$noBanco", 1, 0}}},
+			"soma":         bson.M{"$sum": somente("$centavos")},
+			"menor":        bson.M{"$min": somente(bson.D{{Key: "valor", Value: "$centavos"}, {Key: "modelo", Value: "$models.modelName"}})},
+			"maior":        bson.M{"$max": somente(bson.D{{Key: "valor", Value: "$centavos"}, {Key: "modelo", Value: "$models.modelName"}})},
+			"modelos":      bson.M{"$addToSet": somente("$models.modelCode")},
+			"pendentes": bson.M{"$push": bson.M{"$cond": bson.A{"$noBanco", "$$REMOVE", bson.M{
+				"modelCode": "$models.modelCode",
+				"modelName": "$models.modelName",
+				"ano":       "$models.years",
+			}}}},
+		}}},
+	)
+	cursor, err := database.DB.Collection("Veiculos").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao agregar veículos 0km da tabela %d: %v", tabelaId, err)
+	}
+	var grupos []struct {
+		BrandCode    int32        `bson:"_id"`
+		BrandName    string       `bson:"brandName"`
+		TotalModelos int          `bson:"totalModelos"`
+		Soma         models.Money `bson:"soma"`
+		Menor        *precoModelo `bson:"menor"`
+		Maior        *precoModelo `bson:"maior"`
+		Modelos      []int32      `bson:"modelos"`
+		Pendentes    []struct {
+			ModelCode int32            `bson:"modelCode"`
+			ModelName string           `bson:"modelName"`
+			Ano       models.AnoModelo `bson:"ano"`
+		} `bson:"pendentes"`
+	}
+	if err := cursor.All(ctx, &grupos); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar veículos 0km da tabela %d: %v", tabelaId, err)
+	}
+
+	resultado := make(map[int32]*models.BrandPeriodStats, len(grupos))
+	var pendentes []ItemTabela
+	for _, g := range grupos {
+		stats := novasEstatisticas(tabelaRef, tabelaId)
+		// As entradas acumuladas no banco têm todas preço válido.
+		stats.TotalModelos = g.TotalModelos
+		stats.TotalVeiculos0km = g.TotalModelos
+		stats.SomaValores0km = g.Soma
+		for _, codigo := range g.Modelos {
+			stats.ModelosEncontrados[codigo] = struct{}{}
+		}
+		if g.Menor != nil && g.Maior != nil {
+			stats.MenorPreco0km = models.PriceInfo{Modelo: g.Menor.Modelo, Valor: g.Menor.Valor, ValorFmt: g.Menor.Valor.String()}
+			stats.MaiorPreco0km = models.PriceInfo{Modelo: g.Maior.Modelo, Valor: g.Maior.Valor, ValorFmt: g.Maior.Valor.String()}
+			stats.Inicializado = true
+		}
+		resultado[g.BrandCode] = stats
+
+		for _, e := range g.Pendentes {
+			detalhes := e.Ano.Detalhes()
+			if !detalhes.ZeroKm || !filtro.aceitaAno(detalhes) {
+				continue
+			}
+			segmento := classificador.Classificar(g.BrandName, e.ModelName, e.ModelCode)
+			if filtro.Segmento != "" && segmento != filtro.Segmento {
+				continue
+			}
+			item := ItemTabela{
+				BrandCode:  g.BrandCode,
+				BrandName:  g.BrandName,
+				ModelCode:  e.ModelCode,
+				ModelName:  e.ModelName,
+				Segmento:   segmento,
+				Ano:        detalhes,
+				PrecoTexto: e.Ano.Price,
+			}
+			if preco, err := e.Ano.Valor(); err == nil {
+				item.Preco, item.PrecoValido = preco, true
+			}
+			pendentes = append(pendentes, item)
+		}
+	}
+	if pendentes, err = excluirSinalizados(ctx, tabelaId, filtro, pendentes); err != nil {
+		return nil, err
+	}
+	for _, item := range pendentes {
+		acumularItem(resultado[item.BrandCode], item)
+	}
+	return resultado, nil
+}
+
+// EntradaZeroKm é uma entrada de ano 0km com a marca e o modelo. Ano mantém
+// os campos do documento, como lidos.
+type EntradaZeroKm struct {
+	BrandName string `bson:"brandName"`
+	ModelCode int32  `bson:"modelCode"`
+	ModelName string `bson:"modelName"`
+	Ano       bson.M `bson:"ano"`
+}
+
+// EntradasZeroKm retorna as entradas 0km de uma tabela, na ordem dos
+// documentos. O preço em centavos gravado pelas migrações não é devolvido.
+func EntradasZeroKm(ctx context.Context, tabelaId int) ([]EntradaZeroKm, error) {
+	pipeline := append(pipelineZeroKm(tabelaId, nil),
+		bson.D{{Key: "$project", Value: bson.M{
+			"_id":       0,
+			"brandName": 1,
+			"modelCode": "$models.modelCode",
+			"modelName": "$models.modelName",
+			"ano":       "$models.years",
+		}}},
+		bson.D{{Key: "$project", Value: bson.M{"ano.priceCents": 0}}},
+	)
+	cursor, err := database.DB.Collection("Veiculos").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao agregar veículos 0km da tabela %d: %v", tabelaId, err)
+	}
+	var entradas []EntradaZeroKm
+	if err := cursor.All(ctx, &entradas); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar veículos 0km da tabela %d: %v", tabelaId, err)
+	}
+	return entradas, nil
+}
+
+// NomesMarcas retorna o nome de cada marca da tabela, ou só da marca
+// informada, sem ler os modelos.
+func NomesMarcas(ctx context.Context, tabelaId int, marca *int32) (map[int32]string, error) {
+	filtro := bson.M{"monthYearId": tabelaId}
+	if marca != nil {
+		filtro["brandCode"] = *marca
+	}
+	opts := options.Find().SetProjection(bson.M{"brandCode": 1, "brandName": 1, "_id": 0})
+	cursor, err := database.DB.Collection("Veiculos").Find(ctx, filtro, opts)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao buscar marcas da tabela %d: %v", tabelaId, err)
+	}
+	var marcas []struct {
+		BrandCode int32  `bson:"brandCode"`
+		BrandName string `bson:"brandName"`
+	}
+	if err := cursor.All(ctx, &marcas); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar marcas da tabela %d: %v", tabelaId, err)
+	}
+	nomes := make(map[int32]string, len(marcas))
+	for _, m := range marcas {
+		if _, ok := nomes[m.BrandCode]; !ok {
+			nomes[m.BrandCode] = m.BrandName
+		}
+	}
+	return nomes, nil
+}
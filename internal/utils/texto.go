@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizarTexto converte para minúsculas, remove acentos e pontuação e
+// colapsa espaços, para comparar nomes de marcas e modelos digitados à mão.
+func NormalizarTexto(s string) string {
+	var b strings.Builder
+	espaco := false
+	for _, r := range norm.NFD.String(strings.ToLower(s)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.':
+			b.WriteRune(r)
+			espaco = false
+		default:
+			if !espaco && b.Len() > 0 {
+				b.WriteByte(' ')
+				espaco = true
+			}
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Similaridade retorna o coeficiente de Dice (0 a 1) entre os bigramas dos
+// dois textos normalizados. Textos idênticos após a normalização valem 1.
+func Similaridade(a, b string) float64 {
+	a, b = NormalizarTexto(a), NormalizarTexto(b)
+	if a == b {
+		return 1
+	}
+	if len(a) < 2 || len(b) < 2 {
+		return 0
+	}
+	bigramas := make(map[string]int)
+	ra := []rune(a)
+	for i := 0; i < len(ra)-1; i++ {
+		bigramas[string(ra[i:i+2])]++
+	}
+	comuns := 0
+	rb := []rune(b)
+	for i := 0; i < len(rb)-1; i++ {
+		bg := string(rb[i : i+2])
+		if bigramas[bg] > 0 {
+			bigramas[bg]--
+			comuns++
+		}
+	}
+	return float64(2*comuns) / float64(len(ra)-1+len(rb)-1)
+}